@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+	"sort"
+	"time"
+)
+
+type MemoryCertificateRepo struct {
+	store *Store
+}
+
+func NewCertificateRepo(store *Store) *MemoryCertificateRepo {
+	return &MemoryCertificateRepo{store: store}
+}
+
+func (p *MemoryCertificateRepo) FindAll(ctx context.Context) ([]domain.Certificate, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	return values(p.store.certificates, nil), nil
+}
+
+func (p *MemoryCertificateRepo) FindByID(ctx context.Context,
+	certID domain.ID) (domain.Certificate, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	certificate, ok := p.store.certificates[certID]
+	if !ok {
+		return domain.Certificate{}, errors.Wrapf(errs.ErrNotExist, "certificate %s", certID)
+	}
+	return certificate, nil
+}
+
+func (p *MemoryCertificateRepo) FindUserCertificates(ctx context.Context,
+	userID domain.ID) ([]domain.Certificate, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	certificates := values(p.store.certificates, func(certificate domain.Certificate) bool {
+		return certificate.UserID == userID
+	})
+	sort.SliceStable(certificates, func(i, j int) bool {
+		return certificates[i].CreatedAt.After(certificates[j].CreatedAt)
+	})
+	return certificates, nil
+}
+
+func (p *MemoryCertificateRepo) FindUserCourseCertificate(ctx context.Context,
+	courseID, userID domain.ID) (domain.Certificate, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	certificates := values(p.store.certificates, func(certificate domain.Certificate) bool {
+		return certificate.CourseID == courseID && certificate.UserID == userID
+	})
+	if len(certificates) == 0 {
+		return domain.Certificate{}, errors.Wrapf(errs.ErrNotExist,
+			"certificate of user %s for course %s", userID, courseID)
+	}
+	return certificates[0], nil
+}
+
+func (p *MemoryCertificateRepo) Create(ctx context.Context,
+	cert domain.Certificate) (domain.Certificate, error) {
+	pgCertificate, err := entity.NewPgCertificate(cert)
+	if err != nil {
+		return domain.Certificate{}, err
+	}
+	if err := pgCertificate.Validate(); err != nil {
+		return domain.Certificate{}, err
+	}
+	cert.ID = domain.ID(pgCertificate.ID.String())
+	if pgCertificate.Grade == "" {
+		return domain.Certificate{}, errors.Wrapf(errs.ErrEnumValueError, "certificate grade %v", cert.Grade)
+	}
+
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if _, ok := p.store.certificates[cert.ID]; ok {
+		return domain.Certificate{}, errors.Wrapf(repository.ErrIDConflict, "certificate %s", cert.ID)
+	}
+	// the timestamp column keeps microseconds and no time zone
+	cert.CreatedAt = cert.CreatedAt.UTC().Truncate(time.Microsecond)
+	p.store.certificates[cert.ID] = cert
+	return cert, nil
+}
@@ -0,0 +1,152 @@
+package memory
+
+import (
+	"context"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+)
+
+type MemoryCourseRepo struct {
+	store *Store
+}
+
+func NewCourseRepo(store *Store) *MemoryCourseRepo {
+	return &MemoryCourseRepo{store: store}
+}
+
+func (p *MemoryCourseRepo) FindAll(ctx context.Context) ([]domain.Course, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	return values(p.store.courses, nil), nil
+}
+
+func (p *MemoryCourseRepo) FindByID(ctx context.Context, courseID domain.ID) (domain.Course, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	course, ok := p.store.courses[courseID]
+	if !ok {
+		return domain.Course{}, errors.Wrapf(errs.ErrNotExist, "course %s", courseID)
+	}
+	return course, nil
+}
+
+func (p *MemoryCourseRepo) FindStudentCourses(ctx context.Context, studentID domain.ID) ([]domain.Course, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	return p.coursesByID(groups(p.store.courseStudents, studentID)), nil
+}
+
+func (p *MemoryCourseRepo) FindTeacherCourses(ctx context.Context, teacherID domain.ID) ([]domain.Course, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	return p.coursesByID(groups(p.store.courseTeachers, teacherID)), nil
+}
+
+func (p *MemoryCourseRepo) FindCourseTeachers(ctx context.Context, courseID domain.ID) ([]domain.User, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	teachers := make([]domain.User, 0)
+	for _, teacherID := range members(p.store.courseTeachers, courseID) {
+		if teacher, ok := p.store.users[teacherID]; ok {
+			teachers = append(teachers, teacher)
+		}
+	}
+	return teachers, nil
+}
+
+func (p *MemoryCourseRepo) IsCourseStudent(ctx context.Context, studentID, courseID domain.ID) (bool, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	return p.store.courseStudents[membership{userID: studentID, groupID: courseID}], nil
+}
+
+func (p *MemoryCourseRepo) IsCourseTeacher(ctx context.Context, teacherID, courseID domain.ID) (bool, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	return p.store.courseTeachers[membership{userID: teacherID, groupID: courseID}], nil
+}
+
+func (p *MemoryCourseRepo) AddCourseStudent(ctx context.Context, studentID, courseID domain.ID) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	row := membership{userID: studentID, groupID: courseID}
+	if p.store.courseStudents[row] {
+		return errors.Wrapf(errs.ErrDuplicate, "student %s of course %s", studentID, courseID)
+	}
+	p.store.courseStudents[row] = true
+	return nil
+}
+
+func (p *MemoryCourseRepo) AddCourseTeacher(ctx context.Context, teacherID, courseID domain.ID) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	row := membership{userID: teacherID, groupID: courseID}
+	if p.store.courseTeachers[row] {
+		return errors.Wrapf(errs.ErrDuplicate, "teacher %s of course %s", teacherID, courseID)
+	}
+	p.store.courseTeachers[row] = true
+	return nil
+}
+
+func (p *MemoryCourseRepo) Create(ctx context.Context, course domain.Course) (domain.Course, error) {
+	pgCourse, err := entity.NewPgCourse(course)
+	if err != nil {
+		return domain.Course{}, err
+	}
+	if err := pgCourse.Validate(); err != nil {
+		return domain.Course{}, err
+	}
+	course.ID = domain.ID(pgCourse.ID.String())
+
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if _, ok := p.store.courses[course.ID]; ok {
+		return domain.Course{}, errors.Wrapf(repository.ErrIDConflict, "course %s", course.ID)
+	}
+	p.store.courses[course.ID] = course
+	return course, nil
+}
+
+func (p *MemoryCourseRepo) Update(ctx context.Context, course domain.Course) (domain.Course, error) {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if _, ok := p.store.courses[course.ID]; !ok {
+		return domain.Course{}, errors.Wrapf(errs.ErrNotExist, "course %s", course.ID)
+	}
+	p.store.courses[course.ID] = course
+	return course, nil
+}
+
+func (p *MemoryCourseRepo) UpdateStatus(ctx context.Context, courseID domain.ID, status domain.CourseStatus) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	course, ok := p.store.courses[courseID]
+	if !ok {
+		return errors.Wrapf(errs.ErrNotExist, "course %s", courseID)
+	}
+	course.Status = status
+	p.store.courses[courseID] = course
+	return nil
+}
+
+func (p *MemoryCourseRepo) Delete(ctx context.Context, courseID domain.ID) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	delete(p.store.courses, courseID)
+	return nil
+}
+
+// coursesByID returns the existing courses among ids, the caller holds the
+// store lock.
+func (p *MemoryCourseRepo) coursesByID(ids []domain.ID) []domain.Course {
+	courses := make([]domain.Course, 0, len(ids))
+	for _, id := range ids {
+		if course, ok := p.store.courses[id]; ok {
+			courses = append(courses, course)
+		}
+	}
+	return courses
+}
@@ -0,0 +1,102 @@
+package memory
+
+import (
+	"context"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+)
+
+type MemoryLessonRepo struct {
+	store *Store
+}
+
+func NewLessonRepo(store *Store) *MemoryLessonRepo {
+	return &MemoryLessonRepo{store: store}
+}
+
+func (p *MemoryLessonRepo) FindAll(ctx context.Context) ([]domain.Lesson, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	return values(p.store.lessons, nil), nil
+}
+
+func (p *MemoryLessonRepo) FindByID(ctx context.Context, lessonID domain.ID) (domain.Lesson, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	lesson, ok := p.store.lessons[lessonID]
+	if !ok {
+		return domain.Lesson{}, errors.Wrapf(errs.ErrNotExist, "lesson %s", lessonID)
+	}
+	return lesson, nil
+}
+
+func (p *MemoryLessonRepo) FindCourseLessons(ctx context.Context,
+	courseID domain.ID) ([]domain.Lesson, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	return values(p.store.lessons, func(lesson domain.Lesson) bool {
+		return lesson.CourseID == courseID
+	}), nil
+}
+
+func (p *MemoryLessonRepo) FindLessonTests(ctx context.Context, lessonID domain.ID) ([]domain.Test, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	tests := make([]domain.Test, 0)
+	if lesson, ok := p.store.lessons[lessonID]; ok {
+		tests = append(tests, lesson.Tests...)
+	}
+	return tests, nil
+}
+
+func (p *MemoryLessonRepo) Create(ctx context.Context, lesson domain.Lesson) (domain.Lesson, error) {
+	pgLesson, err := entity.NewPgLesson(lesson)
+	if err != nil {
+		return domain.Lesson{}, err
+	}
+	if err := pgLesson.Validate(); err != nil {
+		return domain.Lesson{}, err
+	}
+	lesson.ID = domain.ID(pgLesson.ID.String())
+
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if _, ok := p.store.lessons[lesson.ID]; ok {
+		return domain.Lesson{}, errors.Wrapf(repository.ErrIDConflict, "lesson %s", lesson.ID)
+	}
+	lesson = storedLesson(lesson)
+	p.store.lessons[lesson.ID] = lesson
+	return lesson, nil
+}
+
+func (p *MemoryLessonRepo) Update(ctx context.Context, lesson domain.Lesson) (domain.Lesson, error) {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if _, ok := p.store.lessons[lesson.ID]; !ok {
+		return domain.Lesson{}, errors.Wrapf(errs.ErrNotExist, "lesson %s", lesson.ID)
+	}
+	lesson = storedLesson(lesson)
+	p.store.lessons[lesson.ID] = lesson
+	return lesson, nil
+}
+
+func (p *MemoryLessonRepo) Delete(ctx context.Context, lessonID domain.ID) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	delete(p.store.lessons, lessonID)
+	return nil
+}
+
+// storedLesson copies the lesson as the postgres repository would read it
+// back: only practice lessons keep their tests.
+func storedLesson(lesson domain.Lesson) domain.Lesson {
+	if lesson.Type == domain.PracticeLesson {
+		lesson.Tests = append(make([]domain.Test, 0, len(lesson.Tests)), lesson.Tests...)
+	} else {
+		lesson.Tests = nil
+	}
+	return lesson
+}
@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"context"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+)
+
+type MemoryReviewRepo struct {
+	store *Store
+}
+
+func NewReviewRepo(store *Store) *MemoryReviewRepo {
+	return &MemoryReviewRepo{store: store}
+}
+
+func (r *MemoryReviewRepo) FindAll(ctx context.Context) ([]domain.Review, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return values(r.store.reviews, func(review domain.Review) bool {
+		return !r.store.deletedReviews[review.ID]
+	}), nil
+}
+
+// FindByID finds deleted reviews too, as the postgres repository does.
+func (r *MemoryReviewRepo) FindByID(ctx context.Context, reviewID domain.ID) (domain.Review, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	review, ok := r.store.reviews[reviewID]
+	if !ok {
+		return domain.Review{}, errors.Wrapf(errs.ErrNotExist, "review %s", reviewID)
+	}
+	return review, nil
+}
+
+func (r *MemoryReviewRepo) FindUserReviews(ctx context.Context, userID domain.ID) ([]domain.Review, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return values(r.store.reviews, func(review domain.Review) bool {
+		return review.UserID == userID && !r.store.deletedReviews[review.ID]
+	}), nil
+}
+
+func (r *MemoryReviewRepo) FindCourseReviews(ctx context.Context, courseID domain.ID) ([]domain.Review, error) {
+	r.store.mu.RLock()
+	defer r.store.mu.RUnlock()
+	return values(r.store.reviews, func(review domain.Review) bool {
+		return review.CourseID == courseID && !r.store.deletedReviews[review.ID]
+	}), nil
+}
+
+func (r *MemoryReviewRepo) Create(ctx context.Context, review domain.Review) (domain.Review, error) {
+	pgReview, err := entity.NewPgReview(review)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	if err := pgReview.Validate(); err != nil {
+		return domain.Review{}, err
+	}
+	review.ID = domain.ID(pgReview.ID.String())
+
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.reviews[review.ID]; ok {
+		return domain.Review{}, errors.Wrapf(repository.ErrIDConflict, "review %s", review.ID)
+	}
+	for _, existing := range r.store.reviews {
+		if existing.UserID == review.UserID && existing.CourseID == review.CourseID &&
+			review.UserID != "" && !r.store.deletedReviews[existing.ID] {
+			return domain.Review{}, errors.Wrapf(errs.ErrDuplicate,
+				"review of user %s for course %s", review.UserID, review.CourseID)
+		}
+	}
+	r.store.reviews[review.ID] = review
+	return review, nil
+}
+
+// Delete hides the review like the soft delete of the postgres repository.
+func (r *MemoryReviewRepo) Delete(ctx context.Context, reviewID domain.ID) error {
+	r.store.mu.Lock()
+	defer r.store.mu.Unlock()
+	if _, ok := r.store.reviews[reviewID]; ok {
+		r.store.deletedReviews[reviewID] = true
+	}
+	return nil
+}
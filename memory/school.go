@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"context"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+)
+
+type MemorySchoolRepo struct {
+	store *Store
+}
+
+func NewSchoolRepo(store *Store) *MemorySchoolRepo {
+	return &MemorySchoolRepo{store: store}
+}
+
+func (s *MemorySchoolRepo) FindAll(ctx context.Context) ([]domain.School, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	return values(s.store.schools, nil), nil
+}
+
+func (s *MemorySchoolRepo) FindByID(ctx context.Context, schoolID domain.ID) (domain.School, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	school, ok := s.store.schools[schoolID]
+	if !ok {
+		return domain.School{}, errors.Wrapf(errs.ErrNotExist, "school %s", schoolID)
+	}
+	return school, nil
+}
+
+func (s *MemorySchoolRepo) FindUserSchools(ctx context.Context, userID domain.ID) ([]domain.School, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	return values(s.store.schools, func(school domain.School) bool {
+		return school.OwnerID == userID
+	}), nil
+}
+
+func (s *MemorySchoolRepo) FindSchoolCourses(ctx context.Context, schoolID domain.ID) ([]domain.Course, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	return values(s.store.courses, func(course domain.Course) bool {
+		return course.SchoolID == schoolID
+	}), nil
+}
+
+func (s *MemorySchoolRepo) FindSchoolTeachers(ctx context.Context, schoolID domain.ID) ([]domain.User, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	teachers := make([]domain.User, 0)
+	for _, teacherID := range members(s.store.schoolTeachers, schoolID) {
+		if teacher, ok := s.store.users[teacherID]; ok {
+			teachers = append(teachers, teacher)
+		}
+	}
+	return teachers, nil
+}
+
+func (s *MemorySchoolRepo) IsSchoolTeacher(ctx context.Context, schoolID, teacherID domain.ID) (bool, error) {
+	s.store.mu.RLock()
+	defer s.store.mu.RUnlock()
+	return s.store.schoolTeachers[membership{userID: teacherID, groupID: schoolID}], nil
+}
+
+func (s *MemorySchoolRepo) AddSchoolTeacher(ctx context.Context, schoolID, teacherID domain.ID) error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	row := membership{userID: teacherID, groupID: schoolID}
+	if s.store.schoolTeachers[row] {
+		return errors.Wrapf(errs.ErrDuplicate, "teacher %s of school %s", teacherID, schoolID)
+	}
+	s.store.schoolTeachers[row] = true
+	return nil
+}
+
+func (s *MemorySchoolRepo) Create(ctx context.Context, school domain.School) (domain.School, error) {
+	pgSchool, err := entity.NewPgSchool(school)
+	if err != nil {
+		return domain.School{}, err
+	}
+	if err := pgSchool.Validate(); err != nil {
+		return domain.School{}, err
+	}
+	school.ID = domain.ID(pgSchool.ID.String())
+
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if _, ok := s.store.schools[school.ID]; ok {
+		return domain.School{}, errors.Wrapf(repository.ErrIDConflict, "school %s", school.ID)
+	}
+	s.store.schools[school.ID] = school
+	return school, nil
+}
+
+func (s *MemorySchoolRepo) Update(ctx context.Context, school domain.School) (domain.School, error) {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	if _, ok := s.store.schools[school.ID]; !ok {
+		return domain.School{}, errors.Wrapf(errs.ErrNotExist, "school %s", school.ID)
+	}
+	s.store.schools[school.ID] = school
+	return school, nil
+}
+
+func (s *MemorySchoolRepo) Delete(ctx context.Context, schoolID domain.ID) error {
+	s.store.mu.Lock()
+	defer s.store.mu.Unlock()
+	delete(s.store.schools, schoolID)
+	return nil
+}
@@ -0,0 +1,52 @@
+package memory
+
+import (
+	"context"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/pkg/errors"
+)
+
+type MemoryStatRepo struct {
+	store *Store
+}
+
+func NewStatRepo(store *Store) *MemoryStatRepo {
+	return &MemoryStatRepo{store: store}
+}
+
+func (p *MemoryStatRepo) FindLessonStat(ctx context.Context,
+	userID, lessonID domain.ID) (domain.LessonStat, error) {
+	p.store.mu.RLock()
+	defer p.store.mu.RUnlock()
+	for _, stat := range p.store.lessonStats {
+		if stat.UserID == userID && stat.LessonID == lessonID {
+			stat.TestStats = append(make([]domain.TestStat, 0, len(stat.TestStats)), stat.TestStats...)
+			return stat, nil
+		}
+	}
+	return domain.LessonStat{}, errors.Wrapf(errs.ErrNotExist, "stat of user %s for lesson %s", userID, lessonID)
+}
+
+func (p *MemoryStatRepo) CreateLessonStat(ctx context.Context, stat domain.LessonStat) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if _, ok := p.store.lessonStats[stat.ID]; ok {
+		return errors.Wrapf(errs.ErrDuplicate, "lesson stat %s", stat.ID)
+	}
+	stat.TestStats = append([]domain.TestStat(nil), stat.TestStats...)
+	p.store.lessonStats[stat.ID] = stat
+	return nil
+}
+
+// UpdateLessonStat replaces the stat with the same id, an unknown stat is
+// ignored as by the postgres repository.
+func (p *MemoryStatRepo) UpdateLessonStat(ctx context.Context, stat domain.LessonStat) error {
+	p.store.mu.Lock()
+	defer p.store.mu.Unlock()
+	if _, ok := p.store.lessonStats[stat.ID]; ok {
+		stat.TestStats = append([]domain.TestStat(nil), stat.TestStats...)
+		p.store.lessonStats[stat.ID] = stat
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Package memory provides map backed fakes of the postgres repositories for
+// unit tests of the services that do not want to start a database. The
+// fakes report the same errs sentinels as the postgres repositories and
+// return lists ordered by id. Foreign keys and cascades are not modelled.
+package memory
+
+import (
+	"github.com/paw1a/eschool-core/domain"
+	"sort"
+	"sync"
+)
+
+// Store holds the rows shared by the fakes, repositories created from the
+// same store see each other's writes, as the postgres ones share a database.
+type Store struct {
+	mu             sync.RWMutex
+	users          map[domain.ID]domain.User
+	schools        map[domain.ID]domain.School
+	courses        map[domain.ID]domain.Course
+	lessons        map[domain.ID]domain.Lesson
+	reviews        map[domain.ID]domain.Review
+	deletedReviews map[domain.ID]bool
+	certificates   map[domain.ID]domain.Certificate
+	lessonStats    map[domain.ID]domain.LessonStat
+	schoolTeachers map[membership]bool
+	courseStudents map[membership]bool
+	courseTeachers map[membership]bool
+}
+
+// membership is a row of a join table such as course_student.
+type membership struct {
+	userID  domain.ID
+	groupID domain.ID
+}
+
+func NewStore() *Store {
+	return &Store{
+		users:          make(map[domain.ID]domain.User),
+		schools:        make(map[domain.ID]domain.School),
+		courses:        make(map[domain.ID]domain.Course),
+		lessons:        make(map[domain.ID]domain.Lesson),
+		reviews:        make(map[domain.ID]domain.Review),
+		deletedReviews: make(map[domain.ID]bool),
+		certificates:   make(map[domain.ID]domain.Certificate),
+		lessonStats:    make(map[domain.ID]domain.LessonStat),
+		schoolTeachers: make(map[membership]bool),
+		courseStudents: make(map[membership]bool),
+		courseTeachers: make(map[membership]bool),
+	}
+}
+
+// values returns the rows accepted by keep ordered by id, a nil keep
+// accepts every row.
+func values[T any](rows map[domain.ID]T, keep func(T) bool) []T {
+	ids := make([]domain.ID, 0, len(rows))
+	for id, row := range rows {
+		if keep == nil || keep(row) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	result := make([]T, len(ids))
+	for i, id := range ids {
+		result[i] = rows[id]
+	}
+	return result
+}
+
+// members returns the ids of the users related to the group by the join
+// table ordered by id.
+func members(rows map[membership]bool, groupID domain.ID) []domain.ID {
+	var ids []domain.ID
+	for row := range rows {
+		if row.groupID == groupID {
+			ids = append(ids, row.userID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// groups returns the ids of the groups the user is related to by the join
+// table ordered by id.
+func groups(rows map[membership]bool, userID domain.ID) []domain.ID {
+	var ids []domain.ID
+	for row := range rows {
+		if row.userID == userID {
+			ids = append(ids, row.groupID)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+	return ids
+}
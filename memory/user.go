@@ -0,0 +1,121 @@
+package memory
+
+import (
+	"context"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/paw1a/eschool-core/port"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+)
+
+type MemoryUserRepo struct {
+	store *Store
+}
+
+func NewUserRepo(store *Store) *MemoryUserRepo {
+	return &MemoryUserRepo{store: store}
+}
+
+func (u *MemoryUserRepo) FindAll(ctx context.Context) ([]domain.User, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+	return values(u.store.users, nil), nil
+}
+
+func (u *MemoryUserRepo) FindByID(ctx context.Context, userID domain.ID) (domain.User, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+	user, ok := u.store.users[userID]
+	if !ok {
+		return domain.User{}, errors.Wrapf(errs.ErrNotExist, "user %s", userID)
+	}
+	return user, nil
+}
+
+func (u *MemoryUserRepo) FindByEmail(ctx context.Context, email string) (domain.User, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+	for _, user := range u.store.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return domain.User{}, errors.Wrapf(errs.ErrNotExist, "user with email %s", email)
+}
+
+func (u *MemoryUserRepo) FindByCredentials(ctx context.Context, email string, password string) (domain.User, error) {
+	u.store.mu.RLock()
+	defer u.store.mu.RUnlock()
+	for _, user := range u.store.users {
+		if user.Email == email && user.Password == password {
+			return user, nil
+		}
+	}
+	return domain.User{}, errors.Wrap(errs.ErrNotExist, "user with credentials")
+}
+
+func (u *MemoryUserRepo) FindUserInfo(ctx context.Context, userID domain.ID) (port.UserInfo, error) {
+	user, err := u.FindByID(ctx, userID)
+	if err != nil {
+		return port.UserInfo{}, err
+	}
+	return port.UserInfo{
+		Name:    user.Name,
+		Surname: user.Surname,
+	}, nil
+}
+
+func (u *MemoryUserRepo) Create(ctx context.Context, user domain.User) (domain.User, error) {
+	pgUser, err := entity.NewPgUser(user)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if err := pgUser.Validate(); err != nil {
+		return domain.User{}, err
+	}
+	user.ID = domain.ID(pgUser.ID.String())
+
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	if _, ok := u.store.users[user.ID]; ok {
+		return domain.User{}, errors.Wrapf(repository.ErrIDConflict, "user %s", user.ID)
+	}
+	if u.emailTaken(user) {
+		return domain.User{}, errors.Wrapf(errs.ErrDuplicate, "user with email %s", user.Email)
+	}
+	u.store.users[user.ID] = user
+	return user, nil
+}
+
+func (u *MemoryUserRepo) Update(ctx context.Context, user domain.User) (domain.User, error) {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	if u.emailTaken(user) {
+		return domain.User{}, errors.Wrapf(errs.ErrUpdateFailed, "user with email %s", user.Email)
+	}
+	if _, ok := u.store.users[user.ID]; !ok {
+		return domain.User{}, errors.Wrapf(errs.ErrNotExist, "user %s", user.ID)
+	}
+	u.store.users[user.ID] = user
+	return user, nil
+}
+
+func (u *MemoryUserRepo) Delete(ctx context.Context, userID domain.ID) error {
+	u.store.mu.Lock()
+	defer u.store.mu.Unlock()
+	delete(u.store.users, userID)
+	return nil
+}
+
+// emailTaken reports whether another user already has the email of user,
+// the caller holds the store lock.
+func (u *MemoryUserRepo) emailTaken(user domain.User) bool {
+	for _, other := range u.store.users {
+		if other.ID != user.ID && other.Email == user.Email {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// BatchResult is the outcome of one user queued in a BatchWriter.
+type BatchResult struct {
+	User domain.User
+	Err  error
+}
+
+// BatchStats counts the work done by a BatchWriter.
+type BatchStats struct {
+	Items   int
+	Flushes int
+}
+
+type batchItem struct {
+	user   entity.PgUser
+	result chan BatchResult
+}
+
+// BatchWriter accumulates user creations and writes them as one multi-row
+// insert when size users are queued or interval has passed since the first
+// queued user. A user conflicting with an existing row, or with an earlier
+// user of the same batch, gets errs.ErrDuplicate while the rest of the
+// batch is still created.
+type BatchWriter struct {
+	db       *sqlx.DB
+	opts     options
+	size     int
+	interval time.Duration
+
+	mu      sync.Mutex
+	pending []batchItem
+	timer   *time.Timer
+	closed  bool
+	stats   BatchStats
+}
+
+// NewBatchWriter creates a writer flushing every size users or every
+// interval, whichever comes first. Keep size*columns under the postgres
+// limit of 65535 bind parameters.
+func NewBatchWriter(db *sqlx.DB, size int, interval time.Duration, opts ...Option) *BatchWriter {
+	return &BatchWriter{
+		db:       db,
+		opts:     newOptions(opts),
+		size:     size,
+		interval: interval,
+	}
+}
+
+// Create queues the user. The returned channel receives the result once the
+// batch holding the user is flushed.
+func (w *BatchWriter) Create(ctx context.Context, user domain.User) <-chan BatchResult {
+	result := make(chan BatchResult, 1)
+	if err := w.opts.checkWritable(); err != nil {
+		result <- BatchResult{Err: err}
+		return result
+	}
+
+	pgUser, err := entity.NewPgUser(user)
+	if err == nil {
+		err = pgUser.Validate()
+	}
+	if err != nil {
+		result <- BatchResult{Err: err}
+		return result
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		result <- BatchResult{Err: errors.Wrap(errs.ErrPersistenceFailed, "batch writer is closed")}
+		return result
+	}
+	w.pending = append(w.pending, batchItem{user: pgUser, result: result})
+	var batch []batchItem
+	if len(w.pending) >= w.size {
+		batch = w.takePending()
+	} else if w.timer == nil {
+		w.timer = time.AfterFunc(w.interval, func() {
+			w.mu.Lock()
+			batch := w.takePending()
+			w.mu.Unlock()
+			w.flush(context.Background(), batch)
+		})
+	}
+	w.mu.Unlock()
+
+	w.flush(ctx, batch)
+	return result
+}
+
+// Close flushes the queued users and rejects any further Create.
+func (w *BatchWriter) Close(ctx context.Context) {
+	w.mu.Lock()
+	w.closed = true
+	batch := w.takePending()
+	w.mu.Unlock()
+
+	w.flush(ctx, batch)
+}
+
+// Stats returns the number of items and flushes done so far.
+func (w *BatchWriter) Stats() BatchStats {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.stats
+}
+
+func (w *BatchWriter) takePending() []batchItem {
+	if w.timer != nil {
+		w.timer.Stop()
+		w.timer = nil
+	}
+	batch := w.pending
+	w.pending = nil
+	return batch
+}
+
+func (w *BatchWriter) flush(ctx context.Context, batch []batchItem) {
+	if len(batch) == 0 {
+		return
+	}
+
+	pgUsers := make([]entity.PgUser, len(batch))
+	for i, item := range batch {
+		pgUsers[i] = item.user
+	}
+
+	w.mu.Lock()
+	w.stats.Items += len(batch)
+	w.stats.Flushes++
+	w.mu.Unlock()
+
+	created, err := w.insert(ctx, pgUsers)
+	for _, item := range batch {
+		if err != nil {
+			item.result <- BatchResult{Err: err}
+			continue
+		}
+		if user, ok := created[item.user.ID.String()]; ok {
+			delete(created, item.user.ID.String())
+			item.result <- BatchResult{User: user}
+		} else {
+			item.result <- BatchResult{Err: errors.Wrapf(errs.ErrDuplicate, "user %s", item.user.ID)}
+		}
+	}
+}
+
+func (w *BatchWriter) insert(ctx context.Context, pgUsers []entity.PgUser) (map[string]domain.User, error) {
+	queryString := strings.TrimSuffix(entity.InsertQueryString(pgUsers[0], "user"), " RETURNING *") +
+		" ON CONFLICT DO NOTHING RETURNING *"
+	if err := entity.ValidateNamedQuery(pgUsers[0], queryString); err != nil {
+		return nil, err
+	}
+	query, args, err := sqlx.Named(queryString, pgUsers)
+	if err != nil {
+		return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+
+	var createdUsers []entity.PgUser
+	if err = w.db.SelectContext(ctx, &createdUsers, w.db.Rebind(query), args...); err != nil {
+		return nil, persistenceError(w.db, err)
+	}
+
+	created := make(map[string]domain.User, len(createdUsers))
+	for _, user := range createdUsers {
+		created[user.ID.String()] = user.ToDomain()
+	}
+	return created, nil
+}
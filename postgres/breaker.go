@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"database/sql/driver"
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned instead of connecting while the circuit breaker
+// is open. The repositories report it as ErrServiceUnavailable.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// CircuitBreaker stops the pool from connecting to a database that is down.
+// After threshold connection attempts in a row failed, every attempt fails
+// fast with ErrCircuitOpen for cooldown. Then a single probe attempt is let
+// through: its success closes the circuit, its failure opens it again.
+type CircuitBreaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	probing  bool
+}
+
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+	}
+}
+
+// IsOpen reports whether connection attempts are being rejected.
+func (b *CircuitBreaker) IsOpen() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.open
+}
+
+// allow reports whether a connection attempt may go to the database.
+func (b *CircuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return nil
+	}
+	if b.probing || time.Since(b.openedAt) < b.cooldown {
+		return errors.WithStack(ErrCircuitOpen)
+	}
+	b.probing = true
+	return nil
+}
+
+// record accounts the outcome of an attempt let through by allow.
+func (b *CircuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err == nil {
+		b.failures = 0
+		b.open = false
+		return
+	}
+	if errors.Is(err, context.Canceled) {
+		return
+	}
+	b.failures++
+	if b.open || b.failures >= b.threshold {
+		b.open = true
+		b.openedAt = time.Now()
+	}
+}
+
+// breakerConnector guards the connections opened by the pool with a
+// CircuitBreaker.
+type breakerConnector struct {
+	driver.Connector
+	breaker *CircuitBreaker
+}
+
+func (c *breakerConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, err
+	}
+	conn, err := c.Connector.Connect(ctx)
+	c.breaker.record(err)
+	return conn, err
+}
@@ -0,0 +1,196 @@
+package repository
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/pkg/errors"
+	"os"
+	"sync"
+)
+
+// ErrWriteBuffered is returned by WriteAheadBuffer.Create when the database
+// was unreachable and the review was queued to be created by FlushPending.
+var ErrWriteBuffered = errors.New("write buffered until the database is reachable")
+
+type pendingReview struct {
+	Key    string        `json:"key"`
+	Review domain.Review `json:"review"`
+}
+
+// WriteAheadBuffer creates reviews through the repository and, while the
+// database is unreachable, appends them to a local file instead. The queued
+// reviews are created by FlushPending in the order they were queued, the
+// idempotency key of each review makes replaying a review that already
+// reached the database harmless.
+type WriteAheadBuffer struct {
+	repo *PostgresReviewRepo
+	path string
+	mu   sync.Mutex
+}
+
+// NewWriteAheadBuffer creates a buffer keeping the queued reviews in the
+// file at path, reviews left there by a previous process are replayed too.
+func NewWriteAheadBuffer(repo *PostgresReviewRepo, path string) *WriteAheadBuffer {
+	return &WriteAheadBuffer{
+		repo: repo,
+		path: path,
+	}
+}
+
+// Create creates the review or, when the database is unreachable, queues it
+// and returns ErrWriteBuffered. A review is queued as well while earlier
+// reviews are still pending, so that the creation order is kept until the
+// next FlushPending.
+func (b *WriteAheadBuffer) Create(ctx context.Context, review domain.Review, key string) (domain.Review, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, err := os.Stat(b.path); err == nil {
+		return review, b.appendPending(pendingReview{Key: key, Review: review})
+	}
+
+	created, err := b.repo.CreateWithIdempotencyKey(ctx, review, key)
+	if err != nil && b.unreachable(ctx, err) {
+		return review, b.appendPending(pendingReview{Key: key, Review: review})
+	}
+	return created, err
+}
+
+// FlushPending creates the queued reviews and returns how many of them were
+// created. It stops with ErrWriteBuffered at the first review that can not
+// reach the database, that review and the following ones stay queued. A
+// review rejected by the database is dropped and its error is returned once
+// the rest of the queue is flushed.
+func (b *WriteAheadBuffer) FlushPending(ctx context.Context) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending, err := b.readPending()
+	if err != nil {
+		return 0, err
+	}
+	return b.flushPending(ctx, pending)
+}
+
+func (b *WriteAheadBuffer) flushPending(ctx context.Context, pending []pendingReview) (int, error) {
+	var flushed int
+	var rejected error
+	for i, p := range pending {
+		_, err := b.repo.CreateWithIdempotencyKey(ctx, p.Review, p.Key)
+		if err != nil && b.unreachable(ctx, err) {
+			if err := b.writePending(pending[i:]); err != nil {
+				return flushed, err
+			}
+			return flushed, errors.Wrap(ErrWriteBuffered, err.Error())
+		}
+		if err != nil {
+			if rejected == nil {
+				rejected = err
+			}
+			continue
+		}
+		flushed++
+	}
+
+	if err := b.writePending(nil); err != nil {
+		return flushed, err
+	}
+	return flushed, rejected
+}
+
+// unreachable tells a failed query apart from a database that can not be
+// reached: the repository errors do not keep the driver error, so the
+// database is pinged to find out.
+func (b *WriteAheadBuffer) unreachable(ctx context.Context, err error) bool {
+	if !errors.Is(err, errs.ErrPersistenceFailed) && !errors.Is(err, errs.ErrTransactionError) &&
+		!errors.Is(err, ErrServiceUnavailable) {
+		return false
+	}
+	return b.repo.db.PingContext(ctx) != nil
+}
+
+func (b *WriteAheadBuffer) readPending() ([]pendingReview, error) {
+	file, err := os.Open(b.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	defer file.Close()
+
+	var pending []pendingReview
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var p pendingReview
+		if err = json.Unmarshal(scanner.Bytes(), &p); err != nil {
+			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+		}
+		pending = append(pending, p)
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	return pending, nil
+}
+
+func (b *WriteAheadBuffer) appendPending(p pendingReview) error {
+	line, err := json.Marshal(p)
+	if err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+
+	file, err := os.OpenFile(b.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	defer file.Close()
+
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	if err = file.Sync(); err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	return ErrWriteBuffered
+}
+
+// writePending replaces the queue with the given reviews, atomically so a
+// crash leaves either the old or the new queue.
+func (b *WriteAheadBuffer) writePending(pending []pendingReview) error {
+	if len(pending) == 0 {
+		if err := os.Remove(b.path); err != nil && !os.IsNotExist(err) {
+			return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+		}
+		return nil
+	}
+
+	tmpPath := b.path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	writer := bufio.NewWriter(file)
+	for _, p := range pending {
+		line, err := json.Marshal(p)
+		if err != nil {
+			file.Close()
+			return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err = writer.Flush(); err == nil {
+		err = file.Sync()
+	}
+	file.Close()
+	if err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+
+	if err = os.Rename(tmpPath, b.path); err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
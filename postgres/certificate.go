@@ -2,123 +2,504 @@ package repository
 
 import (
 	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/hex"
+	"github.com/guregu/null"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/paw1a/eschool-core/domain"
 	"github.com/paw1a/eschool-core/errs"
 	"github.com/paw1a/eschool-repository/postgres/entity"
 	"github.com/pkg/errors"
+	"time"
 )
 
 type PostgresCertificateRepo struct {
-	db *sqlx.DB
+	db   *sqlx.DB
+	opts options
 }
 
-func NewCertificateRepo(db *sqlx.DB) *PostgresCertificateRepo {
+type CertificateWithCourse struct {
+	Certificate domain.Certificate
+	CourseName  string
+	SchoolName  string
+}
+
+// IssuedCertificate is a certificate together with its expiry and its
+// verification hash, which domain.Certificate does not carry. ExpiresAt is
+// null for a certificate that never expires.
+type IssuedCertificate struct {
+	Certificate      domain.Certificate
+	ExpiresAt        null.Time
+	VerificationHash string
+}
+
+func NewCertificateRepo(db *sqlx.DB, opts ...Option) *PostgresCertificateRepo {
 	return &PostgresCertificateRepo{
-		db: db,
+		db:   db,
+		opts: newOptions(opts),
 	}
 }
 
 const (
-	certificateFindAllQuery               = "SELECT * FROM public.certificate"
-	certificateFindByIDQuery              = "SELECT * FROM public.certificate WHERE id = $1"
-	certificateFindByCourseAndUserIDQuery = "SELECT * FROM public.certificate WHERE course_id = $1 AND user_id = $2"
-	certificateFindUserCertificatesQuery  = "SELECT * FROM public.certificate WHERE user_id = $1"
+	certificateFindAllQuery                  = "SELECT * FROM public.certificate"
+	certificateFindByIDQuery                 = "SELECT * FROM public.certificate WHERE id = ?"
+	certificateFindByCourseAndUserIDQuery    = "SELECT * FROM public.certificate WHERE course_id = ? AND user_id = ?"
+	certificateFindUserCertificatesQuery     = "SELECT * FROM public.certificate WHERE user_id = ? ORDER BY created_at DESC, id"
+	certificateFindUserCertificatesPageQuery = "SELECT *, COUNT(*) OVER() AS total FROM public.certificate " +
+		"WHERE user_id = ? ORDER BY created_at DESC, id LIMIT ? OFFSET ?"
+	certificateFindUserCertificatesWithCourseQuery = "SELECT cert.*, c.name AS course_name, " +
+		"s.name AS school_name FROM public.certificate cert " +
+		"JOIN public.course c on cert.course_id = c.id " +
+		"JOIN public.school s on c.school_id = s.id " +
+		"WHERE cert.user_id = ? ORDER BY cert.created_at, cert.id"
+	certificateCountUserCertificatesQuery  = "SELECT COUNT(*) FROM public.certificate WHERE user_id = ?"
+	certificateFindByVerificationHashQuery = "SELECT * FROM public.certificate WHERE verification_hash = ?"
+	certificateFindAllByCursorQuery        = "SELECT * FROM public.certificate " +
+		"WHERE (created_at, id) > (?, ?) ORDER BY created_at, id LIMIT ?"
+	certificateFindByGradeQuery = "SELECT * FROM public.certificate WHERE grade = ? " +
+		"ORDER BY created_at, id LIMIT ? OFFSET ?"
+	certificateFindExpiringBeforeQuery = "SELECT * FROM public.certificate " +
+		"WHERE expires_at IS NOT NULL AND expires_at < ? ORDER BY expires_at"
+	certificateFindUserCertificatesSortedQuery = "SELECT cert.* FROM public.certificate cert " +
+		"JOIN public.course c on cert.course_id = c.id WHERE cert.user_id = ?"
+	certificateFindUserCertificatesInSchoolQuery = "SELECT cert.* FROM public.certificate cert " +
+		"JOIN public.course c on cert.course_id = c.id WHERE cert.user_id = ? AND c.school_id = ? " +
+		"ORDER BY cert.created_at DESC, cert.id"
+	certificateCountByCourseQuery = "SELECT course_id, COUNT(*) AS count FROM public.certificate " +
+		"WHERE course_id IN (?) GROUP BY course_id"
+)
+
+// verificationHashSize is the number of random bytes of a verification hash,
+// which is stored hex encoded.
+const verificationHashSize = 32
+
+// CertificateOrder selects the order of the certificates listed by
+// FindUserCertificatesOrdered.
+type CertificateOrder string
+
+const (
+	CertificateOrderDefault    CertificateOrder = ""
+	CertificateOrderNewest     CertificateOrder = "newest"
+	CertificateOrderOldest     CertificateOrder = "oldest"
+	CertificateOrderCourseName CertificateOrder = "course_name"
+	CertificateOrderExpiry     CertificateOrder = "expiry"
 )
 
+// CertificateSort is the sort spec of FindUserCertificatesSorted. NullsFirst
+// puts the certificates whose sort column is NULL, such as the ones that
+// never expire under CertificateOrderExpiry, first instead of last.
+type CertificateSort struct {
+	Order      CertificateOrder
+	NullsFirst bool
+}
+
+var certificateOrderColumns = map[CertificateOrder][]string{
+	CertificateOrderDefault:    {"cert.created_at DESC", "cert.id"},
+	CertificateOrderNewest:     {"cert.created_at DESC", "cert.id"},
+	CertificateOrderOldest:     {"cert.created_at", "cert.id"},
+	CertificateOrderCourseName: {"c.name", "cert.id"},
+	CertificateOrderExpiry:     {"cert.expires_at", "cert.id"},
+}
+
 func (p *PostgresCertificateRepo) FindAll(ctx context.Context) ([]domain.Certificate, error) {
+	if err := p.opts.checkRateLimit("certificate.FindAll"); err != nil {
+		return nil, err
+	}
+
 	var pgCertificates []entity.PgCertificate
-	if err := p.db.SelectContext(ctx, &pgCertificates, certificateFindAllQuery); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, p.db, p.opts, "certificate.FindAll", &pgCertificates, certificateFindAllQuery); err != nil {
+		return nil, err
 	}
+	p.opts.observeRows("certificate.FindAll", len(pgCertificates))
 
-	certificates := make([]domain.Certificate, len(pgCertificates))
-	for i, certificate := range pgCertificates {
-		certificates[i] = certificate.ToDomain()
+	return certificatesToDomain(pgCertificates)
+}
+
+// FindAllByCursor returns the page of certificates following the cursor
+// together with the cursor of the next page, which is empty on the last page.
+func (p *PostgresCertificateRepo) FindAllByCursor(ctx context.Context,
+	cursor string, limit int) ([]domain.Certificate, string, error) {
+	if err := p.opts.checkRateLimit("certificate.FindAllByCursor"); err != nil {
+		return nil, "", err
 	}
-	return certificates, nil
+
+	after, err := DecodeCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var pgCertificates []entity.PgCertificate
+	if err := selectRows(ctx, p.db, p.opts, "certificate.FindAllByCursor", &pgCertificates,
+		certificateFindAllByCursorQuery, after.CreatedAt, after.ID, limit); err != nil {
+		return nil, "", err
+	}
+	p.opts.observeRows("certificate.FindAllByCursor", len(pgCertificates))
+
+	certificates, err := certificatesToDomain(pgCertificates)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(certificates) == limit && limit > 0 {
+		last := certificates[len(certificates)-1]
+		next = EncodeCursor(Cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return certificates, next, nil
 }
 
 func (p *PostgresCertificateRepo) FindByID(ctx context.Context,
 	certID domain.ID) (domain.Certificate, error) {
 	var pgCertificate entity.PgCertificate
-	if err := p.db.GetContext(ctx, &pgCertificate, certificateFindByIDQuery, certID); err != nil {
+	db := p.opts.reader(ctx, p.db)
+	if err := db.GetContext(ctx, &pgCertificate, db.Rebind(certificateFindByIDQuery), certID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Certificate{}, errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return domain.Certificate{}, persistenceError(db, err)
+		}
+	}
+	return pgCertificate.ToDomainChecked()
+}
+
+func (p *PostgresCertificateRepo) FindByVerificationHash(ctx context.Context,
+	hash string) (domain.Certificate, error) {
+	var pgCertificate entity.PgCertificate
+	if err := p.db.GetContext(ctx, &pgCertificate, p.db.Rebind(certificateFindByVerificationHashQuery), hash); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.Certificate{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Certificate{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Certificate{}, persistenceError(p.db, err)
 		}
 	}
-	return pgCertificate.ToDomain(), nil
+	return pgCertificate.ToDomainChecked()
 }
 
 func (p *PostgresCertificateRepo) FindUserCertificates(ctx context.Context,
 	userID domain.ID) ([]domain.Certificate, error) {
+	return p.FindUserCertificatesOrdered(ctx, userID, CertificateOrderDefault)
+}
+
+// FindUserCertificatesOrdered lists the user certificates in the given
+// order, the newest first by default.
+func (p *PostgresCertificateRepo) FindUserCertificatesOrdered(ctx context.Context,
+	userID domain.ID, order CertificateOrder) ([]domain.Certificate, error) {
+	return p.FindUserCertificatesSorted(ctx, userID, CertificateSort{Order: order})
+}
+
+// FindUserCertificatesSorted lists the user certificates as sort specifies,
+// the ones with a NULL sort column last unless sort.NullsFirst is set.
+func (p *PostgresCertificateRepo) FindUserCertificatesSorted(ctx context.Context,
+	userID domain.ID, sort CertificateSort) ([]domain.Certificate, error) {
+	if err := p.opts.checkRateLimit("certificate.FindUserCertificatesSorted"); err != nil {
+		return nil, err
+	}
+
+	columns, ok := certificateOrderColumns[sort.Order]
+	if !ok {
+		return nil, errors.Wrapf(errs.ErrEnumValueError, "certificate order %q", sort.Order)
+	}
+
 	var pgCertificates []entity.PgCertificate
-	if err := p.db.SelectContext(ctx, &pgCertificates, certificateFindUserCertificatesQuery, userID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
+	if err := selectRows(ctx, p.db, p.opts, "certificate.FindUserCertificatesSorted", &pgCertificates,
+		certificateFindUserCertificatesSortedQuery+orderByClause(columns, sort.NullsFirst), userID); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("certificate.FindUserCertificatesSorted", len(pgCertificates))
+
+	return certificatesToDomain(pgCertificates)
+}
+
+// FindUserCertificatesInSchool returns the user certificates issued for the
+// courses of the school, newest first.
+func (p *PostgresCertificateRepo) FindUserCertificatesInSchool(ctx context.Context,
+	userID, schoolID domain.ID) ([]domain.Certificate, error) {
+	if err := p.opts.checkRateLimit("certificate.FindUserCertificatesInSchool"); err != nil {
+		return nil, err
+	}
+
+	var pgCertificates []entity.PgCertificate
+	if err := selectRows(ctx, p.db, p.opts, "certificate.FindUserCertificatesInSchool", &pgCertificates,
+		certificateFindUserCertificatesInSchoolQuery, userID, schoolID); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("certificate.FindUserCertificatesInSchool", len(pgCertificates))
+
+	return certificatesToDomain(pgCertificates)
+}
+
+// CountCertificatesByCourse returns the number of certificates issued for
+// every given course keyed by course, courses without certificates are
+// absent from the map. The courses are counted 1000 at a time.
+func (p *PostgresCertificateRepo) CountCertificatesByCourse(ctx context.Context,
+	courseIDs []domain.ID) (map[domain.ID]int, error) {
+	if err := p.opts.checkRateLimit("certificate.CountCertificatesByCourse"); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[domain.ID]int)
+	if len(courseIDs) == 0 {
+		return counts, nil
+	}
+
+	for _, chunk := range chunkIDs(courseIDs, inChunkSize) {
+		query, args, err := sqlx.In(certificateCountByCourseQuery, chunk)
+		if err != nil {
 			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
 		}
+
+		var pgCounts []entity.PgCourseCount
+		if err := selectRows(ctx, p.db, p.opts, "certificate.CountCertificatesByCourse", &pgCounts, query, args...); err != nil {
+			return nil, err
+		}
+		for _, count := range pgCounts {
+			counts[domain.ID(count.CourseID.String())] = count.Count
+		}
 	}
+	p.opts.observeRows("certificate.CountCertificatesByCourse", len(counts))
+	return counts, nil
+}
 
-	certificates := make([]domain.Certificate, len(pgCertificates))
+// FindUserCertificatesWithCourse returns the user certificates, oldest first,
+// each with the name of its course and of the school teaching it.
+func (p *PostgresCertificateRepo) FindUserCertificatesWithCourse(ctx context.Context,
+	userID domain.ID) ([]CertificateWithCourse, error) {
+	if err := p.opts.checkRateLimit("certificate.FindUserCertificatesWithCourse"); err != nil {
+		return nil, err
+	}
+
+	var pgCertificates []entity.PgCertificateWithCourse
+	if err := selectRows(ctx, p.db, p.opts, "certificate.FindUserCertificatesWithCourse", &pgCertificates,
+		certificateFindUserCertificatesWithCourseQuery, userID); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("certificate.FindUserCertificatesWithCourse", len(pgCertificates))
+
+	certificates := make([]CertificateWithCourse, len(pgCertificates))
 	for i, certificate := range pgCertificates {
-		certificates[i] = certificate.ToDomain()
+		domainCertificate, err := certificate.ToDomainChecked()
+		if err != nil {
+			return nil, err
+		}
+		certificates[i] = CertificateWithCourse{
+			Certificate: domainCertificate,
+			CourseName:  certificate.CourseName,
+			SchoolName:  certificate.SchoolName,
+		}
 	}
 	return certificates, nil
 }
 
+// FindUserCertificatesPage returns a page of the user certificates, newest
+// first, along with the total number of the user certificates.
+func (p *PostgresCertificateRepo) FindUserCertificatesPage(ctx context.Context, userID domain.ID,
+	params PageParams) (Page[domain.Certificate], error) {
+	if err := p.opts.checkRateLimit("certificate.FindUserCertificatesPage"); err != nil {
+		return Page[domain.Certificate]{}, err
+	}
+
+	var pgCertificates []entity.PgCertificateWithTotal
+	if err := selectRows(ctx, p.db, p.opts, "certificate.FindUserCertificatesPage", &pgCertificates,
+		certificateFindUserCertificatesPageQuery, userID, params.Limit, params.Offset); err != nil {
+		return Page[domain.Certificate]{}, err
+	}
+	p.opts.observeRows("certificate.FindUserCertificatesPage", len(pgCertificates))
+
+	page := Page[domain.Certificate]{
+		Items:  make([]domain.Certificate, len(pgCertificates)),
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}
+	for i, certificate := range pgCertificates {
+		domainCertificate, err := certificate.ToDomainChecked()
+		if err != nil {
+			return Page[domain.Certificate]{}, err
+		}
+		page.Items[i] = domainCertificate
+	}
+
+	if len(pgCertificates) > 0 {
+		page.Total = pgCertificates[0].Total
+	} else if params.Offset > 0 {
+		if err := p.db.GetContext(ctx, &page.Total, p.db.Rebind(certificateCountUserCertificatesQuery), userID); err != nil {
+			return Page[domain.Certificate]{}, persistenceError(p.db, err)
+		}
+	}
+	return page, nil
+}
+
 func (p *PostgresCertificateRepo) FindUserCourseCertificate(ctx context.Context,
 	courseID, userID domain.ID) (domain.Certificate, error) {
 	var pgCertificate entity.PgCertificate
-	if err := p.db.GetContext(ctx, &pgCertificate, certificateFindByCourseAndUserIDQuery,
+	if err := p.db.GetContext(ctx, &pgCertificate, p.db.Rebind(certificateFindByCourseAndUserIDQuery),
 		courseID, userID); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.Certificate{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Certificate{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Certificate{}, persistenceError(p.db, err)
 		}
 	}
-	return pgCertificate.ToDomain(), nil
+	return pgCertificate.ToDomainChecked()
+}
+
+// FindCertificatesExpiringBefore returns the certificates expiring before
+// cutoff with their expiry, the first to expire first. Certificates that
+// never expire are left out.
+func (p *PostgresCertificateRepo) FindCertificatesExpiringBefore(ctx context.Context,
+	cutoff time.Time) ([]IssuedCertificate, error) {
+	if err := p.opts.checkRateLimit("certificate.FindCertificatesExpiringBefore"); err != nil {
+		return nil, err
+	}
+
+	var pgCertificates []entity.PgCertificate
+	if err := selectRows(ctx, p.db, p.opts, "certificate.FindCertificatesExpiringBefore", &pgCertificates,
+		certificateFindExpiringBeforeQuery, cutoff.UTC()); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("certificate.FindCertificatesExpiringBefore", len(pgCertificates))
+
+	certificates := make([]IssuedCertificate, len(pgCertificates))
+	for i, certificate := range pgCertificates {
+		issued, err := newIssuedCertificate(certificate)
+		if err != nil {
+			return nil, err
+		}
+		certificates[i] = issued
+	}
+	return certificates, nil
+}
+
+func (p *PostgresCertificateRepo) FindCertificatesByGrade(ctx context.Context,
+	grade domain.CertificateGrade, page PageParams) ([]domain.Certificate, error) {
+	if err := p.opts.checkRateLimit("certificate.FindCertificatesByGrade"); err != nil {
+		return nil, err
+	}
+
+	pgGrade := entity.NewPgCertificateGrade(grade)
+	if pgGrade == "" {
+		return nil, errors.Wrapf(errs.ErrEnumValueError, "certificate grade %v", grade)
+	}
+
+	var pgCertificates []entity.PgCertificate
+	if err := selectRows(ctx, p.db, p.opts, "certificate.FindCertificatesByGrade", &pgCertificates,
+		certificateFindByGradeQuery, pgGrade, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("certificate.FindCertificatesByGrade", len(pgCertificates))
+
+	return certificatesToDomain(pgCertificates)
 }
 
 func (p *PostgresCertificateRepo) Create(ctx context.Context,
 	cert domain.Certificate) (domain.Certificate, error) {
-	var pgCertificate = entity.NewPgCertificate(cert)
+	pgCertificate, err := entity.NewPgCertificate(cert)
+	if err != nil {
+		return domain.Certificate{}, err
+	}
+	createdCertificate, err := p.create(ctx, pgCertificate)
+	if err != nil {
+		return domain.Certificate{}, err
+	}
+	return createdCertificate.ToDomain(), nil
+}
+
+// IssueCertificate creates the certificate expiring at expiresAt, or never
+// when expiresAt is null. The returned certificate carries the verification
+// hash to hand out for FindByVerificationHash.
+func (p *PostgresCertificateRepo) IssueCertificate(ctx context.Context, cert domain.Certificate,
+	expiresAt null.Time) (IssuedCertificate, error) {
+	pgCertificate, err := entity.NewPgCertificate(cert)
+	if err != nil {
+		return IssuedCertificate{}, err
+	}
+	if expiresAt.Valid {
+		pgCertificate.ExpiresAt = null.TimeFrom(expiresAt.Time.UTC())
+	}
+	createdCertificate, err := p.create(ctx, pgCertificate)
+	if err != nil {
+		return IssuedCertificate{}, err
+	}
+	return newIssuedCertificate(createdCertificate)
+}
+
+func (p *PostgresCertificateRepo) create(ctx context.Context,
+	pgCertificate entity.PgCertificate) (entity.PgCertificate, error) {
+	if err := p.opts.checkWritable(); err != nil {
+		return entity.PgCertificate{}, err
+	}
+
+	if err := pgCertificate.Validate(); err != nil {
+		return entity.PgCertificate{}, err
+	}
+	if !pgCertificate.VerificationHash.Valid {
+		hash, err := newVerificationHash()
+		if err != nil {
+			return entity.PgCertificate{}, err
+		}
+		pgCertificate.VerificationHash = null.StringFrom(hash)
+	}
 	queryString := entity.InsertQueryString(pgCertificate, "certificate")
-	_, err := p.db.NamedExecContext(ctx, queryString, pgCertificate)
+	_, err := namedExecContext(ctx, p.db, queryString, pgCertificate)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
-				return domain.Certificate{}, errors.Wrap(errs.ErrDuplicate, err.Error())
+				return entity.PgCertificate{}, uniqueViolationError(pgErr, err)
 			} else if pgErr.Code == PgEnumValueError {
-				return domain.Certificate{}, errors.Wrap(errs.ErrEnumValueError, err.Error())
+				return entity.PgCertificate{}, errors.Wrap(errs.ErrEnumValueError, err.Error())
 			} else {
-				return domain.Certificate{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return entity.PgCertificate{}, persistenceError(p.db, err)
 			}
 		} else {
-			return domain.Certificate{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return entity.PgCertificate{}, persistenceError(p.db, err)
 		}
 	}
 
 	var createdCertificate entity.PgCertificate
-	err = p.db.GetContext(ctx, &createdCertificate, certificateFindByIDQuery, pgCertificate.ID)
+	err = p.db.GetContext(ctx, &createdCertificate, p.db.Rebind(certificateFindByIDQuery), pgCertificate.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return domain.Certificate{}, errors.Wrap(errs.ErrNotExist, err.Error())
+			return entity.PgCertificate{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Certificate{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return entity.PgCertificate{}, persistenceError(p.db, err)
 		}
 	}
 
-	return createdCertificate.ToDomain(), nil
+	return createdCertificate, nil
+}
+
+func certificatesToDomain(pgCertificates []entity.PgCertificate) ([]domain.Certificate, error) {
+	certificates := make([]domain.Certificate, len(pgCertificates))
+	for i, certificate := range pgCertificates {
+		domainCertificate, err := certificate.ToDomainChecked()
+		if err != nil {
+			return nil, err
+		}
+		certificates[i] = domainCertificate
+	}
+	return certificates, nil
+}
+
+func newIssuedCertificate(pgCertificate entity.PgCertificate) (IssuedCertificate, error) {
+	certificate, err := pgCertificate.ToDomainChecked()
+	if err != nil {
+		return IssuedCertificate{}, err
+	}
+	return IssuedCertificate{
+		Certificate:      certificate,
+		ExpiresAt:        pgCertificate.ExpiresAt,
+		VerificationHash: pgCertificate.VerificationHash.String,
+	}, nil
+}
+
+// newVerificationHash generates the public token of a certificate, random
+// so that it cannot be guessed from the certificate.
+func newVerificationHash() (string, error) {
+	token := make([]byte, verificationHashSize)
+	if _, err := rand.Read(token); err != nil {
+		return "", errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	return hex.EncodeToString(token), nil
 }
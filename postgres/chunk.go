@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"github.com/paw1a/eschool-core/domain"
+)
+
+// inChunkSize is the number of ids a batch finder binds into one IN (?)
+// list, far below the postgres limit of 65535 bind parameters.
+const inChunkSize = 1000
+
+// chunkIDs splits the ids, without duplicates, into lists of at most size
+// ids for the batch finders to query one by one. sqlx.In rejects an empty
+// list, so the batch finders return their empty result without querying
+// when given no ids.
+func chunkIDs(ids []domain.ID, size int) [][]domain.ID {
+	seen := make(map[domain.ID]bool, len(ids))
+	var chunks [][]domain.ID
+	var chunk []domain.ID
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		chunk = append(chunk, id)
+		if len(chunk) == size {
+			chunks = append(chunks, chunk)
+			chunk = nil
+		}
+	}
+	if len(chunk) > 0 {
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
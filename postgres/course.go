@@ -5,117 +5,431 @@ import (
 	"database/sql"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/paw1a/eschool-core/domain"
 	"github.com/paw1a/eschool-core/errs"
 	"github.com/paw1a/eschool-repository/postgres/entity"
 	"github.com/pkg/errors"
+	"time"
 )
 
 type PostgresCourseRepo struct {
-	db *sqlx.DB
+	db   *sqlx.DB
+	opts options
 }
 
-func NewCourseRepo(db *sqlx.DB) *PostgresCourseRepo {
+// CourseDetail is everything the course page shows: the course, the school
+// it belongs to and the summary of its reviews.
+type CourseDetail struct {
+	Course        domain.Course
+	School        domain.School
+	AverageRating float64
+	ReviewCount   int
+}
+
+// Enrollment is a course the user studies, with the date of enrollment and
+// the progress: the course lessons the user has a score for out of all of
+// its lessons.
+type Enrollment struct {
+	Course           domain.Course
+	EnrolledAt       time.Time
+	LessonsCompleted int
+	LessonsTotal     int
+}
+
+func NewCourseRepo(db *sqlx.DB, opts ...Option) *PostgresCourseRepo {
 	return &PostgresCourseRepo{
-		db: db,
+		db:   db,
+		opts: newOptions(opts),
 	}
 }
 
 const (
 	courseFindAllQuery            = "SELECT * FROM public.course ORDER BY id"
-	courseFindByIDQuery           = "SELECT * FROM public.course WHERE id = $1"
+	courseFindByIDQuery           = "SELECT * FROM public.course WHERE id = ?"
+	courseExistsByIDsQuery        = "SELECT id FROM public.course WHERE id IN (?)"
 	courseFindStudentCoursesQuery = "SELECT c.* FROM public.course c " +
 		"JOIN public.course_student cs on c.id = cs.course_id " +
-		"JOIN public.user u on cs.student_id = u.id WHERE u.id = $1"
+		"JOIN public.user u on cs.student_id = u.id WHERE u.id = ?"
+	courseFindUserEnrollmentsQuery = "SELECT c.*, cs.enrolled_at, " +
+		"(SELECT COUNT(DISTINCT ls.lesson_id) FROM public.lesson_stat ls " +
+		"JOIN public.lesson l on ls.lesson_id = l.id " +
+		"WHERE l.course_id = c.id AND ls.user_id = cs.student_id) AS lessons_completed, " +
+		"(SELECT COUNT(*) FROM public.lesson l WHERE l.course_id = c.id) AS lessons_total " +
+		"FROM public.course c JOIN public.course_student cs on c.id = cs.course_id " +
+		"WHERE cs.student_id = ? ORDER BY cs.enrolled_at DESC, c.id LIMIT ? OFFSET ?"
 	courseFindTeacherCoursesQuery = "SELECT c.* FROM public.course c " +
 		"JOIN public.course_teacher ct on c.id = ct.course_id " +
-		"JOIN public.user u on ct.teacher_id = u.id WHERE u.id = $1"
+		"JOIN public.user u on ct.teacher_id = u.id WHERE u.id = ?"
+	courseFindTeacherCoursesInSchoolQuery = "SELECT c.* FROM public.course c " +
+		"JOIN public.course_teacher ct on c.id = ct.course_id " +
+		"WHERE ct.teacher_id = ? AND c.school_id = ? ORDER BY c.id"
+	courseFindUserCompletedCoursesQuery = "SELECT c.* FROM public.course c WHERE EXISTS " +
+		"(SELECT 1 FROM public.certificate cert WHERE cert.course_id = c.id AND cert.user_id = ?) ORDER BY c.id"
+	courseFindCoursesWithoutReviewsQuery = "SELECT * FROM public.course WHERE NOT EXISTS " +
+		"(SELECT 1 FROM public.review r WHERE r.course_id = course.id AND r.deleted_at IS NULL) ORDER BY id LIMIT ? OFFSET ?"
+	courseFindByLanguageQuery = "SELECT * FROM public.course WHERE lower(language) = lower(?) " +
+		"ORDER BY id LIMIT ? OFFSET ?"
+	courseFindByAllTagsQuery       = "SELECT * FROM public.course WHERE tags @> ? ORDER BY id"
+	courseFindByAnyTagQuery        = "SELECT * FROM public.course WHERE tags && ? ORDER BY id"
+	courseSetTagsQuery             = "UPDATE public.course SET tags = ? WHERE id = ?"
+	courseFindNotUpdatedSinceQuery = "SELECT * FROM public.course WHERE updated_at < ? " +
+		"ORDER BY updated_at, id LIMIT ? OFFSET ?"
 	courseFindCourseTeachersQuery = "SELECT u.* FROM public.user u " +
 		"JOIN public.course_teacher ct on u.id = ct.teacher_id " +
-		"JOIN public.course c on ct.course_id = c.id WHERE c.id = $1"
+		"JOIN public.course c on ct.course_id = c.id WHERE c.id = ?"
 	courseContainsStudentQuery = "SELECT EXISTS (SELECT 1 FROM public.course_student " +
-		"WHERE course_id = $1 AND student_id = $2)"
+		"WHERE course_id = ? AND student_id = ?)"
 	courseContainsTeacherQuery = "SELECT EXISTS (SELECT 1 FROM public.course_teacher " +
-		"WHERE course_id = $1 AND teacher_id = $2)"
+		"WHERE course_id = ? AND teacher_id = ?)"
 	courseAddCourseStudentQuery = "INSERT INTO public.course_student (student_id, course_id) " +
-		"VALUES ($1, $2)"
+		"VALUES (?, ?)"
 	courseAddCourseTeacherQuery = "INSERT INTO public.course_teacher (teacher_id, course_id) " +
-		"VALUES ($1, $2)"
-	courseDeleteQuery = "DELETE FROM public.course WHERE id = $1"
+		"VALUES (?, ?)"
+	courseDeleteQuery = "DELETE FROM public.course WHERE id = ?"
+
+	courseFindStatusForUpdateQuery = "SELECT status FROM public.course WHERE id = ? FOR UPDATE"
+	courseUpdateStatusQuery        = "UPDATE public.course SET status = ? WHERE id = ?"
+
+	courseFindWithSchoolQuery = "SELECT c.*, s.owner_id AS school_owner_id, " +
+		"s.name AS school_name, s.description AS school_description FROM public.course c " +
+		"JOIN public.school s on c.school_id = s.id WHERE c.id = ?"
+	courseRatingSummaryQuery = "SELECT COALESCE(AVG(rating), 0) AS average_rating, " +
+		"COUNT(*) AS review_count FROM public.review WHERE course_id = ? AND deleted_at IS NULL"
 )
 
+// courseStatusTransitions lists the statuses a course can move to from its
+// current status, any other transition is rejected by SetCourseStatus.
+var courseStatusTransitions = map[domain.CourseStatus][]domain.CourseStatus{
+	domain.CourseDraft: {domain.CourseReady},
+	domain.CourseReady: {domain.CourseDraft, domain.CoursePublished},
+}
+
 func (p *PostgresCourseRepo) FindAll(ctx context.Context) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindAll"); err != nil {
+		return nil, err
+	}
+
 	var pgCourses []entity.PgCourse
-	if err := p.db.SelectContext(ctx, &pgCourses, courseFindAllQuery); err != nil {
+	if err := selectRows(ctx, p.db, p.opts, "course.FindAll", &pgCourses, courseFindAllQuery); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("course.FindAll", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
+}
+
+func (p *PostgresCourseRepo) FindByID(ctx context.Context, courseID domain.ID) (domain.Course, error) {
+	var pgCourse entity.PgCourse
+	db := p.opts.reader(ctx, p.db)
+	if err := db.GetContext(ctx, &pgCourse, db.Rebind(courseFindByIDQuery), courseID); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
+			return domain.Course{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Course{}, persistenceError(db, err)
 		}
 	}
+	return pgCourse.ToDomainChecked()
+}
 
-	courses := make([]domain.Course, len(pgCourses))
-	for i, course := range pgCourses {
-		courses[i] = course.ToDomain()
+// ExistsByIDs reports for every given id whether the course exists.
+func (p *PostgresCourseRepo) ExistsByIDs(ctx context.Context, ids []domain.ID) (map[domain.ID]bool, error) {
+	return existsByIDs(ctx, p.db, p.opts, "course.ExistsByIDs", courseExistsByIDsQuery, ids)
+}
+
+// FindCourseDetail loads the course with its school and its review summary
+// from one snapshot, so that the parts are consistent with each other.
+func (p *PostgresCourseRepo) FindCourseDetail(ctx context.Context, courseID domain.ID) (CourseDetail, error) {
+	var pgCourse entity.PgCourseWithSchool
+	var pgSummary entity.PgRatingSummary
+	err := WithinTx(ctx, p.db, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true},
+		func(tx *sqlx.Tx) error {
+			if err := tx.GetContext(ctx, &pgCourse, tx.Rebind(courseFindWithSchoolQuery), courseID); err != nil {
+				if err == sql.ErrNoRows {
+					return errors.Wrap(errs.ErrNotExist, err.Error())
+				} else {
+					return persistenceError(p.db, err)
+				}
+			}
+			if err := tx.GetContext(ctx, &pgSummary, tx.Rebind(courseRatingSummaryQuery), courseID); err != nil {
+				return persistenceError(p.db, err)
+			}
+			return nil
+		})
+	if err != nil {
+		return CourseDetail{}, err
 	}
-	return courses, nil
+
+	course, err := pgCourse.ToDomainChecked()
+	if err != nil {
+		return CourseDetail{}, err
+	}
+	pgSchool := pgCourse.School()
+	return CourseDetail{
+		Course:        course,
+		School:        pgSchool.ToDomain(),
+		AverageRating: pgSummary.AverageRating,
+		ReviewCount:   pgSummary.ReviewCount,
+	}, nil
 }
 
-func (p *PostgresCourseRepo) FindByID(ctx context.Context, courseID domain.ID) (domain.Course, error) {
+// FindByIDFields loads only the requested columns of the course, keyed by
+// column name. All columns are loaded when no fields are given.
+func (p *PostgresCourseRepo) FindByIDFields(ctx context.Context, courseID domain.ID,
+	fields ...string) (map[string]any, error) {
+	queryString, err := entity.SelectByIDQueryString(entity.PgCourse{}, "course", fields)
+	if err != nil {
+		return nil, err
+	}
+
+	row := make(map[string]any)
+	if err = p.db.QueryRowxContext(ctx, p.db.Rebind(queryString), courseID).MapScan(row); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return nil, persistenceError(p.db, err)
+		}
+	}
+	for column, value := range row {
+		if bytes, ok := value.([]byte); ok {
+			row[column] = string(bytes)
+		}
+	}
+	return row, nil
+}
+
+// FindByIDForUpdate reads the course inside tx and locks its row with the
+// given strength until tx ends. LockForNoKeyUpdate is enough to update the
+// course and, unlike LockForUpdate, does not block inserts referencing it.
+func (p *PostgresCourseRepo) FindByIDForUpdate(ctx context.Context, tx *sqlx.Tx,
+	courseID domain.ID, lock RowLock) (domain.Course, error) {
+	query, err := lockClause(courseFindByIDQuery, lock)
+	if err != nil {
+		return domain.Course{}, err
+	}
+
 	var pgCourse entity.PgCourse
-	if err := p.db.GetContext(ctx, &pgCourse, courseFindByIDQuery, courseID); err != nil {
+	if err = tx.GetContext(ctx, &pgCourse, tx.Rebind(query), courseID); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.Course{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Course{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Course{}, persistenceError(p.db, err)
 		}
 	}
-	return pgCourse.ToDomain(), nil
+	return pgCourse.ToDomainChecked()
 }
 
 func (p *PostgresCourseRepo) FindStudentCourses(ctx context.Context, studentID domain.ID) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindStudentCourses"); err != nil {
+		return nil, err
+	}
+
 	var pgCourses []entity.PgCourse
-	if err := p.db.SelectContext(ctx, &pgCourses, courseFindStudentCoursesQuery, studentID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, p.db, p.opts, "course.FindStudentCourses", &pgCourses,
+		courseFindStudentCoursesQuery, studentID); err != nil {
+		return nil, err
 	}
+	p.opts.observeRows("course.FindStudentCourses", len(pgCourses))
 
-	courses := make([]domain.Course, len(pgCourses))
-	for i, course := range pgCourses {
-		courses[i] = course.ToDomain()
+	return coursesToDomain(pgCourses)
+}
+
+// FindUserEnrollments returns the courses the user is enrolled in with their
+// progress, the latest enrollment first.
+func (p *PostgresCourseRepo) FindUserEnrollments(ctx context.Context, userID domain.ID,
+	page PageParams) ([]Enrollment, error) {
+	if err := p.opts.checkRateLimit("course.FindUserEnrollments"); err != nil {
+		return nil, err
 	}
-	return courses, nil
+
+	var pgEnrollments []entity.PgEnrollment
+	if err := selectRows(ctx, p.db, p.opts, "course.FindUserEnrollments", &pgEnrollments,
+		courseFindUserEnrollmentsQuery, userID, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("course.FindUserEnrollments", len(pgEnrollments))
+
+	enrollments := make([]Enrollment, len(pgEnrollments))
+	for i, pgEnrollment := range pgEnrollments {
+		course, err := pgEnrollment.ToDomainChecked()
+		if err != nil {
+			return nil, err
+		}
+		enrollments[i] = Enrollment{
+			Course:           course,
+			EnrolledAt:       pgEnrollment.EnrolledAt,
+			LessonsCompleted: pgEnrollment.LessonsCompleted,
+			LessonsTotal:     pgEnrollment.LessonsTotal,
+		}
+	}
+	return enrollments, nil
 }
 
 func (p *PostgresCourseRepo) FindTeacherCourses(ctx context.Context, teacherID domain.ID) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindTeacherCourses"); err != nil {
+		return nil, err
+	}
+
 	var pgCourses []entity.PgCourse
-	if err := p.db.SelectContext(ctx, &pgCourses, courseFindTeacherCoursesQuery, teacherID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, p.db, p.opts, "course.FindTeacherCourses", &pgCourses,
+		courseFindTeacherCoursesQuery, teacherID); err != nil {
+		return nil, err
 	}
+	p.opts.observeRows("course.FindTeacherCourses", len(pgCourses))
 
-	courses := make([]domain.Course, len(pgCourses))
-	for i, course := range pgCourses {
-		courses[i] = course.ToDomain()
+	return coursesToDomain(pgCourses)
+}
+
+// FindTeacherCoursesInSchool returns the courses of the school the teacher
+// teaches.
+func (p *PostgresCourseRepo) FindTeacherCoursesInSchool(ctx context.Context,
+	teacherID, schoolID domain.ID) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindTeacherCoursesInSchool"); err != nil {
+		return nil, err
 	}
-	return courses, nil
+
+	var pgCourses []entity.PgCourse
+	if err := selectRows(ctx, p.db, p.opts, "course.FindTeacherCoursesInSchool", &pgCourses,
+		courseFindTeacherCoursesInSchoolQuery, teacherID, schoolID); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("course.FindTeacherCoursesInSchool", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
+}
+
+func (p *PostgresCourseRepo) FindUserCompletedCourses(ctx context.Context, userID domain.ID) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindUserCompletedCourses"); err != nil {
+		return nil, err
+	}
+
+	var pgCourses []entity.PgCourse
+	if err := selectRows(ctx, p.db, p.opts, "course.FindUserCompletedCourses", &pgCourses,
+		courseFindUserCompletedCoursesQuery, userID); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("course.FindUserCompletedCourses", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
+}
+
+// FindCoursesWithoutReviews returns the courses that never received a review.
+func (p *PostgresCourseRepo) FindCoursesWithoutReviews(ctx context.Context, page PageParams) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindCoursesWithoutReviews"); err != nil {
+		return nil, err
+	}
+
+	var pgCourses []entity.PgCourse
+	if err := selectRows(ctx, p.db, p.opts, "course.FindCoursesWithoutReviews", &pgCourses,
+		courseFindCoursesWithoutReviewsQuery, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("course.FindCoursesWithoutReviews", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
+}
+
+// FindCoursesByLanguage returns the courses taught in the language, which is
+// compared case insensitively.
+func (p *PostgresCourseRepo) FindCoursesByLanguage(ctx context.Context, lang string,
+	page PageParams) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindCoursesByLanguage"); err != nil {
+		return nil, err
+	}
+
+	var pgCourses []entity.PgCourse
+	if err := selectRows(ctx, p.db, p.opts, "course.FindCoursesByLanguage", &pgCourses,
+		courseFindByLanguageQuery, lang, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("course.FindCoursesByLanguage", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
+}
+
+// FindCoursesByTags returns the courses tagged with every given tag when
+// matchAll is set and with any of them otherwise. No tags match no course,
+// whatever matchAll, and the result is returned without querying.
+func (p *PostgresCourseRepo) FindCoursesByTags(ctx context.Context, tags []string,
+	matchAll bool) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindCoursesByTags"); err != nil {
+		return nil, err
+	}
+	if len(tags) == 0 {
+		return []domain.Course{}, nil
+	}
+
+	query := courseFindByAnyTagQuery
+	if matchAll {
+		query = courseFindByAllTagsQuery
+	}
+	var pgCourses []entity.PgCourse
+	if err := selectRows(ctx, p.db, p.opts, "course.FindCoursesByTags", &pgCourses,
+		query, courseTags(tags)); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("course.FindCoursesByTags", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
+}
+
+// SetCourseTags replaces the tags of the course.
+func (p *PostgresCourseRepo) SetCourseTags(ctx context.Context, courseID domain.ID, tags []string) error {
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	result, err := p.db.ExecContext(ctx, p.db.Rebind(courseSetTagsQuery), courseTags(tags), courseID)
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	if updated == 0 {
+		return errors.Wrapf(errs.ErrNotExist, "course %s", courseID)
+	}
+	return nil
+}
+
+// courseTags binds the tags as a text array, a nil slice as an empty one.
+func courseTags(tags []string) pq.StringArray {
+	if tags == nil {
+		return pq.StringArray{}
+	}
+	return pq.StringArray(tags)
+}
+
+// FindCoursesNotUpdatedSince returns the courses last updated before cutoff,
+// the stalest first.
+func (p *PostgresCourseRepo) FindCoursesNotUpdatedSince(ctx context.Context, cutoff time.Time,
+	page PageParams) ([]domain.Course, error) {
+	if err := p.opts.checkRateLimit("course.FindCoursesNotUpdatedSince"); err != nil {
+		return nil, err
+	}
+
+	var pgCourses []entity.PgCourse
+	if err := selectRows(ctx, p.db, p.opts, "course.FindCoursesNotUpdatedSince", &pgCourses,
+		courseFindNotUpdatedSinceQuery, cutoff.UTC(), page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	p.opts.observeRows("course.FindCoursesNotUpdatedSince", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
 }
 
 func (p *PostgresCourseRepo) FindCourseTeachers(ctx context.Context, courseID domain.ID) ([]domain.User, error) {
+	if err := p.opts.checkRateLimit("course.FindCourseTeachers"); err != nil {
+		return nil, err
+	}
+
 	var pgUsers []entity.PgUser
-	if err := p.db.SelectContext(ctx, &pgUsers, courseFindCourseTeachersQuery, courseID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, p.db, p.opts, "course.FindCourseTeachers", &pgUsers,
+		courseFindCourseTeachersQuery, courseID); err != nil {
+		return nil, err
 	}
+	p.opts.observeRows("course.FindCourseTeachers", len(pgUsers))
 
 	teachers := make([]domain.User, len(pgUsers))
 	for i, teacher := range pgUsers {
@@ -126,7 +440,7 @@ func (p *PostgresCourseRepo) FindCourseTeachers(ctx context.Context, courseID do
 
 func (p *PostgresCourseRepo) IsCourseStudent(ctx context.Context, studentID, courseID domain.ID) (bool, error) {
 	var exists bool
-	err := p.db.GetContext(ctx, &exists, courseContainsStudentQuery, courseID, studentID)
+	err := p.db.GetContext(ctx, &exists, p.db.Rebind(courseContainsStudentQuery), courseID, studentID)
 	if err != nil {
 		return false, err
 	}
@@ -135,7 +449,7 @@ func (p *PostgresCourseRepo) IsCourseStudent(ctx context.Context, studentID, cou
 
 func (p *PostgresCourseRepo) IsCourseTeacher(ctx context.Context, teacherID, courseID domain.ID) (bool, error) {
 	var exists bool
-	err := p.db.GetContext(ctx, &exists, courseContainsTeacherQuery, courseID, teacherID)
+	err := p.db.GetContext(ctx, &exists, p.db.Rebind(courseContainsTeacherQuery), courseID, teacherID)
 	if err != nil {
 		return false, err
 	}
@@ -143,63 +457,81 @@ func (p *PostgresCourseRepo) IsCourseTeacher(ctx context.Context, teacherID, cou
 }
 
 func (p *PostgresCourseRepo) AddCourseStudent(ctx context.Context, studentID, courseID domain.ID) error {
-	_, err := p.db.ExecContext(ctx, courseAddCourseStudentQuery, studentID, courseID)
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := p.db.ExecContext(ctx, p.db.Rebind(courseAddCourseStudentQuery), studentID, courseID)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
 				return errors.Wrap(errs.ErrDuplicate, err.Error())
 			} else {
-				return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return persistenceError(p.db, err)
 			}
 		} else {
-			return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return persistenceError(p.db, err)
 		}
 	}
 	return nil
 }
 
 func (p *PostgresCourseRepo) AddCourseTeacher(ctx context.Context, teacherID, courseID domain.ID) error {
-	_, err := p.db.ExecContext(ctx, courseAddCourseTeacherQuery, teacherID, courseID)
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := p.db.ExecContext(ctx, p.db.Rebind(courseAddCourseTeacherQuery), teacherID, courseID)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
 				return errors.Wrap(errs.ErrDuplicate, err.Error())
 			} else {
-				return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return persistenceError(p.db, err)
 			}
 		} else {
-			return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return persistenceError(p.db, err)
 		}
 	}
 	return nil
 }
 
 func (p *PostgresCourseRepo) Create(ctx context.Context, course domain.Course) (domain.Course, error) {
-	var pgCourse = entity.NewPgCourse(course)
+	if err := p.opts.checkWritable(); err != nil {
+		return domain.Course{}, err
+	}
+
+	pgCourse, err := entity.NewPgCourse(course)
+	if err != nil {
+		return domain.Course{}, err
+	}
+	if err := pgCourse.Validate(); err != nil {
+		return domain.Course{}, err
+	}
 	queryString := entity.InsertQueryString(pgCourse, "course")
-	_, err := p.db.NamedExecContext(ctx, queryString, pgCourse)
+	_, err = namedExecContext(ctx, p.db, queryString, pgCourse)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
-				return domain.Course{}, errors.Wrap(errs.ErrDuplicate, err.Error())
+				return domain.Course{}, uniqueViolationError(pgErr, err)
 			} else {
-				return domain.Course{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return domain.Course{}, persistenceError(p.db, err)
 			}
 		} else {
-			return domain.Course{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Course{}, persistenceError(p.db, err)
 		}
 	}
 
 	var createdCourse entity.PgCourse
-	err = p.db.GetContext(ctx, &createdCourse, courseFindByIDQuery, pgCourse.ID)
+	err = p.db.GetContext(ctx, &createdCourse, p.db.Rebind(courseFindByIDQuery), pgCourse.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.Course{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Course{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Course{}, persistenceError(p.db, err)
 		}
 	}
 
@@ -207,42 +539,59 @@ func (p *PostgresCourseRepo) Create(ctx context.Context, course domain.Course) (
 }
 
 func (p *PostgresCourseRepo) Update(ctx context.Context, course domain.Course) (domain.Course, error) {
-	var pgCourse = entity.NewPgCourse(course)
+	if err := p.opts.checkWritable(); err != nil {
+		return domain.Course{}, err
+	}
+
+	pgCourse, err := entity.NewPgCourse(course)
+	if err != nil {
+		return domain.Course{}, err
+	}
 	queryString := entity.UpdateQueryString(pgCourse, "course")
-	_, err := p.db.NamedExecContext(ctx, queryString, pgCourse)
+	_, err = namedExecContext(ctx, p.db, queryString, pgCourse)
 	if err != nil {
 		return domain.Course{}, errors.Wrap(errs.ErrUpdateFailed, err.Error())
 	}
 
 	var updatedCourse entity.PgCourse
-	err = p.db.GetContext(ctx, &updatedCourse, courseFindByIDQuery, pgCourse.ID)
+	err = p.db.GetContext(ctx, &updatedCourse, p.db.Rebind(courseFindByIDQuery), pgCourse.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.Course{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Course{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Course{}, persistenceError(p.db, err)
 		}
 	}
 	return updatedCourse.ToDomain(), nil
 }
 
 func (p *PostgresCourseRepo) UpdateStatus(ctx context.Context, courseID domain.ID, status domain.CourseStatus) error {
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
 	var pgCourse entity.PgCourse
-	err := p.db.GetContext(ctx, &pgCourse, courseFindByIDQuery, courseID)
+	err := p.db.GetContext(ctx, &pgCourse, p.db.Rebind(courseFindByIDQuery), courseID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return persistenceError(p.db, err)
 		}
 	}
 
-	course := pgCourse.ToDomain()
+	course, err := pgCourse.ToDomainChecked()
+	if err != nil {
+		return err
+	}
 	course.Status = status
-	pgCourse = entity.NewPgCourse(course)
+	pgCourse, err = entity.NewPgCourse(course)
+	if err != nil {
+		return err
+	}
 
 	queryString := entity.UpdateQueryString(pgCourse, "course")
-	_, err = p.db.NamedExecContext(ctx, queryString, pgCourse)
+	_, err = namedExecContext(ctx, p.db, queryString, pgCourse)
 	if err != nil {
 		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
 	}
@@ -251,9 +600,76 @@ func (p *PostgresCourseRepo) UpdateStatus(ctx context.Context, courseID domain.I
 }
 
 func (p *PostgresCourseRepo) Delete(ctx context.Context, courseID domain.ID) error {
-	_, err := p.db.ExecContext(ctx, courseDeleteQuery, courseID)
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := p.db.ExecContext(ctx, p.db.Rebind(courseDeleteQuery), courseID)
 	if err != nil {
 		return errors.Wrap(errs.ErrDeleteFailed, err.Error())
 	}
 	return nil
 }
+
+func (p *PostgresCourseRepo) SetCourseStatus(ctx context.Context, courseID domain.ID, to domain.CourseStatus) error {
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	tx, err := p.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+
+	var pgStatus string
+	err = tx.GetContext(ctx, &pgStatus, tx.Rebind(courseFindStatusForUpdateQuery), courseID)
+	if err != nil {
+		tx.Rollback()
+		if err == sql.ErrNoRows {
+			return errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return persistenceError(p.db, err)
+		}
+	}
+
+	from := entity.CourseStatusToDomain(pgStatus)
+	if !isCourseStatusTransitionAllowed(from, to) {
+		tx.Rollback()
+		return errors.Wrapf(ErrIllegalTransition, "course status %s to %s",
+			pgStatus, entity.NewPgCourseStatus(to))
+	}
+
+	_, err = tx.ExecContext(ctx, tx.Rebind(courseUpdateStatusQuery), entity.NewPgCourseStatus(to), courseID)
+	if err != nil {
+		tx.Rollback()
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+
+	return nil
+}
+
+func isCourseStatusTransitionAllowed(from, to domain.CourseStatus) bool {
+	for _, allowed := range courseStatusTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func coursesToDomain(pgCourses []entity.PgCourse) ([]domain.Course, error) {
+	courses := make([]domain.Course, len(pgCourses))
+	for i, course := range pgCourses {
+		domainCourse, err := course.ToDomainChecked()
+		if err != nil {
+			return nil, err
+		}
+		courses[i] = domainCourse
+	}
+	return courses, nil
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"encoding/base64"
+	"github.com/google/uuid"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/pkg/errors"
+	"strings"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor is the last seen (created_at, id) pair of a keyset paginated listing.
+// It is passed to clients as an opaque string, an empty string means the first page.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        domain.ID
+}
+
+func EncodeCursor(cursor Cursor) string {
+	raw := cursor.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + cursor.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func DecodeCursor(cursor string) (Cursor, error) {
+	if cursor == "" {
+		return Cursor{ID: domain.ID(uuid.Nil.String())}, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return Cursor{}, errors.Wrap(ErrInvalidCursor, err.Error())
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 {
+		return Cursor{}, errors.Wrap(ErrInvalidCursor, "malformed cursor")
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, parts[0])
+	if err != nil {
+		return Cursor{}, errors.Wrap(ErrInvalidCursor, err.Error())
+	}
+	id, err := uuid.Parse(parts[1])
+	if err != nil {
+		return Cursor{}, errors.Wrap(ErrInvalidCursor, err.Error())
+	}
+	return Cursor{CreatedAt: createdAt, ID: domain.ID(id.String())}, nil
+}
@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/pkg/errors"
+	"strconv"
+	"time"
+)
+
+const setSessionConfigQuery = "SELECT set_config($1, $2, false)"
+
+type sessionSetting struct {
+	name  string
+	value string
+}
+
+type dbConfig struct {
+	conn     *pgx.ConnConfig
+	settings []sessionSetting
+	breaker  *CircuitBreaker
+}
+
+// DBOption configures the connections opened by NewPostgresDB.
+type DBOption func(config *dbConfig)
+
+// WithApplicationName sets application_name on every session of the pool,
+// so that pg_stat_statements and pg_stat_activity entries can be attributed
+// to the service.
+func WithApplicationName(name string) DBOption {
+	return func(config *dbConfig) {
+		config.conn.RuntimeParams["application_name"] = name
+	}
+}
+
+// WithStatementTimeout makes the server abort any statement running longer
+// than d, even if the caller's context has no deadline.
+func WithStatementTimeout(d time.Duration) DBOption {
+	return func(config *dbConfig) {
+		config.settings = append(config.settings, sessionSetting{"statement_timeout", durationSetting(d)})
+	}
+}
+
+// WithLockTimeout makes the server abort any statement waiting longer than d
+// to acquire a lock.
+func WithLockTimeout(d time.Duration) DBOption {
+	return func(config *dbConfig) {
+		config.settings = append(config.settings, sessionSetting{"lock_timeout", durationSetting(d)})
+	}
+}
+
+// WithCircuitBreaker makes the pool fail fast while the breaker is open
+// instead of waiting on connections to a database that is down.
+func WithCircuitBreaker(breaker *CircuitBreaker) DBOption {
+	return func(config *dbConfig) {
+		config.breaker = breaker
+	}
+}
+
+func durationSetting(d time.Duration) string {
+	return strconv.FormatInt(d.Milliseconds(), 10) + "ms"
+}
+
+// NewPostgresDB opens a pgx backed pool for the repositories.
+func NewPostgresDB(ctx context.Context, url string, opts ...DBOption) (*sqlx.DB, error) {
+	connConfig, err := pgx.ParseConfig(url)
+	if err != nil {
+		return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	config := dbConfig{conn: connConfig}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	afterConnect := stdlib.OptionAfterConnect(func(ctx context.Context, conn *pgx.Conn) error {
+		for _, setting := range config.settings {
+			if _, err := conn.Exec(ctx, setSessionConfigQuery, setting.name, setting.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
+	connector := stdlib.GetConnector(*config.conn, afterConnect)
+	if config.breaker != nil {
+		connector = &breakerConnector{Connector: connector, breaker: config.breaker}
+	}
+
+	db := sqlx.NewDb(sql.OpenDB(connector), "pgx")
+	if err = db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	return db, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DeadlineBudget splits the deadline of a request between the queries it is
+// expected to make. Each call to Next gives the query an even share of the
+// time left, so a slow query can not eat the time of the queries after it.
+//
+// The share is recomputed from the time actually left on every call, so the
+// time saved by fast queries goes to the following ones. The split is even:
+// a query that is legitimately slower than the others may be cancelled while
+// the request as a whole would still have met its deadline. Overestimating
+// the number of calls makes every share smaller, underestimating it gives
+// the calls past the estimate whatever is left of the deadline.
+type DeadlineBudget struct {
+	mu        sync.Mutex
+	callsLeft int
+}
+
+// NewDeadlineBudget creates a budget for expectedCalls sequential queries.
+func NewDeadlineBudget(expectedCalls int) *DeadlineBudget {
+	return &DeadlineBudget{callsLeft: expectedCalls}
+}
+
+// Next returns a context for the next query whose deadline is its share of
+// the time left before the deadline of ctx. Without a deadline on ctx the
+// query is not limited.
+func (b *DeadlineBudget) Next(ctx context.Context) (context.Context, context.CancelFunc) {
+	b.mu.Lock()
+	calls := b.callsLeft
+	if b.callsLeft > 1 {
+		b.callsLeft--
+	}
+	b.mu.Unlock()
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return context.WithCancel(ctx)
+	}
+	if calls < 1 {
+		calls = 1
+	}
+	share := time.Until(deadline) / time.Duration(calls)
+	return context.WithTimeout(ctx, share)
+}
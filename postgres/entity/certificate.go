@@ -1,8 +1,11 @@
 package entity
 
 import (
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/guregu/null"
 	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
 	"time"
 )
 
@@ -20,43 +23,87 @@ type PgCertificate struct {
 	CreatedAt time.Time `db:"created_at"`
 	Grade     string    `db:"grade"`
 	Score     int       `db:"score"`
+	ExpiresAt null.Time `db:"expires_at"`
+
+	VerificationHash null.String `db:"verification_hash"`
 }
 
-func (s *PgCertificate) ToDomain() domain.Certificate {
-	var grade domain.CertificateGrade
-	switch s.Grade {
+type PgCertificateWithTotal struct {
+	PgCertificate
+	Total int `db:"total"`
+}
+
+type PgCourseCount struct {
+	CourseID uuid.UUID `db:"course_id"`
+	Count    int       `db:"count"`
+}
+
+type PgCertificateWithCourse struct {
+	PgCertificate
+	CourseName string `db:"course_name"`
+	SchoolName string `db:"school_name"`
+}
+
+func CertificateGradeToDomain(grade string) domain.CertificateGrade {
+	var certificateGrade domain.CertificateGrade
+	switch grade {
 	case PgBronzeCertificate:
-		grade = domain.BronzeCertificate
+		certificateGrade = domain.BronzeCertificate
 	case PgSilverCertificate:
-		grade = domain.SilverCertificate
+		certificateGrade = domain.SilverCertificate
 	case PgGoldCertificate:
-		grade = domain.GoldCertificate
+		certificateGrade = domain.GoldCertificate
 	}
+	return certificateGrade
+}
 
+func NewPgCertificateGrade(grade domain.CertificateGrade) string {
+	var pgGrade string
+	switch grade {
+	case domain.BronzeCertificate:
+		pgGrade = PgBronzeCertificate
+	case domain.SilverCertificate:
+		pgGrade = PgSilverCertificate
+	case domain.GoldCertificate:
+		pgGrade = PgGoldCertificate
+	}
+	return pgGrade
+}
+
+func (s *PgCertificate) Validate() error {
+	return validateRequired("certificate",
+		requiredField{"name", s.Name})
+}
+
+func (s *PgCertificate) ToDomain() domain.Certificate {
 	return domain.Certificate{
 		ID:        domain.ID(s.ID.String()),
 		CourseID:  domain.ID(s.CourseID.String()),
 		UserID:    domain.ID(s.UserID.String()),
 		Name:      s.Name,
 		CreatedAt: s.CreatedAt,
-		Grade:     grade,
+		Grade:     CertificateGradeToDomain(s.Grade),
 		Score:     s.Score,
 	}
 }
 
-func NewPgCertificate(certificate domain.Certificate) PgCertificate {
-	id, _ := uuid.Parse(certificate.ID.String())
+// ToDomainChecked converts the row like ToDomain, but reports a grade the
+// domain does not know instead of silently zeroing it.
+func (s *PgCertificate) ToDomainChecked() (domain.Certificate, error) {
+	switch s.Grade {
+	case PgBronzeCertificate, PgSilverCertificate, PgGoldCertificate:
+		return s.ToDomain(), nil
+	}
+	return domain.Certificate{}, fmt.Errorf("%w: certificate %s has grade %q", errs.ErrEnumValueError, s.ID, s.Grade)
+}
+
+func NewPgCertificate(certificate domain.Certificate) (PgCertificate, error) {
+	id, err := idOrNew(certificate.ID)
+	if err != nil {
+		return PgCertificate{}, err
+	}
 	courseID, _ := uuid.Parse(certificate.CourseID.String())
 	userID, _ := uuid.Parse(certificate.UserID.String())
-	var grade string
-	switch certificate.Grade {
-	case domain.BronzeCertificate:
-		grade = PgBronzeCertificate
-	case domain.SilverCertificate:
-		grade = PgSilverCertificate
-	case domain.GoldCertificate:
-		grade = PgGoldCertificate
-	}
 
 	return PgCertificate{
 		ID:        id,
@@ -64,7 +111,7 @@ func NewPgCertificate(certificate domain.Certificate) PgCertificate {
 		UserID:    userID,
 		Name:      certificate.Name,
 		CreatedAt: certificate.CreatedAt,
-		Grade:     grade,
+		Grade:     NewPgCertificateGrade(certificate.Grade),
 		Score:     certificate.Score,
-	}
+	}, nil
 }
@@ -1,8 +1,12 @@
 package entity
 
 import (
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	"time"
 )
 
 const (
@@ -12,26 +16,82 @@ const (
 )
 
 type PgCourse struct {
-	ID       uuid.UUID `db:"id"`
-	SchoolID uuid.UUID `db:"school_id"`
-	Name     string    `db:"name"`
-	Level    int       `db:"level"`
-	Price    int64     `db:"price"`
-	Language string    `db:"language"`
-	Status   string    `db:"status"`
+	ID        uuid.UUID `db:"id"`
+	SchoolID  uuid.UUID `db:"school_id"`
+	Name      string    `db:"name"`
+	Level     int       `db:"level"`
+	Price     int64     `db:"price"`
+	Language  string    `db:"language"`
+	Status    string    `db:"status"`
+	UpdatedAt time.Time `db:"updated_at" readonly:"true"`
+
+	// Tags are not part of domain.Course, they are written by SetCourseTags
+	// only so that Update keeps them.
+	Tags pq.StringArray `db:"tags" readonly:"true"`
 }
 
-func (s *PgCourse) ToDomain() domain.Course {
-	var status domain.CourseStatus
-	switch s.Status {
+type PgCourseWithSchool struct {
+	PgCourse
+	SchoolOwnerID     uuid.UUID `db:"school_owner_id"`
+	SchoolName        string    `db:"school_name"`
+	SchoolDescription string    `db:"school_description"`
+}
+
+type PgCourseWithRating struct {
+	PgCourse
+	AverageRating float64 `db:"average_rating"`
+	ReviewCount   int     `db:"review_count"`
+}
+
+type PgEnrollment struct {
+	PgCourse
+	EnrolledAt       time.Time `db:"enrolled_at"`
+	LessonsCompleted int       `db:"lessons_completed"`
+	LessonsTotal     int       `db:"lessons_total"`
+}
+
+func (c *PgCourseWithSchool) School() PgSchool {
+	return PgSchool{
+		ID:          c.SchoolID,
+		OwnerID:     c.SchoolOwnerID,
+		Name:        c.SchoolName,
+		Description: c.SchoolDescription,
+	}
+}
+
+func CourseStatusToDomain(status string) domain.CourseStatus {
+	var courseStatus domain.CourseStatus
+	switch status {
 	case PgCourseDraft:
-		status = domain.CourseDraft
+		courseStatus = domain.CourseDraft
 	case PgCourseReady:
-		status = domain.CourseReady
+		courseStatus = domain.CourseReady
 	case PgCoursePublished:
-		status = domain.CoursePublished
+		courseStatus = domain.CoursePublished
+	}
+	return courseStatus
+}
+
+func NewPgCourseStatus(status domain.CourseStatus) string {
+	var pgStatus string
+	switch status {
+	case domain.CourseDraft:
+		pgStatus = PgCourseDraft
+	case domain.CourseReady:
+		pgStatus = PgCourseReady
+	case domain.CoursePublished:
+		pgStatus = PgCoursePublished
 	}
+	return pgStatus
+}
+
+func (s *PgCourse) Validate() error {
+	return validateRequired("course",
+		requiredField{"name", s.Name},
+		requiredField{"language", s.Language})
+}
 
+func (s *PgCourse) ToDomain() domain.Course {
 	return domain.Course{
 		ID:       domain.ID(s.ID.String()),
 		SchoolID: domain.ID(s.SchoolID.String()),
@@ -39,22 +99,26 @@ func (s *PgCourse) ToDomain() domain.Course {
 		Level:    s.Level,
 		Price:    s.Price,
 		Language: s.Language,
-		Status:   status,
+		Status:   CourseStatusToDomain(s.Status),
 	}
 }
 
-func NewPgCourse(course domain.Course) PgCourse {
-	id, _ := uuid.Parse(course.ID.String())
-	schoolID, _ := uuid.Parse(course.SchoolID.String())
-	var status string
-	switch course.Status {
-	case domain.CourseDraft:
-		status = PgCourseDraft
-	case domain.CourseReady:
-		status = PgCourseReady
-	case domain.CoursePublished:
-		status = PgCoursePublished
+// ToDomainChecked converts the row like ToDomain, but reports a status the
+// domain does not know instead of silently zeroing it.
+func (s *PgCourse) ToDomainChecked() (domain.Course, error) {
+	switch s.Status {
+	case PgCourseDraft, PgCourseReady, PgCoursePublished:
+		return s.ToDomain(), nil
+	}
+	return domain.Course{}, fmt.Errorf("%w: course %s has status %q", errs.ErrEnumValueError, s.ID, s.Status)
+}
+
+func NewPgCourse(course domain.Course) (PgCourse, error) {
+	id, err := idOrNew(course.ID)
+	if err != nil {
+		return PgCourse{}, err
 	}
+	schoolID, _ := uuid.Parse(course.SchoolID.String())
 
 	return PgCourse{
 		ID:       id,
@@ -63,6 +127,6 @@ func NewPgCourse(course domain.Course) PgCourse {
 		Level:    course.Level,
 		Price:    course.Price,
 		Language: course.Language,
-		Status:   status,
-	}
+		Status:   NewPgCourseStatus(course.Status),
+	}, nil
 }
@@ -1,9 +1,11 @@
 package entity
 
 import (
+	"fmt"
 	"github.com/google/uuid"
 	"github.com/guregu/null"
 	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
 	"strings"
 )
 
@@ -34,6 +36,11 @@ type PgTest struct {
 	Score    int       `db:"score"`
 }
 
+func (s *PgLesson) Validate() error {
+	return validateRequired("lesson",
+		requiredField{"title", s.Title})
+}
+
 func (s *PgLesson) ToDomain() domain.Lesson {
 	var lessonType domain.LessonType
 	switch s.Type {
@@ -57,8 +64,21 @@ func (s *PgLesson) ToDomain() domain.Lesson {
 	}
 }
 
-func NewPgLesson(lesson domain.Lesson) PgLesson {
-	id, _ := uuid.Parse(lesson.ID.String())
+// ToDomainChecked converts the row like ToDomain, but reports a type the
+// domain does not know instead of silently zeroing it.
+func (s *PgLesson) ToDomainChecked() (domain.Lesson, error) {
+	switch s.Type {
+	case PgLessonTheory, PgLessonVideo, PgLessonPractice:
+		return s.ToDomain(), nil
+	}
+	return domain.Lesson{}, fmt.Errorf("%w: lesson %s has type %q", errs.ErrEnumValueError, s.ID, s.Type)
+}
+
+func NewPgLesson(lesson domain.Lesson) (PgLesson, error) {
+	id, err := idOrNew(lesson.ID)
+	if err != nil {
+		return PgLesson{}, err
+	}
 	courseID, _ := uuid.Parse(lesson.CourseID.String())
 	var lessonType string
 	switch lesson.Type {
@@ -78,7 +98,7 @@ func NewPgLesson(lesson domain.Lesson) PgLesson {
 		Type:      lessonType,
 		TheoryUrl: lesson.TheoryUrl,
 		VideoUrl:  lesson.VideoUrl,
-	}
+	}, nil
 }
 
 func (s *PgTest) ToDomain() domain.Test {
@@ -94,8 +114,11 @@ func (s *PgTest) ToDomain() domain.Test {
 	}
 }
 
-func NewPgTest(test domain.Test) PgTest {
-	id, _ := uuid.Parse(test.ID.String())
+func NewPgTest(test domain.Test) (PgTest, error) {
+	id, err := idOrNew(test.ID)
+	if err != nil {
+		return PgTest{}, err
+	}
 	lessonID, _ := uuid.Parse(test.LessonID.String())
 	options := strings.Join(test.Options, "\n")
 	return PgTest{
@@ -106,5 +129,5 @@ func NewPgTest(test domain.Test) PgTest {
 		Answer:   test.Answer,
 		Level:    test.Level,
 		Score:    test.Score,
-	}
+	}, nil
 }
@@ -0,0 +1,14 @@
+package entity
+
+import (
+	"github.com/guregu/null"
+	"time"
+)
+
+type PgOutboxEvent struct {
+	ID          int64     `db:"id"`
+	Type        string    `db:"type"`
+	Payload     []byte    `db:"payload"`
+	CreatedAt   time.Time `db:"created_at"`
+	PublishedAt null.Time `db:"published_at"`
+}
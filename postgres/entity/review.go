@@ -1,15 +1,59 @@
 package entity
 
 import (
+	"fmt"
 	"github.com/google/uuid"
+	"github.com/guregu/null"
 	"github.com/paw1a/eschool-core/domain"
+	"time"
 )
 
 type PgReview struct {
-	ID       uuid.UUID `db:"id"`
-	UserID   uuid.UUID `db:"user_id"`
-	CourseID uuid.UUID `db:"course_id"`
-	Text     string    `db:"text"`
+	ID           uuid.UUID   `db:"id"`
+	UserID       uuid.UUID   `db:"user_id"`
+	CourseID     uuid.UUID   `db:"course_id"`
+	Text         string      `db:"text"`
+	Rating       null.Int    `db:"rating"`
+	FlagCount    int         `db:"flag_count"`
+	HelpfulCount int         `db:"helpful_count"`
+	CreatedAt    time.Time   `db:"created_at"`
+	UpdatedAt    time.Time   `db:"updated_at" readonly:"true"`
+	DeletedAt    null.Time   `db:"deleted_at"`
+	Response     null.String `db:"response" readonly:"true"`
+}
+
+type PgReviewWithContext struct {
+	PgReview
+	CourseName string    `db:"course_name"`
+	SchoolID   uuid.UUID `db:"school_id"`
+	SchoolName string    `db:"school_name"`
+}
+
+type PgRatingCount struct {
+	Rating int `db:"rating"`
+	Count  int `db:"count"`
+}
+
+type PgMonthlyRating struct {
+	Month         time.Time `db:"month"`
+	AverageRating float64   `db:"average_rating"`
+	ReviewCount   int       `db:"review_count"`
+}
+
+type PgRatingSummary struct {
+	AverageRating float64 `db:"average_rating"`
+	ReviewCount   int     `db:"review_count"`
+}
+
+func (r *PgReview) Validate() error {
+	if err := validateRequired("review",
+		requiredField{"text", r.Text}); err != nil {
+		return err
+	}
+	if r.Rating.Valid && (r.Rating.Int64 < 1 || r.Rating.Int64 > 5) {
+		return fmt.Errorf("%w: review.rating %d is not between 1 and 5", ErrValidation, r.Rating.Int64)
+	}
+	return nil
 }
 
 func (r *PgReview) ToDomain() domain.Review {
@@ -21,14 +65,29 @@ func (r *PgReview) ToDomain() domain.Review {
 	}
 }
 
-func NewPgReview(review domain.Review) PgReview {
-	id, _ := uuid.Parse(review.ID.String())
+func NewPgReview(review domain.Review) (PgReview, error) {
+	id, err := idOrNew(review.ID)
+	if err != nil {
+		return PgReview{}, err
+	}
 	userID, _ := uuid.Parse(review.UserID.String())
 	courseID, _ := uuid.Parse(review.CourseID.String())
 	return PgReview{
-		ID:       id,
-		UserID:   userID,
-		CourseID: courseID,
-		Text:     review.Text,
+		ID:        id,
+		UserID:    userID,
+		CourseID:  courseID,
+		Text:      review.Text,
+		CreatedAt: time.Now().UTC(),
+	}, nil
+}
+
+// NewPgRatedReview is NewPgReview with the star rating of the review, which
+// domain.Review does not carry.
+func NewPgRatedReview(review domain.Review, rating int) (PgReview, error) {
+	pgReview, err := NewPgReview(review)
+	if err != nil {
+		return PgReview{}, err
 	}
+	pgReview.Rating = null.IntFrom(int64(rating))
+	return pgReview, nil
 }
@@ -0,0 +1,87 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"reflect"
+)
+
+// SelectDTOs runs the query and maps every returned row into a T with
+// ScanRows, so that a joined or aggregate listing only needs its DTO.
+func SelectDTOs[T any](ctx context.Context, q sqlx.QueryerContext, query string, args ...interface{}) ([]T, error) {
+	rows, err := q.QueryxContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return ScanRows[T](rows)
+}
+
+// ScanRows maps every row into a T by the db tags of T and of the structs
+// embedded in it. A NULL column leaves its field at the zero value, so the
+// columns of an outer join can be scanned into plain fields. A column
+// without a matching tag is reported with ErrUnknownColumn.
+func ScanRows[T any](rows *sqlx.Rows) ([]T, error) {
+	columnNames, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+
+	var dto T
+	fields := fieldIndexes(reflect.TypeOf(dto), nil, make(map[string][]int))
+	indexes := make([][]int, len(columnNames))
+	for i, column := range columnNames {
+		index, ok := fields[column]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s is not a field of %T", ErrUnknownColumn, column, dto)
+		}
+		indexes[i] = index
+	}
+
+	result := make([]T, 0)
+	holders := make([]interface{}, len(columnNames))
+	for rows.Next() {
+		row := reflect.New(reflect.TypeOf(dto)).Elem()
+		// each column is scanned into a pointer to its field type, which
+		// database/sql leaves nil for NULL
+		for i, index := range indexes {
+			holders[i] = reflect.New(reflect.PointerTo(row.FieldByIndex(index).Type())).Interface()
+		}
+		if err = rows.Scan(holders...); err != nil {
+			return nil, err
+		}
+		for i, index := range indexes {
+			if value := reflect.ValueOf(holders[i]).Elem(); !value.IsNil() {
+				row.FieldByIndex(index).Set(value.Elem())
+			}
+		}
+		result = append(result, row.Interface().(T))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// fieldIndexes maps the db tags of t to the field index paths, the fields
+// of an outer struct shadow the ones of the structs it embeds.
+func fieldIndexes(t reflect.Type, prefix []int, fields map[string][]int) map[string][]int {
+	var embedded []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		index := append(append([]int(nil), prefix...), i)
+		if tag := field.Tag.Get("db"); tag != "" {
+			if _, ok := fields[tag]; !ok {
+				fields[tag] = index
+			}
+		} else if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			field.Index = index
+			embedded = append(embedded, field)
+		}
+	}
+	for _, field := range embedded {
+		fieldIndexes(field.Type, field.Index, fields)
+	}
+	return fields
+}
@@ -10,6 +10,41 @@ type PgSchool struct {
 	OwnerID     uuid.UUID `db:"owner_id"`
 	Name        string    `db:"name"`
 	Description string    `db:"description"`
+	Status      string    `db:"status" readonly:"true"`
+
+	// Slug is set by a trigger from the name and the id, so it is never
+	// written by the repository.
+	Slug string `db:"slug" readonly:"true"`
+}
+
+type PgSchoolWithTotal struct {
+	PgSchool
+	Total int `db:"total"`
+}
+
+type PgTeacherWithCount struct {
+	PgUser
+	CourseCount int `db:"course_count"`
+}
+
+type PgReviewerCount struct {
+	PgUser
+	ReviewCount int `db:"review_count"`
+}
+
+type PgSchoolWithMetric struct {
+	PgSchool
+	Metric int `db:"metric"`
+}
+
+type PgSchoolRole struct {
+	SchoolID uuid.UUID `db:"school_id"`
+	Role     string    `db:"role"`
+}
+
+func (s *PgSchool) Validate() error {
+	return validateRequired("school",
+		requiredField{"name", s.Name})
 }
 
 func (s *PgSchool) ToDomain() domain.School {
@@ -21,13 +56,16 @@ func (s *PgSchool) ToDomain() domain.School {
 	}
 }
 
-func NewPgSchool(school domain.School) PgSchool {
-	id, _ := uuid.Parse(school.ID.String())
+func NewPgSchool(school domain.School) (PgSchool, error) {
+	id, err := idOrNew(school.ID)
+	if err != nil {
+		return PgSchool{}, err
+	}
 	ownerID, _ := uuid.Parse(school.OwnerID.String())
 	return PgSchool{
 		ID:          id,
 		OwnerID:     ownerID,
 		Name:        school.Name,
 		Description: school.Description,
-	}
+	}, nil
 }
@@ -29,8 +29,11 @@ func (s *PgLessonStat) ToDomain() domain.LessonStat {
 	}
 }
 
-func NewPgLessonStat(stat domain.LessonStat) PgLessonStat {
-	id, _ := uuid.Parse(stat.ID.String())
+func NewPgLessonStat(stat domain.LessonStat) (PgLessonStat, error) {
+	id, err := idOrNew(stat.ID)
+	if err != nil {
+		return PgLessonStat{}, err
+	}
 	lessonID, _ := uuid.Parse(stat.LessonID.String())
 	userID, _ := uuid.Parse(stat.UserID.String())
 	return PgLessonStat{
@@ -38,7 +41,7 @@ func NewPgLessonStat(stat domain.LessonStat) PgLessonStat {
 		LessonID: lessonID,
 		UserID:   userID,
 		Score:    stat.Score,
-	}
+	}, nil
 }
 
 func (s *PgTestStat) ToDomain() domain.TestStat {
@@ -50,8 +53,11 @@ func (s *PgTestStat) ToDomain() domain.TestStat {
 	}
 }
 
-func NewPgTestStat(stat domain.TestStat) PgTestStat {
-	id, _ := uuid.Parse(stat.ID.String())
+func NewPgTestStat(stat domain.TestStat) (PgTestStat, error) {
+	id, err := idOrNew(stat.ID)
+	if err != nil {
+		return PgTestStat{}, err
+	}
 	testID, _ := uuid.Parse(stat.TestID.String())
 	userID, _ := uuid.Parse(stat.UserID.String())
 	return PgTestStat{
@@ -59,5 +65,5 @@ func NewPgTestStat(stat domain.TestStat) PgTestStat {
 		TestID: testID,
 		UserID: userID,
 		Score:  stat.Score,
-	}
+	}, nil
 }
@@ -4,6 +4,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/guregu/null"
 	"github.com/paw1a/eschool-core/domain"
+	"time"
 )
 
 type PgUser struct {
@@ -15,6 +16,33 @@ type PgUser struct {
 	AvatarUrl null.String `db:"avatar_url"`
 	Email     string      `db:"email"`
 	Password  string      `db:"password"`
+	CreatedAt time.Time   `db:"created_at" readonly:"true"`
+	UpdatedAt time.Time   `db:"updated_at" readonly:"true"`
+}
+
+type PgEmailGroup struct {
+	Email   string `db:"email"`
+	UserIDs string `db:"user_ids"`
+	Count   int    `db:"count"`
+}
+
+type PgUserActivity struct {
+	CoursesCompleted int `db:"courses_completed"`
+	ReviewsWritten   int `db:"reviews_written"`
+	SchoolsOwned     int `db:"schools_owned"`
+}
+
+type PgDateCount struct {
+	Date  time.Time `db:"date"`
+	Count int       `db:"count"`
+}
+
+func (u *PgUser) Validate() error {
+	return validateRequired("user",
+		requiredField{"email", u.Email},
+		requiredField{"password", u.Password},
+		requiredField{"name", u.Name},
+		requiredField{"surname", u.Surname})
 }
 
 func (u *PgUser) ToDomain() domain.User {
@@ -30,8 +58,11 @@ func (u *PgUser) ToDomain() domain.User {
 	}
 }
 
-func NewPgUser(user domain.User) PgUser {
-	id, _ := uuid.Parse(user.ID.String())
+func NewPgUser(user domain.User) (PgUser, error) {
+	id, err := idOrNew(user.ID)
+	if err != nil {
+		return PgUser{}, err
+	}
 	return PgUser{
 		ID:        id,
 		Name:      user.Name,
@@ -41,5 +72,5 @@ func NewPgUser(user domain.User) PgUser {
 		AvatarUrl: user.AvatarUrl,
 		Email:     user.Email,
 		Password:  user.Password,
-	}
+	}, nil
 }
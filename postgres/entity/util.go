@@ -1,12 +1,48 @@
 package entity
 
 import (
+	"errors"
 	"fmt"
+	"github.com/google/uuid"
+	"github.com/paw1a/eschool-core/domain"
 	"reflect"
+	"regexp"
+	"sort"
 	"strings"
 )
 
+var ErrInvalidNamedQuery = errors.New("invalid named query")
+var ErrUnknownColumn = errors.New("unknown column")
+var ErrValidation = errors.New("validation failed")
+
+var namedParamRegexp = regexp.MustCompile(`(^|[^:]):([a-zA-Z_][a-zA-Z0-9_]*)`)
+
+// idOrNew parses the id the caller chose for a new entity, or generates one
+// when the caller left it empty. An id that is not a uuid is rejected with
+// ErrValidation.
+func idOrNew(id domain.ID) (uuid.UUID, error) {
+	if id == "" {
+		return uuid.New(), nil
+	}
+	parsed, err := uuid.Parse(id.String())
+	if err != nil {
+		return uuid.UUID{}, fmt.Errorf("%w: id %q is not a uuid", ErrValidation, id)
+	}
+	return parsed, nil
+}
+
 func entityColumns(entity interface{}) []string {
+	return columns(entity, true)
+}
+
+// writableColumns leaves out the fields tagged readonly:"true", such as
+// timestamps filled in by a column default, so that inserts and updates
+// never overwrite them.
+func writableColumns(entity interface{}) []string {
+	return columns(entity, false)
+}
+
+func columns(entity interface{}, withReadOnly bool) []string {
 	v := reflect.ValueOf(entity)
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
@@ -14,7 +50,11 @@ func entityColumns(entity interface{}) []string {
 	var fields []string
 	if v.Kind() == reflect.Struct {
 		for i := 0; i < v.NumField(); i++ {
-			field := v.Type().Field(i).Tag.Get("db")
+			tag := v.Type().Field(i).Tag
+			field := tag.Get("db")
+			if !withReadOnly && tag.Get("readonly") == "true" {
+				continue
+			}
 			if field != "" {
 				fields = append(fields, field)
 			}
@@ -28,8 +68,30 @@ func entityColumns(entity interface{}) []string {
 	return fields
 }
 
+// SelectByIDQueryString builds a query selecting only the given columns of
+// the row with the given id. Every column must be a db tag of the entity.
+// Columns are sorted, so the same set of columns always gives the same query.
+func SelectByIDQueryString(entity interface{}, tableName string, columns []string) (string, error) {
+	known := make(map[string]bool)
+	for _, column := range entityColumns(entity) {
+		known[column] = true
+	}
+	if len(columns) == 0 {
+		columns = entityColumns(entity)
+	}
+	columns = append([]string(nil), columns...)
+	sort.Strings(columns)
+	for _, column := range columns {
+		if !known[column] {
+			return "", fmt.Errorf("%w: %s is not a column of %s", ErrUnknownColumn, column, tableName)
+		}
+	}
+	return fmt.Sprintf("SELECT %s FROM public.%s WHERE id = ?",
+		strings.Join(columns, ", "), tableName), nil
+}
+
 func UpdateQueryString(entity interface{}, tableName string) string {
-	columnNames := entityColumns(entity)
+	columnNames := writableColumns(entity)
 	params := make([]string, len(columnNames))
 	for i, columnName := range columnNames {
 		params[i] = fmt.Sprintf("%s = :%s", columnName, columnName)
@@ -39,8 +101,41 @@ func UpdateQueryString(entity interface{}, tableName string) string {
 		tableName, paramsString)
 }
 
+// PatchQueryString builds a query updating only the given columns of the
+// row with the id bound last, returning the updated row, together with the
+// values to bind. Every column must be a writable db tag of the entity other
+// than the id, and at least one is required. Columns are sorted, so the same
+// set of columns always gives the same query.
+func PatchQueryString(entity interface{}, tableName string, fields map[string]any) (string, []any, error) {
+	if len(fields) == 0 {
+		return "", nil, fmt.Errorf("%w: empty %s patch", ErrValidation, tableName)
+	}
+	writable := make(map[string]bool)
+	for _, column := range writableColumns(entity) {
+		writable[column] = column != "id"
+	}
+
+	columnNames := make([]string, 0, len(fields))
+	for column := range fields {
+		if !writable[column] {
+			return "", nil, fmt.Errorf("%w: %s is not a writable column of %s", ErrUnknownColumn, column, tableName)
+		}
+		columnNames = append(columnNames, column)
+	}
+	sort.Strings(columnNames)
+
+	params := make([]string, len(columnNames))
+	args := make([]any, len(columnNames))
+	for i, columnName := range columnNames {
+		params[i] = columnName + " = ?"
+		args[i] = fields[columnName]
+	}
+	return fmt.Sprintf("UPDATE public.%s SET %s WHERE id = ? RETURNING *",
+		tableName, strings.Join(params, ", ")), args, nil
+}
+
 func InsertQueryString(entity interface{}, tableName string) string {
-	columnNames := entityColumns(entity)
+	columnNames := writableColumns(entity)
 	values := make([]string, len(columnNames))
 	for i, columnName := range columnNames {
 		values[i] = fmt.Sprintf(":%s", columnName)
@@ -50,3 +145,51 @@ func InsertQueryString(entity interface{}, tableName string) string {
 	return fmt.Sprintf("INSERT INTO public.%s (%s) VALUES (%s) RETURNING *",
 		tableName, columnsString, valuesString)
 }
+
+// ValidateNamedQuery checks that every exported field of the entity is mapped
+// to a column by a db tag and that every named parameter of the query can be
+// bound from the entity, so that a mismatch is reported before execution.
+func ValidateNamedQuery(entity interface{}, query string) error {
+	v := reflect.ValueOf(entity)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() == reflect.Struct {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.IsExported() && field.Tag.Get("db") == "" {
+				return fmt.Errorf("%w: field %s.%s has no db tag",
+					ErrInvalidNamedQuery, t.Name(), field.Name)
+			}
+		}
+	}
+
+	columns := make(map[string]bool)
+	for _, column := range entityColumns(entity) {
+		columns[column] = true
+	}
+	for _, match := range namedParamRegexp.FindAllStringSubmatch(query, -1) {
+		if !columns[match[2]] {
+			return fmt.Errorf("%w: parameter :%s is not bound to any field of %s",
+				ErrInvalidNamedQuery, match[2], v.Type().Name())
+		}
+	}
+	return nil
+}
+
+type requiredField struct {
+	name  string
+	value string
+}
+
+// validateRequired reports the first required field left empty, so that a
+// missing value is rejected before the insert instead of by the database.
+func validateRequired(tableName string, fields ...requiredField) error {
+	for _, field := range fields {
+		if strings.TrimSpace(field.value) == "" {
+			return fmt.Errorf("%w: %s.%s is required", ErrValidation, tableName, field.name)
+		}
+	}
+	return nil
+}
@@ -1,4 +1,72 @@
 package repository
 
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+	"strings"
+)
+
 var PgUniqueViolationCode = "23505"
 var PgEnumValueError = "22P02"
+var PgForeignKeyViolationCode = "23503"
+
+var ErrServiceUnavailable = errors.New("service unavailable")
+var ErrIllegalTransition = errors.New("illegal status transition")
+var ErrInvalidDiscount = errors.New("discount percent out of range")
+var ErrInvalidBucket = errors.New("invalid time bucket")
+var ErrResultTooLarge = errors.New("result exceeds the maximum number of rows")
+
+// ErrIDConflict is a duplicate whose primary key collided, which points at a
+// reused id or a retried create rather than at taken business data. It is
+// still an errs.ErrDuplicate for the callers that do not tell them apart.
+var ErrIDConflict = fmt.Errorf("%w: id conflict", errs.ErrDuplicate)
+
+// ErrOwnerNotExist is returned when a school is created for an owner that
+// does not exist, it is an errs.ErrNotExist.
+var ErrOwnerNotExist = fmt.Errorf("%w: school owner", errs.ErrNotExist)
+
+// persistenceError wraps a failed query error. When the query failed because
+// the context expired while every pool connection was busy, or because the
+// circuit breaker is open, the error is mapped to ErrServiceUnavailable
+// instead of the generic persistence failure.
+func persistenceError(db *sqlx.DB, err error) error {
+	if errors.Is(err, ErrCircuitOpen) || isPoolExhausted(db, err) {
+		return errors.Wrap(ErrServiceUnavailable, err.Error())
+	}
+	return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+}
+
+func isPoolExhausted(db *sqlx.DB, err error) bool {
+	if !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, context.Canceled) {
+		return false
+	}
+	stats := db.Stats()
+	return stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections
+}
+
+// uniqueViolationError tells a primary key collision, reported as
+// ErrIDConflict, from a violated business unique key, such as a taken email,
+// reported as errs.ErrDuplicate.
+func uniqueViolationError(pgErr *pgconn.PgError, err error) error {
+	if strings.HasSuffix(pgErr.ConstraintName, "_pkey") {
+		return errors.Wrap(ErrIDConflict, err.Error())
+	}
+	return errors.Wrap(errs.ErrDuplicate, err.Error())
+}
+
+// namedExecContext validates the named query against the entity before
+// executing it, so that a missing db tag or an unbound parameter is reported
+// with the offending field instead of a driver error.
+func namedExecContext(ctx context.Context, ext sqlx.ExtContext,
+	query string, arg interface{}) (sql.Result, error) {
+	if err := entity.ValidateNamedQuery(arg, query); err != nil {
+		return nil, err
+	}
+	return sqlx.NamedExecContext(ctx, ext, query, arg)
+}
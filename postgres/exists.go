@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/pkg/errors"
+)
+
+// existsByIDs reports for every given id whether query, selecting the ids
+// of a table found among the ids bound to its IN list, returns it. The ids
+// are looked up 1000 at a time. method labels the lookup like the finders
+// label theirs, such as "user.ExistsByIDs".
+func existsByIDs(ctx context.Context, db *sqlx.DB, opts options, method, query string,
+	ids []domain.ID) (map[domain.ID]bool, error) {
+	if err := opts.checkRateLimit(method); err != nil {
+		return nil, err
+	}
+
+	exists := make(map[domain.ID]bool, len(ids))
+	if len(ids) == 0 {
+		return exists, nil
+	}
+
+	var found []domain.ID
+	for _, chunk := range chunkIDs(ids, inChunkSize) {
+		chunkQuery, args, err := sqlx.In(query, chunk)
+		if err != nil {
+			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+		}
+
+		var foundChunk []domain.ID
+		if err = selectRows(ctx, db, opts, method, &foundChunk, chunkQuery, args...); err != nil {
+			return nil, err
+		}
+		found = append(found, foundChunk...)
+	}
+	opts.observeRows(method, len(found))
+
+	for _, id := range ids {
+		exists[id] = false
+	}
+	for _, id := range found {
+		exists[id] = true
+	}
+	return exists, nil
+}
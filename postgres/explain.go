@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/pkg/errors"
+	"strings"
+)
+
+var ErrExplainDisabled = errors.New("query explain is disabled")
+var ErrUnknownMethod = errors.New("unknown repository method")
+
+// explainQueries maps the repository methods, labeled like the rows
+// observer labels them, to the query they run. Only read queries are listed,
+// EXPLAIN ANALYZE executes the query it explains.
+var explainQueries = map[string]string{
+	"user.FindAll":                     userFindAllQuery,
+	"user.FindByID":                    userFindByIDQuery,
+	"user.FindByEmail":                 userFindByEmailQuery,
+	"user.FindUsersModifiedSince":      userFindModifiedSinceQuery,
+	"school.FindAll":                   schoolFindAllQuery,
+	"school.FindByID":                  schoolFindByIDQuery,
+	"school.FindSchoolCourses":         schoolFindSchoolCoursesQuery,
+	"school.FindSchoolTeachers":        schoolFindSchoolTeachersQuery,
+	"course.FindAll":                   courseFindAllQuery,
+	"course.FindByID":                  courseFindByIDQuery,
+	"course.FindStudentCourses":        courseFindStudentCoursesQuery,
+	"course.FindCoursesByLanguage":     courseFindByLanguageQuery,
+	"lesson.FindAll":                   lessonFindAllQuery,
+	"lesson.FindByID":                  lessonFindByIDQuery,
+	"review.FindAll":                   reviewFindAllQuery,
+	"review.FindByID":                  reviewFindByIDQuery,
+	"review.FindCourseReviews":         reviewFindCourseReviewsQuery,
+	"review.FindFlaggedReviews":        reviewFindFlaggedReviewsQuery,
+	"certificate.FindAll":              certificateFindAllQuery,
+	"certificate.FindByID":             certificateFindByIDQuery,
+	"certificate.FindUserCertificates": certificateFindUserCertificatesQuery,
+}
+
+// Explainer profiles the repository queries against the database. It runs
+// EXPLAIN (ANALYZE, BUFFERS), which executes the query, so it is meant for
+// debugging and refuses to run unless built with WithExplain.
+type Explainer struct {
+	db   *sqlx.DB
+	opts options
+}
+
+func NewExplainer(db *sqlx.DB, opts ...Option) *Explainer {
+	return &Explainer{
+		db:   db,
+		opts: newOptions(opts),
+	}
+}
+
+// ExplainQuery returns the plan of the query run by method, such as
+// "user.FindByID", executed with args. The query runs in a transaction that
+// is rolled back.
+func (e *Explainer) ExplainQuery(ctx context.Context, method string, args ...any) (string, error) {
+	if !e.opts.explain {
+		return "", errors.WithStack(ErrExplainDisabled)
+	}
+	query, ok := explainQueries[method]
+	if !ok {
+		return "", errors.Wrap(ErrUnknownMethod, method)
+	}
+
+	tx, err := e.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return "", errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+	defer tx.Rollback()
+
+	var plan []string
+	if err = tx.SelectContext(ctx, &plan, tx.Rebind("EXPLAIN (ANALYZE, BUFFERS) "+query), args...); err != nil {
+		return "", persistenceError(e.db, err)
+	}
+	return strings.Join(plan, "\n"), nil
+}
@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Pinger is the part of *sqlx.DB a HealthWatcher needs.
+type Pinger interface {
+	PingContext(ctx context.Context) error
+}
+
+// HealthWatcher pings the database in the background so that an outage is
+// noticed before a request fails on it. The database is reported unhealthy
+// once threshold pings in a row failed and healthy again after the first
+// successful ping.
+type HealthWatcher struct {
+	db        Pinger
+	interval  time.Duration
+	threshold int
+
+	mu          sync.Mutex
+	healthy     bool
+	failures    int
+	subscribers []chan bool
+	stop        chan struct{}
+	done        chan struct{}
+}
+
+// NewHealthWatcher creates a watcher pinging every interval, each ping is
+// given interval to answer. The database is assumed healthy until proven
+// otherwise.
+func NewHealthWatcher(db Pinger, interval time.Duration, threshold int) *HealthWatcher {
+	return &HealthWatcher{
+		db:        db,
+		interval:  interval,
+		threshold: threshold,
+		healthy:   true,
+	}
+}
+
+// Start pings the database every interval until ctx is done or Close is
+// called.
+func (w *HealthWatcher) Start(ctx context.Context) {
+	w.mu.Lock()
+	if w.stop != nil {
+		w.mu.Unlock()
+		return
+	}
+	w.stop = make(chan struct{})
+	w.done = make(chan struct{})
+	stop, done := w.stop, w.done
+	w.mu.Unlock()
+
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(w.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-stop:
+				return
+			case <-ticker.C:
+				w.Check(ctx)
+			}
+		}
+	}()
+}
+
+// Close stops the pings started by Start and waits for the last one.
+func (w *HealthWatcher) Close() {
+	w.mu.Lock()
+	stop, done := w.stop, w.done
+	w.stop, w.done = nil, nil
+	w.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+}
+
+// Check pings the database once, updates the state and returns it.
+func (w *HealthWatcher) Check(ctx context.Context) bool {
+	pingCtx, cancel := context.WithTimeout(ctx, w.interval)
+	err := w.db.PingContext(pingCtx)
+	cancel()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err != nil {
+		w.failures++
+		if w.healthy && w.failures >= w.threshold {
+			w.setHealthy(false)
+		}
+	} else {
+		w.failures = 0
+		if !w.healthy {
+			w.setHealthy(true)
+		}
+	}
+	return w.healthy
+}
+
+func (w *HealthWatcher) IsHealthy() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.healthy
+}
+
+// Subscribe returns a channel receiving the new state on every transition.
+// A subscriber that does not keep up only gets the latest state.
+func (w *HealthWatcher) Subscribe() <-chan bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	subscriber := make(chan bool, 1)
+	w.subscribers = append(w.subscribers, subscriber)
+	return subscriber
+}
+
+// setHealthy records the transition and notifies the subscribers, the
+// caller holds w.mu.
+func (w *HealthWatcher) setHealthy(healthy bool) {
+	w.healthy = healthy
+	for _, subscriber := range w.subscribers {
+		select {
+		case <-subscriber:
+		default:
+		}
+		subscriber <- healthy
+	}
+}
@@ -12,37 +12,44 @@ import (
 )
 
 type PostgresLessonRepo struct {
-	db *sqlx.DB
+	db   *sqlx.DB
+	opts options
 }
 
-func NewLessonRepo(db *sqlx.DB) *PostgresLessonRepo {
+func NewLessonRepo(db *sqlx.DB, opts ...Option) *PostgresLessonRepo {
 	return &PostgresLessonRepo{
-		db: db,
+		db:   db,
+		opts: newOptions(opts),
 	}
 }
 
 const (
 	lessonFindAllQuery            = "SELECT * FROM public.lesson ORDER BY id"
-	lessonFindByIDQuery           = "SELECT * FROM public.lesson WHERE id = $1"
-	lessonFindStudentCoursesQuery = "SELECT * FROM public.lesson WHERE course_id = $1"
-	lessonFindLessonTestsQuery    = "SELECT * FROM public.test WHERE lesson_id = $1"
-	lessonDeleteQuery             = "DELETE FROM public.lesson WHERE id = $1"
-	lessonDeleteLessonTestsQuery  = "DELETE FROM public.test WHERE lesson_id = $1"
+	lessonFindByIDQuery           = "SELECT * FROM public.lesson WHERE id = ?"
+	lessonFindStudentCoursesQuery = "SELECT * FROM public.lesson WHERE course_id = ?"
+	lessonFindLessonTestsQuery    = "SELECT * FROM public.test WHERE lesson_id = ?"
+	lessonDeleteQuery             = "DELETE FROM public.lesson WHERE id = ?"
+	lessonDeleteLessonTestsQuery  = "DELETE FROM public.test WHERE lesson_id = ?"
 )
 
 func (p *PostgresLessonRepo) FindAll(ctx context.Context) ([]domain.Lesson, error) {
+	if err := p.opts.checkRateLimit("lesson.FindAll"); err != nil {
+		return nil, err
+	}
+
 	var pgLessons []entity.PgLesson
-	if err := p.db.SelectContext(ctx, &pgLessons, lessonFindAllQuery); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, p.db, p.opts, "lesson.FindAll", &pgLessons, lessonFindAllQuery); err != nil {
+		return nil, err
 	}
+	p.opts.observeRows("lesson.FindAll", len(pgLessons))
 
 	lessons := make([]domain.Lesson, len(pgLessons))
 	for i, lesson := range pgLessons {
-		lessons[i] = lesson.ToDomain()
+		domainLesson, err := lesson.ToDomainChecked()
+		if err != nil {
+			return nil, err
+		}
+		lessons[i] = domainLesson
 		if lesson.Type == entity.PgLessonPractice {
 			tests, err := p.FindLessonTests(ctx, lessons[i].ID)
 			if err != nil {
@@ -56,14 +63,18 @@ func (p *PostgresLessonRepo) FindAll(ctx context.Context) ([]domain.Lesson, erro
 
 func (p *PostgresLessonRepo) FindByID(ctx context.Context, lessonID domain.ID) (domain.Lesson, error) {
 	var pgLesson entity.PgLesson
-	if err := p.db.GetContext(ctx, &pgLesson, lessonFindByIDQuery, lessonID); err != nil {
+	db := p.opts.reader(ctx, p.db)
+	if err := db.GetContext(ctx, &pgLesson, db.Rebind(lessonFindByIDQuery), lessonID); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.Lesson{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Lesson{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Lesson{}, persistenceError(db, err)
 		}
 	}
-	lesson := pgLesson.ToDomain()
+	lesson, err := pgLesson.ToDomainChecked()
+	if err != nil {
+		return domain.Lesson{}, err
+	}
 	if pgLesson.Type == entity.PgLessonPractice {
 		tests, err := p.FindLessonTests(ctx, lessonID)
 		if err != nil {
@@ -76,18 +87,24 @@ func (p *PostgresLessonRepo) FindByID(ctx context.Context, lessonID domain.ID) (
 
 func (p *PostgresLessonRepo) FindCourseLessons(ctx context.Context,
 	courseID domain.ID) ([]domain.Lesson, error) {
+	if err := p.opts.checkRateLimit("lesson.FindCourseLessons"); err != nil {
+		return nil, err
+	}
+
 	var pgLessons []entity.PgLesson
-	if err := p.db.SelectContext(ctx, &pgLessons, lessonFindStudentCoursesQuery, courseID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, p.db, p.opts, "lesson.FindCourseLessons", &pgLessons,
+		lessonFindStudentCoursesQuery, courseID); err != nil {
+		return nil, err
 	}
+	p.opts.observeRows("lesson.FindCourseLessons", len(pgLessons))
 
 	lessons := make([]domain.Lesson, len(pgLessons))
 	for i, lesson := range pgLessons {
-		lessons[i] = lesson.ToDomain()
+		domainLesson, err := lesson.ToDomainChecked()
+		if err != nil {
+			return nil, err
+		}
+		lessons[i] = domainLesson
 		if lesson.Type == entity.PgLessonPractice {
 			tests, err := p.FindLessonTests(ctx, lessons[i].ID)
 			if err != nil {
@@ -100,14 +117,16 @@ func (p *PostgresLessonRepo) FindCourseLessons(ctx context.Context,
 }
 
 func (p *PostgresLessonRepo) FindLessonTests(ctx context.Context, lessonID domain.ID) ([]domain.Test, error) {
+	if err := p.opts.checkRateLimit("lesson.FindLessonTests"); err != nil {
+		return nil, err
+	}
+
 	var pgTests []entity.PgTest
-	if err := p.db.SelectContext(ctx, &pgTests, lessonFindLessonTestsQuery, lessonID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, p.db, p.opts, "lesson.FindLessonTests", &pgTests,
+		lessonFindLessonTestsQuery, lessonID); err != nil {
+		return nil, err
 	}
+	p.opts.observeRows("lesson.FindLessonTests", len(pgTests))
 
 	tests := make([]domain.Test, len(pgTests))
 	for i, test := range pgTests {
@@ -117,44 +136,63 @@ func (p *PostgresLessonRepo) FindLessonTests(ctx context.Context, lessonID domai
 }
 
 func (p *PostgresLessonRepo) Create(ctx context.Context, lesson domain.Lesson) (domain.Lesson, error) {
-	tx, err := p.db.Beginx()
+	if err := p.opts.checkWritable(); err != nil {
+		return domain.Lesson{}, err
+	}
+
+	pgLesson, err := entity.NewPgLesson(lesson)
+	if err != nil {
+		return domain.Lesson{}, err
+	}
+	if err := pgLesson.Validate(); err != nil {
+		return domain.Lesson{}, err
+	}
+	lesson.ID = domain.ID(pgLesson.ID.String())
+
+	tx, err := p.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return domain.Lesson{}, errors.Wrap(errs.ErrTransactionError, err.Error())
 	}
 
-	var pgLesson = entity.NewPgLesson(lesson)
 	queryString := entity.InsertQueryString(pgLesson, "lesson")
-	_, err = tx.NamedExecContext(ctx, queryString, pgLesson)
+	_, err = namedExecContext(ctx, tx, queryString, pgLesson)
 	if err != nil {
 		tx.Rollback()
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
-				return domain.Lesson{}, errors.Wrap(errs.ErrDuplicate, err.Error())
+				return domain.Lesson{}, uniqueViolationError(pgErr, err)
 			} else {
-				return domain.Lesson{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return domain.Lesson{}, persistenceError(p.db, err)
 			}
 		} else {
-			return domain.Lesson{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Lesson{}, persistenceError(p.db, err)
 		}
 	}
 
 	if pgLesson.Type == entity.PgLessonPractice {
 		for _, test := range lesson.Tests {
-			var pgTest = entity.NewPgTest(test)
+			if test.LessonID == "" {
+				test.LessonID = lesson.ID
+			}
+			pgTest, err := entity.NewPgTest(test)
+			if err != nil {
+				tx.Rollback()
+				return domain.Lesson{}, err
+			}
 			queryString := entity.InsertQueryString(pgTest, "test")
-			_, err = tx.NamedExecContext(ctx, queryString, pgTest)
+			_, err = namedExecContext(ctx, tx, queryString, pgTest)
 			if err != nil {
 				tx.Rollback()
 				var pgErr *pgconn.PgError
 				if errors.As(err, &pgErr) {
 					if pgErr.Code == PgUniqueViolationCode {
-						return domain.Lesson{}, errors.Wrap(errs.ErrDuplicate, err.Error())
+						return domain.Lesson{}, uniqueViolationError(pgErr, err)
 					} else {
-						return domain.Lesson{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+						return domain.Lesson{}, persistenceError(p.db, err)
 					}
 				} else {
-					return domain.Lesson{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+					return domain.Lesson{}, persistenceError(p.db, err)
 				}
 			}
 		}
@@ -165,34 +203,46 @@ func (p *PostgresLessonRepo) Create(ctx context.Context, lesson domain.Lesson) (
 		return domain.Lesson{}, errors.Wrap(errs.ErrTransactionError, err.Error())
 	}
 
-	return p.FindByID(ctx, lesson.ID)
+	return p.FindByID(ReadYourWrites(ctx), lesson.ID)
 }
 
 func (p *PostgresLessonRepo) Update(ctx context.Context, lesson domain.Lesson) (domain.Lesson, error) {
-	tx, err := p.db.Beginx()
+	if err := p.opts.checkWritable(); err != nil {
+		return domain.Lesson{}, err
+	}
+
+	tx, err := p.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return domain.Lesson{}, errors.Wrap(errs.ErrTransactionError, err.Error())
 	}
 
-	var pgLesson = entity.NewPgLesson(lesson)
+	pgLesson, err := entity.NewPgLesson(lesson)
+	if err != nil {
+		tx.Rollback()
+		return domain.Lesson{}, err
+	}
 	queryString := entity.UpdateQueryString(pgLesson, "lesson")
-	_, err = tx.NamedExecContext(ctx, queryString, pgLesson)
+	_, err = namedExecContext(ctx, tx, queryString, pgLesson)
 	if err != nil {
 		tx.Rollback()
 		return domain.Lesson{}, errors.Wrap(errs.ErrUpdateFailed, err.Error())
 	}
 
 	if pgLesson.Type == entity.PgLessonPractice {
-		_, err = tx.ExecContext(ctx, lessonDeleteLessonTestsQuery, lesson.ID)
+		_, err = tx.ExecContext(ctx, tx.Rebind(lessonDeleteLessonTestsQuery), lesson.ID)
 		if err != nil {
 			tx.Rollback()
 			return domain.Lesson{}, errors.Wrap(errs.ErrUpdateFailed, err.Error())
 		}
 
 		for _, test := range lesson.Tests {
-			var pgTest = entity.NewPgTest(test)
+			pgTest, err := entity.NewPgTest(test)
+			if err != nil {
+				tx.Rollback()
+				return domain.Lesson{}, err
+			}
 			queryString := entity.InsertQueryString(pgTest, "test")
-			_, err = tx.NamedExecContext(ctx, queryString, pgTest)
+			_, err = namedExecContext(ctx, tx, queryString, pgTest)
 			if err != nil {
 				tx.Rollback()
 				var pgErr *pgconn.PgError
@@ -200,10 +250,10 @@ func (p *PostgresLessonRepo) Update(ctx context.Context, lesson domain.Lesson) (
 					if pgErr.Code == PgUniqueViolationCode {
 						return domain.Lesson{}, errors.Wrap(errs.ErrDuplicate, err.Error())
 					} else {
-						return domain.Lesson{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+						return domain.Lesson{}, persistenceError(p.db, err)
 					}
 				} else {
-					return domain.Lesson{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+					return domain.Lesson{}, persistenceError(p.db, err)
 				}
 			}
 		}
@@ -214,11 +264,15 @@ func (p *PostgresLessonRepo) Update(ctx context.Context, lesson domain.Lesson) (
 		return domain.Lesson{}, errors.Wrap(errs.ErrTransactionError, err.Error())
 	}
 
-	return p.FindByID(ctx, lesson.ID)
+	return p.FindByID(ReadYourWrites(ctx), lesson.ID)
 }
 
 func (p *PostgresLessonRepo) Delete(ctx context.Context, lessonID domain.ID) error {
-	_, err := p.db.ExecContext(ctx, lessonDeleteQuery, lessonID)
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := p.db.ExecContext(ctx, p.db.Rebind(lessonDeleteQuery), lessonID)
 	if err != nil {
 		return errors.Wrap(errs.ErrDeleteFailed, err.Error())
 	}
@@ -0,0 +1,192 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/pkg/errors"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+var migrationFileRegexp = regexp.MustCompile(`^([0-9]+)_(.*)\.(up|down)\.sql$`)
+
+const (
+	migrationsCreateTableQuery = "CREATE TABLE IF NOT EXISTS public.schema_migrations " +
+		"(version bigint NOT NULL PRIMARY KEY, dirty boolean NOT NULL)"
+	migrationsLockQuery          = "LOCK TABLE public.schema_migrations IN ACCESS EXCLUSIVE MODE"
+	migrationsFindVersionQuery   = "SELECT version FROM public.schema_migrations LIMIT 1"
+	migrationsDeleteVersionQuery = "DELETE FROM public.schema_migrations"
+	migrationsInsertVersionQuery = "INSERT INTO public.schema_migrations (version, dirty) VALUES ($1, false)"
+	migrationsDirectory          = "migrations"
+)
+
+type migration struct {
+	version uint
+	name    string
+	up      string
+	down    string
+}
+
+// Migrate applies every embedded migration newer than the current schema
+// version. Each migration runs in its own transaction, the version is kept
+// in public.schema_migrations the same way golang-migrate does.
+func Migrate(ctx context.Context, db *sqlx.DB) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		err = runMigration(ctx, db, func(tx *sqlx.Tx, current uint) (bool, error) {
+			if m.version <= current {
+				return false, nil
+			}
+			if _, err := tx.ExecContext(ctx, m.up); err != nil {
+				return false, errors.Wrapf(errs.ErrPersistenceFailed,
+					"migration %d_%s up: %s", m.version, m.name, err.Error())
+			}
+			return true, setMigrationVersion(ctx, tx, m.version)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Rollback reverts the latest applied migration.
+func Rollback(ctx context.Context, db *sqlx.DB) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return err
+	}
+
+	return runMigration(ctx, db, func(tx *sqlx.Tx, current uint) (bool, error) {
+		for i := len(migrations) - 1; i >= 0; i-- {
+			m := migrations[i]
+			if m.version != current {
+				continue
+			}
+			if _, err := tx.ExecContext(ctx, m.down); err != nil {
+				return false, errors.Wrapf(errs.ErrPersistenceFailed,
+					"migration %d_%s down: %s", m.version, m.name, err.Error())
+			}
+			var previous uint
+			if i > 0 {
+				previous = migrations[i-1].version
+			}
+			return true, setMigrationVersion(ctx, tx, previous)
+		}
+		if current == 0 {
+			return false, nil
+		}
+		return false, errors.Wrapf(errs.ErrNotExist, "migration %d is not embedded", current)
+	})
+}
+
+func runMigration(ctx context.Context, db *sqlx.DB,
+	apply func(tx *sqlx.Tx, current uint) (bool, error)) error {
+	if _, err := db.ExecContext(ctx, migrationsCreateTableQuery); err != nil {
+		return persistenceError(db, err)
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+
+	if _, err = tx.ExecContext(ctx, migrationsLockQuery); err != nil {
+		tx.Rollback()
+		return persistenceError(db, err)
+	}
+
+	var current uint
+	if err = tx.GetContext(ctx, &current, migrationsFindVersionQuery); err != nil {
+		if err != sql.ErrNoRows {
+			tx.Rollback()
+			return persistenceError(db, err)
+		}
+	}
+
+	applied, err := apply(tx, current)
+	if err != nil || !applied {
+		tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+	return nil
+}
+
+func setMigrationVersion(ctx context.Context, tx *sqlx.Tx, version uint) error {
+	if _, err := tx.ExecContext(ctx, migrationsDeleteVersionQuery); err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	if version == 0 {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, migrationsInsertVersionQuery, version); err != nil {
+		return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+	return nil
+}
+
+func loadMigrations() ([]migration, error) {
+	entries, err := migrationsFS.ReadDir(migrationsDirectory)
+	if err != nil {
+		return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+
+	byVersion := make(map[uint]*migration)
+	for _, e := range entries {
+		match := migrationFileRegexp.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+		version, err := strconv.ParseUint(match[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+		}
+		body, err := migrationsFS.ReadFile(path.Join(migrationsDirectory, e.Name()))
+		if err != nil {
+			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+		}
+
+		m, ok := byVersion[uint(version)]
+		if !ok {
+			m = &migration{version: uint(version), name: match[2]}
+			byVersion[uint(version)] = m
+		}
+		switch match[3] {
+		case "up":
+			m.up = string(body)
+		case "down":
+			m.down = string(body)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.up == "" || m.down == "" {
+			return nil, errors.Wrap(errs.ErrPersistenceFailed,
+				fmt.Sprintf("migration %d_%s must have up and down files", m.version, m.name))
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+	return migrations, nil
+}
@@ -0,0 +1,4 @@
+drop index if exists public.user_updated_at_idx;
+drop trigger if exists user_set_updated_at on public.user;
+drop function if exists public.set_updated_at();
+alter table public.user drop column if exists updated_at;
@@ -0,0 +1,15 @@
+alter table public.user add column updated_at timestamp not null default now();
+
+create function public.set_updated_at() returns trigger as $$
+begin
+    if new.updated_at is not distinct from old.updated_at then
+        new.updated_at = now();
+    end if;
+    return new;
+end;
+$$ language plpgsql;
+
+create trigger user_set_updated_at before update on public.user
+    for each row execute function public.set_updated_at();
+
+create index user_updated_at_idx on public.user (updated_at, id);
@@ -0,0 +1 @@
+alter table public.review add column updated_at timestamp not null default now();
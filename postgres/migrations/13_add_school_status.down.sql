@@ -0,0 +1,3 @@
+alter table public.school drop column if exists status;
+
+drop type if exists school_status;
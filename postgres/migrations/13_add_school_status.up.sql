@@ -0,0 +1,4 @@
+create type school_status as enum ('pending', 'approved');
+
+alter table public.school add column status school_status not null default 'pending';
+update public.school set status = 'approved';
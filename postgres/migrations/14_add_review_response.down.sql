@@ -0,0 +1 @@
+alter table public.review drop column if exists response;
@@ -0,0 +1 @@
+alter table public.review add column response text;
@@ -0,0 +1,9 @@
+create table public.outbox (
+    id bigserial primary key,
+    type text not null,
+    payload jsonb not null,
+    created_at timestamp not null default now(),
+    published_at timestamp
+);
+
+create index outbox_unpublished_idx on public.outbox (id) where published_at is null;
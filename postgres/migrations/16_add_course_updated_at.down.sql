@@ -0,0 +1,3 @@
+drop index if exists public.course_updated_at_idx;
+drop trigger if exists course_set_updated_at on public.course;
+alter table public.course drop column if exists updated_at;
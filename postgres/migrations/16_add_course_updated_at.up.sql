@@ -0,0 +1,6 @@
+alter table public.course add column updated_at timestamp not null default now();
+
+create trigger course_set_updated_at before update on public.course
+    for each row execute function public.set_updated_at();
+
+create index course_updated_at_idx on public.course (updated_at, id);
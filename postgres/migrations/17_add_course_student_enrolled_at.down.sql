@@ -0,0 +1,3 @@
+drop index if exists course_student_enrolled_at_idx;
+
+alter table public.course_student drop column if exists enrolled_at;
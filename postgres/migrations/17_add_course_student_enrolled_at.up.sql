@@ -0,0 +1,3 @@
+alter table public.course_student add column enrolled_at timestamp not null default now();
+
+create index course_student_enrolled_at_idx on public.course_student (student_id, enrolled_at);
@@ -0,0 +1,3 @@
+drop index if exists course_tags_idx;
+
+alter table public.course drop column if exists tags;
@@ -0,0 +1,3 @@
+alter table public.course add column tags text[] not null default '{}';
+
+create index course_tags_idx on public.course using gin (tags);
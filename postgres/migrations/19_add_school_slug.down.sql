@@ -0,0 +1,8 @@
+drop index if exists public.school_slug_idx;
+drop trigger if exists school_set_slug on public.school;
+alter table public.school drop column if exists slug;
+drop function if exists public.set_school_slug();
+drop function if exists public.school_slug(text, uuid);
+
+drop index if exists public.school_owner_name_idx;
+create unique index if not exists school_name_idx on public.school (name);
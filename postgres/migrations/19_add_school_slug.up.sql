@@ -0,0 +1,24 @@
+-- school names are not unique, schools are resolved by slug
+drop index if exists public.school_name_idx;
+create index school_owner_name_idx on public.school (owner_id, name);
+
+create function public.school_slug(name text, id uuid) returns text as $$
+    select coalesce(nullif(trim(both '-' from regexp_replace(lower(name), '[^[:alnum:]]+', '-', 'g')), ''), 'school')
+        || '-' || right(replace(id::text, '-', ''), 8);
+$$ language sql immutable;
+
+create function public.set_school_slug() returns trigger as $$
+begin
+    new.slug = public.school_slug(new.name, new.id);
+    return new;
+end;
+$$ language plpgsql;
+
+alter table public.school add column slug varchar(255);
+update public.school set slug = public.school_slug(name, id);
+alter table public.school alter column slug set not null;
+
+create trigger school_set_slug before insert or update of name, id on public.school
+    for each row execute function public.set_school_slug();
+
+create unique index school_slug_idx on public.school (slug);
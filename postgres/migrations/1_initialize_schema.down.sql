@@ -0,0 +1,15 @@
+drop table if exists public.school_teacher;
+drop table if exists public.course_teacher;
+drop table if exists public.course_student;
+drop table if exists public.test_stat;
+drop table if exists public.lesson_stat;
+drop table if exists public.certificate;
+drop type if exists certificate_grade;
+drop table if exists public.review;
+drop table if exists public.test;
+drop table if exists public.lesson;
+drop type if exists lesson_type;
+drop table if exists public.course;
+drop type if exists course_status;
+drop table if exists public.school;
+drop table if exists public.user;
@@ -0,0 +1 @@
+drop index if exists public.review_user_course_idx;
@@ -0,0 +1,9 @@
+-- a user holds one live review per course, all but the last updated live
+-- duplicate are soft deleted so that the index can be built
+update public.review r set deleted_at = now()
+where r.deleted_at is null and exists (
+    select 1 from public.review newer
+    where newer.user_id = r.user_id and newer.course_id = r.course_id and newer.deleted_at is null
+        and (newer.updated_at, newer.id) > (r.updated_at, r.id));
+
+create unique index review_user_course_idx on public.review (user_id, course_id) where deleted_at is null;
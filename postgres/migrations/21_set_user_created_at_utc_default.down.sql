@@ -0,0 +1 @@
+alter table public.user alter column created_at set default now();
@@ -0,0 +1 @@
+alter table public.review add column if not exists created_at timestamp not null default now();
@@ -0,0 +1 @@
+alter table public.review add column rating int check (rating between 1 and 5);
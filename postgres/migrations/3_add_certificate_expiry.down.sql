@@ -0,0 +1 @@
+alter table public.certificate drop column if exists expires_at;
@@ -0,0 +1 @@
+alter table public.certificate add column expires_at timestamp;
@@ -0,0 +1,3 @@
+drop index if exists public.certificate_verification_hash_idx;
+
+alter table public.certificate drop column if exists verification_hash;
@@ -0,0 +1,3 @@
+alter table public.certificate add column verification_hash varchar(128);
+
+create unique index certificate_verification_hash_idx on public.certificate (verification_hash);
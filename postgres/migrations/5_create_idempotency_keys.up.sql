@@ -0,0 +1,7 @@
+create table public.idempotency_keys (
+    scope varchar(64) not null,
+    key varchar(255) not null,
+    entity_id uuid not null,
+    created_at timestamp not null,
+    primary key (scope, key)
+);
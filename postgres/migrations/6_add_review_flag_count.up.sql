@@ -0,0 +1 @@
+alter table public.review add column flag_count int not null default 0;
@@ -0,0 +1 @@
+alter table public.review add column helpful_count int not null default 0;
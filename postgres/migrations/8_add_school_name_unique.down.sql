@@ -0,0 +1 @@
+drop index if exists public.school_name_idx;
@@ -0,0 +1 @@
+create unique index school_name_idx on public.school (name);
@@ -0,0 +1 @@
+alter table public.user drop column if exists created_at;
@@ -0,0 +1 @@
+alter table public.user add column created_at timestamp not null default now();
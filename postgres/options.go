@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"github.com/jmoiron/sqlx"
+	"github.com/pkg/errors"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+const defaultIdempotencyKeyTTL = 24 * time.Hour
+
+const defaultMinReviewLength = 20
+
+// Option configures behaviour shared by the postgres repositories.
+type Option func(*options)
+
+// RowsObserver is told how many rows a finder read, method is labeled as
+// "school.FindSchoolCourses", so that an unexpectedly large result can be
+// alerted on.
+type RowsObserver func(method string, rows int)
+
+type options struct {
+	guard             *ReadOnlyGuard
+	idempotencyKeyTTL time.Duration
+	rowsObserver      RowsObserver
+	queryLogger       *slog.Logger
+	replica           *sqlx.DB
+	replicaLag        time.Duration
+	maxResultRows     int
+	minReviewLength   int
+	explain           bool
+	rateLimits        map[string]*tokenBucket
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		idempotencyKeyTTL: defaultIdempotencyKeyTTL,
+		minReviewLength:   defaultMinReviewLength,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithReadOnlyGuard makes the repository reject writes while the guard is
+// in read-only mode. The same guard can be shared by all repositories.
+func WithReadOnlyGuard(guard *ReadOnlyGuard) Option {
+	return func(o *options) {
+		o.guard = guard
+	}
+}
+
+// WithIdempotencyKeyTTL sets how long an idempotency key is remembered,
+// a key older than ttl is treated as new. The default is 24 hours.
+func WithIdempotencyKeyTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.idempotencyKeyTTL = ttl
+	}
+}
+
+// WithMinReviewLength sets the text length under which FindSubstantiveCourseReviews
+// leaves a review out when called without a length. The default is 20 characters.
+func WithMinReviewLength(n int) Option {
+	return func(o *options) {
+		o.minReviewLength = n
+	}
+}
+
+// WithReadReplica sends the FindByID reads of the repository to replica.
+// lag bounds how far the replica may fall behind the primary: for that long
+// after ReadYourWrites, the reads of the marked context stay on the primary.
+func WithReadReplica(replica *sqlx.DB, lag time.Duration) Option {
+	return func(o *options) {
+		o.replica = replica
+		o.replicaLag = lag
+	}
+}
+
+// WithExplain enables Explainer.ExplainQuery. Leave it out of production
+// configurations, explaining a query executes it.
+func WithExplain() Option {
+	return func(o *options) {
+		o.explain = true
+	}
+}
+
+// WithMaxResultRows makes every finder of the repository fail with
+// ErrResultTooLarge instead of loading more than n rows into memory.
+func WithMaxResultRows(n int) Option {
+	return func(o *options) {
+		o.maxResultRows = n
+	}
+}
+
+// WithRowsObserver reports the number of rows every finder of the
+// repository read to the observer.
+func WithRowsObserver(observer RowsObserver) Option {
+	return func(o *options) {
+		o.rowsObserver = observer
+	}
+}
+
+// WithQueryLogger logs at debug level the queries of every finder of the
+// repository and of the user methods handling emails and passwords, with
+// their arguments passed through RedactArgs.
+func WithQueryLogger(logger *slog.Logger) Option {
+	return func(o *options) {
+		o.queryLogger = logger
+	}
+}
+
+func (o options) logQuery(method string, args ...interface{}) {
+	if o.queryLogger != nil {
+		o.queryLogger.Debug("query", "method", method, "args", RedactArgs(method, args...))
+	}
+}
+
+func (o options) observeRows(method string, rows int) {
+	if o.rowsObserver != nil {
+		o.rowsObserver(method, rows)
+	}
+}
+
+// limitResultRows limits a query to one row past the maximum, which is
+// enough for checkResultRows to tell that the result was cut. The query is
+// wrapped rather than appended to, as it may have a LIMIT of its own, and
+// the outer query keeps the order of the rows of the inner one.
+func (o options) limitResultRows(query string) string {
+	if o.maxResultRows <= 0 {
+		return query
+	}
+	return "SELECT * FROM (" + query + ") AS limited LIMIT " + strconv.Itoa(o.maxResultRows+1)
+}
+
+func (o options) checkResultRows(method string, rows int) error {
+	if o.maxResultRows > 0 && rows > o.maxResultRows {
+		return errors.Wrapf(ErrResultTooLarge, "%s read more than %d rows", method, o.maxResultRows)
+	}
+	return nil
+}
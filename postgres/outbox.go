@@ -0,0 +1,121 @@
+package repository
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/pkg/errors"
+	"time"
+)
+
+// PostgresOutboxRepo implements the transactional outbox: events are stored
+// in the transaction of the change they describe and published after it
+// committed, so that an event is never lost nor published for a change that
+// was rolled back.
+type PostgresOutboxRepo struct {
+	db   *sqlx.DB
+	opts options
+}
+
+// OutboxEvent is a domain event, such as "CertificateIssued", waiting in the
+// outbox. Payload is the JSON encoded event body.
+type OutboxEvent struct {
+	ID        int64
+	Type      string
+	Payload   []byte
+	CreatedAt time.Time
+}
+
+func NewOutboxRepo(db *sqlx.DB, opts ...Option) *PostgresOutboxRepo {
+	return &PostgresOutboxRepo{
+		db:   db,
+		opts: newOptions(opts),
+	}
+}
+
+const (
+	outboxInsertEventQuery           = "INSERT INTO public.outbox (type, payload) VALUES (?, ?::jsonb)"
+	outboxFindUnpublishedEventsQuery = "SELECT * FROM public.outbox WHERE published_at IS NULL " +
+		"ORDER BY id LIMIT ? FOR UPDATE SKIP LOCKED"
+	outboxMarkPublishedQuery = "UPDATE public.outbox SET published_at = now() WHERE id IN (?)"
+)
+
+// outboxRelayBatch is the number of events RelayOutbox publishes at most.
+const outboxRelayBatch = 100
+
+// EnqueueEvent stores the event in the outbox within tx, the transaction of
+// the change the event describes. WithinTx hands its function the
+// transaction rather than a context carrying it, so the caller passes on the
+// tx it was given:
+//
+//	err := WithinTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+//		// write the change within tx
+//		return outbox.EnqueueEvent(ctx, tx, event)
+//	})
+func (o *PostgresOutboxRepo) EnqueueEvent(ctx context.Context, tx *sqlx.Tx, event OutboxEvent) error {
+	if err := o.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := tx.ExecContext(ctx, tx.Rebind(outboxInsertEventQuery), event.Type, string(event.Payload))
+	if err != nil {
+		return persistenceError(o.db, err)
+	}
+	return nil
+}
+
+// RelayOutbox publishes the oldest unpublished events in order, up to 100 of
+// them, and marks them published. It returns the number of published events,
+// the caller runs it again until it returns 0. When publish fails, the events
+// published before stay marked and the rest is retried by the next run, so an
+// event is published at least once. Concurrent relays skip the events locked
+// by each other.
+func (o *PostgresOutboxRepo) RelayOutbox(ctx context.Context, publish func(OutboxEvent) error) (int, error) {
+	if err := o.opts.checkWritable(); err != nil {
+		return 0, err
+	}
+	if err := o.opts.checkRateLimit("outbox.RelayOutbox"); err != nil {
+		return 0, err
+	}
+
+	var published []int64
+	var publishErr error
+	err := WithinTx(ctx, o.db, nil, func(tx *sqlx.Tx) error {
+		var pgEvents []entity.PgOutboxEvent
+		err := tx.SelectContext(ctx, &pgEvents, tx.Rebind(outboxFindUnpublishedEventsQuery), outboxRelayBatch)
+		if err != nil {
+			return persistenceError(o.db, err)
+		}
+		o.opts.observeRows("outbox.RelayOutbox", len(pgEvents))
+
+		for _, event := range pgEvents {
+			publishErr = publish(OutboxEvent{
+				ID:        event.ID,
+				Type:      event.Type,
+				Payload:   event.Payload,
+				CreatedAt: event.CreatedAt,
+			})
+			if publishErr != nil {
+				break
+			}
+			published = append(published, event.ID)
+		}
+		if len(published) == 0 {
+			return nil
+		}
+
+		query, args, err := sqlx.In(outboxMarkPublishedQuery, published)
+		if err != nil {
+			return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+		}
+		if _, err = tx.ExecContext(ctx, tx.Rebind(query), args...); err != nil {
+			return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(published), publishErr
+}
@@ -0,0 +1,15 @@
+package repository
+
+// PageParams selects a page of a listing.
+type PageParams struct {
+	Limit  int
+	Offset int
+}
+
+// Page is a page of a listing together with the total size of the listing.
+type Page[T any] struct {
+	Items  []T
+	Total  int
+	Limit  int
+	Offset int
+}
@@ -0,0 +1,71 @@
+package repository
+
+import (
+	"github.com/pkg/errors"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned instead of querying when a method configured
+// with WithRateLimit is called more often than its rate allows.
+var ErrRateLimited = errors.New("rate limited")
+
+// tokenBucket lets burst calls through at once and then rps calls a second.
+type tokenBucket struct {
+	rps   float64
+	burst float64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rps float64, burst int) *tokenBucket {
+	return &tokenBucket{
+		rps:    rps,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// allow takes a token if one is left, refilling the bucket for the time
+// passed since the previous call first.
+func (b *tokenBucket) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.rps
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// WithRateLimit lets the repository method, labeled like the RowsObserver
+// labels it, such as "course.FindAll", run at most rps times a second with
+// bursts of up to burst calls. Calls over the limit fail with ErrRateLimited
+// without touching the database. Only the methods reporting to the
+// RowsObserver can be limited, and the limit is kept per repository.
+func WithRateLimit(method string, rps float64, burst int) Option {
+	return func(o *options) {
+		if o.rateLimits == nil {
+			o.rateLimits = make(map[string]*tokenBucket)
+		}
+		o.rateLimits[method] = newTokenBucket(rps, burst)
+	}
+}
+
+func (o options) checkRateLimit(method string) error {
+	if bucket, ok := o.rateLimits[method]; ok && !bucket.allow() {
+		return errors.Wrap(ErrRateLimited, method)
+	}
+	return nil
+}
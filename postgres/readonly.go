@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"github.com/pkg/errors"
+	"sync/atomic"
+)
+
+var ErrReadOnly = errors.New("repository is in read-only mode")
+
+// ReadOnlyGuard is a switch for maintenance windows: while it is enabled
+// every Create, Update, Delete and Add method fails with ErrReadOnly before
+// touching the database, reads are served as usual.
+type ReadOnlyGuard struct {
+	readOnly atomic.Bool
+}
+
+func NewReadOnlyGuard() *ReadOnlyGuard {
+	return &ReadOnlyGuard{}
+}
+
+func (g *ReadOnlyGuard) SetReadOnly(readOnly bool) {
+	g.readOnly.Store(readOnly)
+}
+
+func (g *ReadOnlyGuard) IsReadOnly() bool {
+	return g.readOnly.Load()
+}
+
+func (o options) checkWritable() error {
+	if o.guard != nil && o.guard.IsReadOnly() {
+		return errors.WithStack(ErrReadOnly)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+// RedactedArg replaces a sensitive argument in diagnostic output.
+const RedactedArg = "[REDACTED]"
+
+// redactAllArgs in a policy masks every argument of the method, for a method
+// whose arguments are bound at positions depending on the call, such as Patch.
+const redactAllArgs = -1
+
+// sensitiveArgs lists per method, labeled as "user.FindByEmail", the
+// positions of the arguments bound to its query that must never reach logs
+// or traces. A user entity argument carries the password, so it is masked
+// as a whole.
+var sensitiveArgs = map[string][]int{
+	"user.FindByEmail":       {0},
+	"user.FindByCredentials": {0, 1},
+	"user.Create":            {0},
+	"user.ImportUsers":       {0},
+	"user.Update":            {0},
+	"user.Patch":             {redactAllArgs},
+}
+
+// RedactArgs returns a copy of the query arguments of the method labeled
+// method, where the sensitive ones are replaced by RedactedArg. Arguments of
+// methods without a policy are returned as is.
+func RedactArgs(method string, args ...interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	copy(redacted, args)
+	for _, position := range sensitiveArgs[method] {
+		if position == redactAllArgs {
+			for i := range redacted {
+				redacted[i] = RedactedArg
+			}
+		} else if position < len(redacted) {
+			redacted[position] = RedactedArg
+		}
+	}
+	return redacted
+}
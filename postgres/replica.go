@@ -0,0 +1,42 @@
+package repository
+
+import (
+	"context"
+	"github.com/jmoiron/sqlx"
+	"time"
+)
+
+type readYourWritesKey struct{}
+
+// ReadYourWrites marks ctx as following a write made by the caller. For the
+// replica lag window of the repository, reads made with the returned context
+// go to the primary, so that they see the write even if the replica has not
+// caught up yet. Use it on the context of the write or right after it.
+func ReadYourWrites(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readYourWritesKey{}, time.Now())
+}
+
+type freshReadKey struct{}
+
+// FreshRead marks ctx as needing the latest committed state, such as an
+// admin tool about to edit the record: reads made with the returned context
+// always go to the primary, whatever the replica lag.
+func FreshRead(ctx context.Context) context.Context {
+	return context.WithValue(ctx, freshReadKey{}, true)
+}
+
+// reader returns the database to read from: the replica, unless none is
+// configured, ctx was marked by FreshRead or ctx was marked by
+// ReadYourWrites less than the replica lag window ago.
+func (o options) reader(ctx context.Context, primary *sqlx.DB) *sqlx.DB {
+	if o.replica == nil {
+		return primary
+	}
+	if fresh, _ := ctx.Value(freshReadKey{}).(bool); fresh {
+		return primary
+	}
+	if writtenAt, ok := ctx.Value(readYourWritesKey{}).(time.Time); ok && time.Since(writtenAt) < o.replicaLag {
+		return primary
+	}
+	return o.replica
+}
@@ -3,41 +3,185 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"github.com/guregu/null"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/paw1a/eschool-core/domain"
 	"github.com/paw1a/eschool-core/errs"
 	"github.com/paw1a/eschool-repository/postgres/entity"
 	"github.com/pkg/errors"
+	"strings"
+	"sync"
+	"time"
 )
 
 type PostgresReviewRepo struct {
-	db *sqlx.DB
+	db   *sqlx.DB
+	opts options
 }
 
-func NewReviewRepo(db *sqlx.DB) *PostgresReviewRepo {
+type ReviewWithContext struct {
+	Review     domain.Review
+	CourseName string
+	SchoolID   domain.ID
+	SchoolName string
+}
+
+// RatedReview is a review together with its star rating, which
+// domain.Review does not carry. Rating is null for an unrated review.
+type RatedReview struct {
+	Review domain.Review
+	Rating null.Int
+}
+
+// ReviewChange is a review written or soft deleted after a checkpoint, with
+// the rating it counted with. DeletedAt is null for a review still in place.
+// A deleted review written after the checkpoint too was never counted, its
+// CreatedAt tells it apart from one to subtract.
+type ReviewChange struct {
+	Review    domain.Review
+	Rating    null.Int
+	CreatedAt time.Time
+	DeletedAt null.Time
+}
+
+// MonthlyRating is the average rating of the course reviews written in the
+// month starting at Month.
+type MonthlyRating struct {
+	Month         time.Time
+	AverageRating float64
+	ReviewCount   int
+}
+
+// ReviewPage is a batch of a keyset paginated review listing, NextCursor
+// is empty on the last page.
+type ReviewPage struct {
+	Reviews    []domain.Review
+	NextCursor string
+}
+
+func NewReviewRepo(db *sqlx.DB, opts ...Option) *PostgresReviewRepo {
 	return &PostgresReviewRepo{
-		db: db,
+		db:   db,
+		opts: newOptions(opts),
 	}
 }
 
 const (
-	reviewFindAllQuery           = "SELECT * FROM public.review"
-	reviewFindByIDQuery          = "SELECT * FROM public.review WHERE id = $1"
-	reviewFindUserReviewsQuery   = "SELECT * FROM public.review WHERE user_id = $1"
-	reviewFindCourseReviewsQuery = "SELECT * FROM public.review WHERE course_id = $1"
-	reviewDeleteQuery            = "DELETE FROM public.school WHERE id = $1"
+	reviewFindAllQuery                   = "SELECT * FROM public.review WHERE deleted_at IS NULL"
+	reviewFindByIDQuery                  = "SELECT * FROM public.review WHERE id = ?"
+	reviewFindUserReviewsQuery           = "SELECT * FROM public.review WHERE user_id = ? AND deleted_at IS NULL"
+	reviewFindCourseReviewsQuery         = "SELECT * FROM public.review WHERE course_id = ? AND deleted_at IS NULL"
+	reviewFindCourseReviewsInWindowQuery = "SELECT * FROM public.review WHERE course_id = ? " +
+		"AND created_at >= ? AND created_at < ? ORDER BY created_at, id"
+	reviewFindCourseReviewsSinceQuery = "SELECT * FROM public.review WHERE course_id = ? " +
+		"AND GREATEST(created_at, deleted_at) > ? ORDER BY GREATEST(created_at, deleted_at), id"
+	reviewCountDistinctReviewersInWindowQuery = "SELECT COUNT(DISTINCT user_id) FROM public.review " +
+		"WHERE course_id = ? AND created_at >= ? AND created_at < ?"
+	reviewFindCourseReviewsFirstPageQuery = "SELECT * FROM public.review WHERE course_id = ? " +
+		"AND deleted_at IS NULL ORDER BY created_at DESC, id DESC LIMIT ?"
+	reviewFindCourseReviewsAfterCursorQuery = "SELECT * FROM public.review WHERE course_id = ? " +
+		"AND deleted_at IS NULL AND (created_at, id) < (?, ?) ORDER BY created_at DESC, id DESC LIMIT ?"
+	reviewCountCourseReviewsQuery         = "SELECT COUNT(*) FROM public.review WHERE course_id = ? AND deleted_at IS NULL"
+	reviewFindUserReviewsWithContextQuery = "SELECT r.*, c.name AS course_name, " +
+		"s.id AS school_id, s.name AS school_name FROM public.review r " +
+		"JOIN public.course c on r.course_id = c.id " +
+		"JOIN public.school s on c.school_id = s.id " +
+		"WHERE r.user_id = ? AND r.deleted_at IS NULL ORDER BY r.id LIMIT ? OFFSET ?"
+	reviewFindUnansweredOlderThanQuery = "SELECT r.*, c.name AS course_name, " +
+		"s.id AS school_id, s.name AS school_name FROM public.review r " +
+		"JOIN public.course c on r.course_id = c.id " +
+		"JOIN public.school s on c.school_id = s.id " +
+		"WHERE r.response IS NULL AND r.deleted_at IS NULL " +
+		"AND r.created_at < ? " +
+		"ORDER BY r.created_at, r.id LIMIT ? OFFSET ?"
+	reviewFindLowRatingForSchoolQuery = "SELECT r.*, c.name AS course_name, " +
+		"s.id AS school_id, s.name AS school_name FROM public.review r " +
+		"JOIN public.course c on r.course_id = c.id " +
+		"JOIN public.school s on c.school_id = s.id " +
+		"WHERE s.id = ? AND r.rating <= ? AND r.created_at >= ? AND r.deleted_at IS NULL " +
+		"ORDER BY r.created_at DESC, r.id"
+	reviewFindFlaggedReviewsQuery = "SELECT * FROM public.review WHERE flag_count >= ? " +
+		"AND deleted_at IS NULL ORDER BY flag_count DESC, id LIMIT ? OFFSET ?"
+	reviewFindPriorityModerationQueueQuery = "SELECT r.* FROM public.review r " +
+		"LEFT JOIN public.user u on r.user_id = u.id WHERE r.flag_count > 0 AND r.deleted_at IS NULL " +
+		"ORDER BY COALESCE(u.created_at > ?, false) DESC, r.flag_count DESC, r.id LIMIT ? OFFSET ?"
+	reviewIncrementHelpfulQuery            = "UPDATE public.review SET helpful_count = helpful_count + 1 WHERE id = ?"
+	reviewFlagQuery                        = "UPDATE public.review SET flag_count = flag_count + 1 WHERE id = ?"
+	reviewDeleteExpiredIdempotencyKeyQuery = "DELETE FROM public.idempotency_keys " +
+		"WHERE scope = ? AND key = ? AND created_at < ?"
+	reviewInsertIdempotencyKeyQuery = "INSERT INTO public.idempotency_keys (scope, key, entity_id, created_at) " +
+		"VALUES (?, ?, ?, ?) ON CONFLICT (scope, key) DO NOTHING"
+	reviewFindIdempotencyKeyQuery    = "SELECT entity_id FROM public.idempotency_keys WHERE scope = ? AND key = ?"
+	reviewDeleteQuery                = "UPDATE public.review SET deleted_at = now() WHERE id = ? AND deleted_at IS NULL"
+	reviewRestoreQuery               = "UPDATE public.review SET deleted_at = NULL WHERE id = ? AND deleted_at IS NOT NULL"
+	reviewFindDeletedReviewsQuery    = "SELECT * FROM public.review WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id"
+	reviewCourseRatingHistogramQuery = "SELECT rating, COUNT(*) AS count FROM public.review " +
+		"WHERE course_id = ? AND rating IS NOT NULL AND deleted_at IS NULL GROUP BY rating"
+	reviewFindCourseReviewsInRangeQuery = "SELECT * FROM public.review WHERE course_id = ? " +
+		"AND created_at >= ? AND created_at < ? AND deleted_at IS NULL ORDER BY created_at, id LIMIT ?"
+	reviewCourseRatingTrendQuery = "SELECT date_trunc('month', created_at) AS month, " +
+		"AVG(rating) AS average_rating, COUNT(*) AS review_count FROM public.review " +
+		"WHERE course_id = ? AND rating IS NOT NULL AND deleted_at IS NULL " +
+		"AND created_at >= ? AND created_at < ? GROUP BY month ORDER BY month"
+	reviewCourseAverageRatingQuery = "SELECT COALESCE(AVG(rating), 0) FROM public.review " +
+		"WHERE course_id = ? AND deleted_at IS NULL"
+	reviewUpsertUserCourseReviewSuffix = " ON CONFLICT (user_id, course_id) WHERE deleted_at IS NULL " +
+		"DO UPDATE SET text = EXCLUDED.text, rating = COALESCE(EXCLUDED.rating, review.rating), " +
+		"updated_at = now() RETURNING *"
+	reviewFindCourseReviewsWithoutResponseQuery = "SELECT * FROM public.review WHERE course_id = ? " +
+		"AND response IS NULL AND deleted_at IS NULL ORDER BY created_at, id LIMIT ? OFFSET ?"
+	reviewFindSubstantiveCourseReviewsQuery = "SELECT * FROM public.review WHERE course_id = ? " +
+		"AND char_length(text) >= ? AND deleted_at IS NULL ORDER BY created_at DESC, id LIMIT ? OFFSET ?"
+	reviewFindCourseReviewsExcludingUserQuery = "SELECT * FROM public.review WHERE course_id = ? " +
+		"AND user_id IS DISTINCT FROM ? AND deleted_at IS NULL ORDER BY created_at DESC, id LIMIT ? OFFSET ?"
+	reviewAddResponseQuery = "UPDATE public.review SET response = ? WHERE id = ? AND deleted_at IS NULL"
+)
+
+const reviewIdempotencyScope = "review"
+
+// reviewNewAuthorAge is the account age under which a review author is not
+// trusted yet, their flagged reviews are moderated first.
+const reviewNewAuthorAge = 30 * 24 * time.Hour
+
+// ReviewOrder selects the order of the reviews listed by FindCourseReviewsOrdered.
+type ReviewOrder string
+
+const (
+	ReviewOrderDefault ReviewOrder = ""
+	ReviewOrderHelpful ReviewOrder = "helpful"
+	ReviewOrderRating  ReviewOrder = "rating"
+)
+
+// ReviewSort is the sort spec of FindCourseReviewsSorted. NullsFirst puts
+// the reviews whose sort column is NULL, such as the unrated ones under
+// ReviewOrderRating, first instead of last.
+type ReviewSort struct {
+	Order      ReviewOrder
+	NullsFirst bool
+}
+
+var reviewOrderColumns = map[ReviewOrder][]string{
+	ReviewOrderDefault: nil,
+	ReviewOrderHelpful: {"helpful_count DESC", "created_at DESC"},
+	ReviewOrderRating:  {"rating DESC", "created_at DESC", "id"},
+}
+
+const (
+	reviewMinRating = 1
+	reviewMaxRating = 5
 )
 
 func (r *PostgresReviewRepo) FindAll(ctx context.Context) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindAll"); err != nil {
+		return nil, err
+	}
+
 	var pgReviews []entity.PgReview
-	if err := r.db.SelectContext(ctx, &pgReviews, reviewFindAllQuery); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, r.db, r.opts, "review.FindAll", &pgReviews, reviewFindAllQuery); err != nil {
+		return nil, err
 	}
+	r.opts.observeRows("review.FindAll", len(pgReviews))
 
 	reviews := make([]domain.Review, len(pgReviews))
 	for i, review := range pgReviews {
@@ -48,25 +192,42 @@ func (r *PostgresReviewRepo) FindAll(ctx context.Context) ([]domain.Review, erro
 
 func (r *PostgresReviewRepo) FindByID(ctx context.Context, reviewID domain.ID) (domain.Review, error) {
 	var pgReview entity.PgReview
-	if err := r.db.GetContext(ctx, &pgReview, reviewFindByIDQuery, reviewID); err != nil {
+	db := r.opts.reader(ctx, r.db)
+	if err := db.GetContext(ctx, &pgReview, db.Rebind(reviewFindByIDQuery), reviewID); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.Review{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Review{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.Review{}, persistenceError(db, err)
 		}
 	}
 	return pgReview.ToDomain(), nil
 }
 
-func (r *PostgresReviewRepo) FindUserReviews(ctx context.Context, userID domain.ID) ([]domain.Review, error) {
-	var pgReviews []entity.PgReview
-	if err := r.db.SelectContext(ctx, &pgReviews, reviewFindUserReviewsQuery, userID); err != nil {
+// FindRatedByID returns the review together with its star rating.
+func (r *PostgresReviewRepo) FindRatedByID(ctx context.Context, reviewID domain.ID) (RatedReview, error) {
+	var pgReview entity.PgReview
+	db := r.opts.reader(ctx, r.db)
+	if err := db.GetContext(ctx, &pgReview, db.Rebind(reviewFindByIDQuery), reviewID); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
+			return RatedReview{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return RatedReview{}, persistenceError(db, err)
 		}
 	}
+	return newRatedReview(pgReview), nil
+}
+
+func (r *PostgresReviewRepo) FindUserReviews(ctx context.Context, userID domain.ID) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindUserReviews"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindUserReviews", &pgReviews,
+		reviewFindUserReviewsQuery, userID); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindUserReviews", len(pgReviews))
 
 	reviews := make([]domain.Review, len(pgReviews))
 	for i, review := range pgReviews {
@@ -75,15 +236,293 @@ func (r *PostgresReviewRepo) FindUserReviews(ctx context.Context, userID domain.
 	return reviews, nil
 }
 
+func (r *PostgresReviewRepo) FindUserReviewsWithContext(ctx context.Context, userID domain.ID,
+	limit, offset int) ([]ReviewWithContext, error) {
+	if err := r.opts.checkRateLimit("review.FindUserReviewsWithContext"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReviewWithContext
+	if err := selectRows(ctx, r.db, r.opts, "review.FindUserReviewsWithContext", &pgReviews,
+		reviewFindUserReviewsWithContextQuery, userID, limit, offset); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindUserReviewsWithContext", len(pgReviews))
+
+	reviews := make([]ReviewWithContext, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = ReviewWithContext{
+			Review:     review.ToDomain(),
+			CourseName: review.CourseName,
+			SchoolID:   domain.ID(review.SchoolID.String()),
+			SchoolName: review.SchoolName,
+		}
+	}
+	return reviews, nil
+}
+
 func (r *PostgresReviewRepo) FindCourseReviews(ctx context.Context, courseID domain.ID) ([]domain.Review, error) {
+	return r.FindCourseReviewsOrdered(ctx, courseID, ReviewOrderDefault)
+}
+
+// FindCourseReviewsOrdered lists the course reviews in the given order,
+// ReviewOrderHelpful puts the most helpful and then the newest reviews first
+// and ReviewOrderRating the best rated and then the newest ones.
+func (r *PostgresReviewRepo) FindCourseReviewsOrdered(ctx context.Context, courseID domain.ID,
+	order ReviewOrder) ([]domain.Review, error) {
+	return r.FindCourseReviewsSorted(ctx, courseID, ReviewSort{Order: order})
+}
+
+// FindCourseReviewsSorted lists the course reviews as sort specifies, the
+// ones with a NULL sort column last unless sort.NullsFirst is set.
+func (r *PostgresReviewRepo) FindCourseReviewsSorted(ctx context.Context, courseID domain.ID,
+	sort ReviewSort) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindCourseReviewsSorted"); err != nil {
+		return nil, err
+	}
+
+	columns, ok := reviewOrderColumns[sort.Order]
+	if !ok {
+		return nil, errors.Wrapf(errs.ErrEnumValueError, "review order %q", sort.Order)
+	}
+
 	var pgReviews []entity.PgReview
-	if err := r.db.SelectContext(ctx, &pgReviews, reviewFindCourseReviewsQuery, courseID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	if err := selectRows(ctx, r.db, r.opts, "review.FindCourseReviewsSorted", &pgReviews,
+		reviewFindCourseReviewsQuery+orderByClause(columns, sort.NullsFirst), courseID); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindCourseReviewsSorted", len(pgReviews))
+
+	reviews := make([]domain.Review, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+// FindCourseReviewsExcludingUser lists the course reviews, newest first,
+// leaving out the ones written by excludeUserID. The reviews of deleted
+// users are kept.
+func (r *PostgresReviewRepo) FindCourseReviewsExcludingUser(ctx context.Context, courseID, excludeUserID domain.ID,
+	page PageParams) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindCourseReviewsExcludingUser"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindCourseReviewsExcludingUser", &pgReviews,
+		reviewFindCourseReviewsExcludingUserQuery, courseID, excludeUserID, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindCourseReviewsExcludingUser", len(pgReviews))
+
+	reviews := make([]domain.Review, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+// FindSubstantiveCourseReviews lists the course reviews, newest first, whose
+// text is at least minLength characters long. A minLength of 0 applies the
+// length set with WithMinReviewLength.
+func (r *PostgresReviewRepo) FindSubstantiveCourseReviews(ctx context.Context, courseID domain.ID,
+	minLength int, page PageParams) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindSubstantiveCourseReviews"); err != nil {
+		return nil, err
+	}
+
+	if minLength <= 0 {
+		minLength = r.opts.minReviewLength
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindSubstantiveCourseReviews", &pgReviews,
+		reviewFindSubstantiveCourseReviewsQuery, courseID, minLength, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindSubstantiveCourseReviews", len(pgReviews))
+
+	reviews := make([]domain.Review, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+// FindCourseReviewsCursor lists the course reviews newest first in batches
+// of limit for infinite scroll, an empty cursor starts from the newest review.
+// Reviews written after the first page was read do not show up on the next
+// pages, so no review is returned twice.
+func (r *PostgresReviewRepo) FindCourseReviewsCursor(ctx context.Context, courseID domain.ID,
+	cursor string, limit int) (ReviewPage, error) {
+	if err := r.opts.checkRateLimit("review.FindCourseReviewsCursor"); err != nil {
+		return ReviewPage{}, err
+	}
+
+	query := reviewFindCourseReviewsFirstPageQuery
+	args := []interface{}{courseID, limit}
+	if cursor != "" {
+		before, err := DecodeCursor(cursor)
+		if err != nil {
+			return ReviewPage{}, err
+		}
+		query = reviewFindCourseReviewsAfterCursorQuery
+		args = []interface{}{courseID, before.CreatedAt.UTC(), before.ID, limit}
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindCourseReviewsCursor", &pgReviews, query, args...); err != nil {
+		return ReviewPage{}, err
+	}
+	r.opts.observeRows("review.FindCourseReviewsCursor", len(pgReviews))
+
+	page := ReviewPage{Reviews: make([]domain.Review, len(pgReviews))}
+	for i, review := range pgReviews {
+		page.Reviews[i] = review.ToDomain()
+	}
+	if len(pgReviews) == limit && limit > 0 {
+		last := pgReviews[len(pgReviews)-1]
+		page.NextCursor = EncodeCursor(Cursor{CreatedAt: last.CreatedAt, ID: domain.ID(last.ID.String())})
+	}
+	return page, nil
+}
+
+func (r *PostgresReviewRepo) FindFlaggedReviews(ctx context.Context, minFlags int,
+	page PageParams) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindFlaggedReviews"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindFlaggedReviews", &pgReviews,
+		reviewFindFlaggedReviewsQuery, minFlags, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindFlaggedReviews", len(pgReviews))
+
+	reviews := make([]domain.Review, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+// FindCourseReviewsWithoutResponse returns the course reviews the instructor
+// has not responded to yet, the longest waiting first.
+func (r *PostgresReviewRepo) FindCourseReviewsWithoutResponse(ctx context.Context, courseID domain.ID,
+	page PageParams) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindCourseReviewsWithoutResponse"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindCourseReviewsWithoutResponse", &pgReviews,
+		reviewFindCourseReviewsWithoutResponseQuery, courseID, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindCourseReviewsWithoutResponse", len(pgReviews))
+
+	reviews := make([]domain.Review, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+// FindUnansweredReviewsOlderThan returns the reviews written more than age
+// ago that still have no response, the longest waiting first, with the
+// course and school they are about.
+func (r *PostgresReviewRepo) FindUnansweredReviewsOlderThan(ctx context.Context, age time.Duration,
+	page PageParams) ([]ReviewWithContext, error) {
+	if err := r.opts.checkRateLimit("review.FindUnansweredReviewsOlderThan"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReviewWithContext
+	if err := selectRows(ctx, r.db, r.opts, "review.FindUnansweredReviewsOlderThan", &pgReviews,
+		reviewFindUnansweredOlderThanQuery, time.Now().UTC().Add(-age), page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindUnansweredReviewsOlderThan", len(pgReviews))
+
+	reviews := make([]ReviewWithContext, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = ReviewWithContext{
+			Review:     review.ToDomain(),
+			CourseName: review.CourseName,
+			SchoolID:   domain.ID(review.SchoolID.String()),
+			SchoolName: review.SchoolName,
+		}
+	}
+	return reviews, nil
+}
+
+// FindLowRatingReviewsForSchool returns the reviews of the school courses
+// rated maxRating or lower and written since the given time, newest first,
+// with the course they are about. Unrated reviews are left out.
+func (r *PostgresReviewRepo) FindLowRatingReviewsForSchool(ctx context.Context, schoolID domain.ID,
+	maxRating int, since time.Time) ([]ReviewWithContext, error) {
+	if err := r.opts.checkRateLimit("review.FindLowRatingReviewsForSchool"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReviewWithContext
+	if err := selectRows(ctx, r.db, r.opts, "review.FindLowRatingReviewsForSchool", &pgReviews,
+		reviewFindLowRatingForSchoolQuery, schoolID, maxRating, since.UTC()); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindLowRatingReviewsForSchool", len(pgReviews))
+
+	reviews := make([]ReviewWithContext, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = ReviewWithContext{
+			Review:     review.ToDomain(),
+			CourseName: review.CourseName,
+			SchoolID:   domain.ID(review.SchoolID.String()),
+			SchoolName: review.SchoolName,
 		}
 	}
+	return reviews, nil
+}
+
+// FindPriorityModerationQueue returns the flagged reviews, the ones written
+// from accounts younger than 30 days first and then the most flagged first.
+func (r *PostgresReviewRepo) FindPriorityModerationQueue(ctx context.Context,
+	page PageParams) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindPriorityModerationQueue"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	newAuthorSince := time.Now().UTC().Add(-reviewNewAuthorAge)
+	if err := selectRows(ctx, r.db, r.opts, "review.FindPriorityModerationQueue", &pgReviews,
+		reviewFindPriorityModerationQueueQuery, newAuthorSince, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindPriorityModerationQueue", len(pgReviews))
+
+	reviews := make([]domain.Review, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+// FindReviewsForCourseInWindow returns the course reviews written in
+// [from, to), oldest first.
+func (r *PostgresReviewRepo) FindReviewsForCourseInWindow(ctx context.Context, courseID domain.ID,
+	from, to time.Time) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindReviewsForCourseInWindow"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindReviewsForCourseInWindow", &pgReviews,
+		reviewFindCourseReviewsInWindowQuery, courseID, from.UTC(), to.UTC()); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindReviewsForCourseInWindow", len(pgReviews))
 
 	reviews := make([]domain.Review, len(pgReviews))
 	for i, review := range pgReviews {
@@ -92,40 +531,539 @@ func (r *PostgresReviewRepo) FindCourseReviews(ctx context.Context, courseID dom
 	return reviews, nil
 }
 
+// FindCourseReviewsSince returns the course reviews written or soft deleted
+// after since, in the order of those changes, so that a cached aggregate
+// recomputed from a checkpoint can add the written ones and subtract the
+// deleted ones. A review written before since is returned once deleted, a
+// deleted review whose CreatedAt is after since was never counted.
+func (r *PostgresReviewRepo) FindCourseReviewsSince(ctx context.Context, courseID domain.ID,
+	since time.Time) ([]ReviewChange, error) {
+	if err := r.opts.checkRateLimit("review.FindCourseReviewsSince"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindCourseReviewsSince", &pgReviews,
+		reviewFindCourseReviewsSinceQuery, courseID, since.UTC()); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindCourseReviewsSince", len(pgReviews))
+
+	changes := make([]ReviewChange, len(pgReviews))
+	for i, review := range pgReviews {
+		changes[i] = ReviewChange{
+			Review:    review.ToDomain(),
+			Rating:    review.Rating,
+			CreatedAt: review.CreatedAt,
+			DeletedAt: review.DeletedAt,
+		}
+	}
+	return changes, nil
+}
+
+// CountDistinctReviewersInWindow counts the users who reviewed the course
+// in [from, to).
+func (r *PostgresReviewRepo) CountDistinctReviewersInWindow(ctx context.Context, courseID domain.ID,
+	from, to time.Time) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, r.db.Rebind(reviewCountDistinctReviewersInWindowQuery),
+		courseID, from.UTC(), to.UTC()); err != nil {
+		return 0, persistenceError(r.db, err)
+	}
+	return count, nil
+}
+
+func (r *PostgresReviewRepo) CountCourseReviews(ctx context.Context, courseID domain.ID) (int, error) {
+	var count int
+	if err := r.db.GetContext(ctx, &count, r.db.Rebind(reviewCountCourseReviewsQuery), courseID); err != nil {
+		return 0, persistenceError(r.db, err)
+	}
+	return count, nil
+}
+
+func (r *PostgresReviewRepo) GetCourseRatingHistogram(ctx context.Context,
+	courseID domain.ID) (map[int]int, error) {
+	if err := r.opts.checkRateLimit("review.GetCourseRatingHistogram"); err != nil {
+		return nil, err
+	}
+
+	var pgCounts []entity.PgRatingCount
+	if err := selectRows(ctx, r.db, r.opts, "review.GetCourseRatingHistogram", &pgCounts,
+		reviewCourseRatingHistogramQuery, courseID); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.GetCourseRatingHistogram", len(pgCounts))
+
+	histogram := make(map[int]int, reviewMaxRating-reviewMinRating+1)
+	for rating := reviewMinRating; rating <= reviewMaxRating; rating++ {
+		histogram[rating] = 0
+	}
+	for _, count := range pgCounts {
+		histogram[count.Rating] = count.Count
+	}
+	return histogram, nil
+}
+
+// GetCourseAverageRating returns the mean rating of the course reviews,
+// unrated and deleted reviews are left out, and 0 when there are none.
+func (r *PostgresReviewRepo) GetCourseAverageRating(ctx context.Context, courseID domain.ID) (float64, error) {
+	var average float64
+	if err := r.db.GetContext(ctx, &average, r.db.Rebind(reviewCourseAverageRatingQuery), courseID); err != nil {
+		return 0, persistenceError(r.db, err)
+	}
+	return average, nil
+}
+
+// GetCourseAverageRatings returns the GetCourseAverageRating of every given
+// course keyed by course, computing up to concurrency of them at once. Each
+// worker holds a pool connection while it runs, so concurrency is capped at
+// the pool size when the pool has one and should stay well below it to
+// leave connections for other requests. The first failure or the
+// cancellation of ctx stops the remaining computations.
+func (r *PostgresReviewRepo) GetCourseAverageRatings(ctx context.Context, courseIDs []domain.ID,
+	concurrency int) (map[domain.ID]float64, error) {
+	if len(courseIDs) == 0 {
+		return map[domain.ID]float64{}, nil
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if maxOpen := r.db.Stats().MaxOpenConnections; maxOpen > 0 && concurrency > maxOpen {
+		concurrency = maxOpen
+	}
+
+	workCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var mu sync.Mutex
+	var firstErr error
+	ratings := make(map[domain.ID]float64, len(courseIDs))
+
+	var wg sync.WaitGroup
+	courses := make(chan domain.ID)
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for courseID := range courses {
+				rating, err := r.GetCourseAverageRating(workCtx, courseID)
+				mu.Lock()
+				if err != nil && firstErr == nil {
+					firstErr = err
+					cancel()
+				} else if err == nil {
+					ratings[courseID] = rating
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+send:
+	for _, courseID := range courseIDs {
+		select {
+		case courses <- courseID:
+		case <-workCtx.Done():
+			break send
+		}
+	}
+	close(courses)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return ratings, nil
+}
+
+// GetCourseRatingTrend returns the monthly average rating of the course
+// reviews written in [from, to), oldest month first. Months without rated
+// reviews are left out rather than reported as zero.
+func (r *PostgresReviewRepo) GetCourseRatingTrend(ctx context.Context, courseID domain.ID,
+	from, to time.Time) ([]MonthlyRating, error) {
+	if err := r.opts.checkRateLimit("review.GetCourseRatingTrend"); err != nil {
+		return nil, err
+	}
+
+	var pgRatings []entity.PgMonthlyRating
+	if err := selectRows(ctx, r.db, r.opts, "review.GetCourseRatingTrend", &pgRatings,
+		reviewCourseRatingTrendQuery, courseID, from.UTC(), to.UTC()); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.GetCourseRatingTrend", len(pgRatings))
+
+	ratings := make([]MonthlyRating, len(pgRatings))
+	for i, rating := range pgRatings {
+		ratings[i] = MonthlyRating{
+			Month:         rating.Month,
+			AverageRating: rating.AverageRating,
+			ReviewCount:   rating.ReviewCount,
+		}
+	}
+	return ratings, nil
+}
+
+// maxReviewsByMonth bounds the reviews FindCourseReviewsByMonth holds in
+// memory.
+const maxReviewsByMonth = 10000
+
+// FindCourseReviewsByMonth returns the course reviews written in [from, to)
+// keyed by their month formatted as YYYY-MM, oldest first within a month.
+// All of them are held in memory, so a range holding more than 10000
+// reviews fails with ErrResultTooLarge and has to be split by the caller.
+func (r *PostgresReviewRepo) FindCourseReviewsByMonth(ctx context.Context, courseID domain.ID,
+	from, to time.Time) (map[string][]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindCourseReviewsByMonth"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindCourseReviewsByMonth", &pgReviews,
+		reviewFindCourseReviewsInRangeQuery, courseID, from.UTC(), to.UTC(), maxReviewsByMonth+1); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindCourseReviewsByMonth", len(pgReviews))
+	if len(pgReviews) > maxReviewsByMonth {
+		return nil, errors.Wrapf(ErrResultTooLarge, "review.FindCourseReviewsByMonth read more than %d rows",
+			maxReviewsByMonth)
+	}
+
+	months := make(map[string][]domain.Review)
+	for _, review := range pgReviews {
+		month := review.CreatedAt.Format("2006-01")
+		months[month] = append(months[month], review.ToDomain())
+	}
+	return months, nil
+}
+
 func (r *PostgresReviewRepo) Create(ctx context.Context, review domain.Review) (domain.Review, error) {
-	var pgReview = entity.NewPgReview(review)
+	pgReview, err := entity.NewPgReview(review)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	createdReview, err := r.create(ctx, pgReview)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	return createdReview.ToDomain(), nil
+}
+
+// CreateRatedReview creates the review with its star rating, between 1 and 5.
+func (r *PostgresReviewRepo) CreateRatedReview(ctx context.Context, review domain.Review,
+	rating int) (RatedReview, error) {
+	pgReview, err := entity.NewPgRatedReview(review, rating)
+	if err != nil {
+		return RatedReview{}, err
+	}
+	createdReview, err := r.create(ctx, pgReview)
+	if err != nil {
+		return RatedReview{}, err
+	}
+	return newRatedReview(createdReview), nil
+}
+
+func (r *PostgresReviewRepo) create(ctx context.Context, pgReview entity.PgReview) (entity.PgReview, error) {
+	if err := r.opts.checkWritable(); err != nil {
+		return entity.PgReview{}, err
+	}
+
+	if err := pgReview.Validate(); err != nil {
+		return entity.PgReview{}, err
+	}
 	queryString := entity.InsertQueryString(pgReview, "review")
-	_, err := r.db.NamedExecContext(ctx, queryString, pgReview)
+	_, err := namedExecContext(ctx, r.db, queryString, pgReview)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
-				return domain.Review{}, errors.Wrap(errs.ErrDuplicate, err.Error())
+				return entity.PgReview{}, uniqueViolationError(pgErr, err)
 			} else {
-				return domain.Review{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return entity.PgReview{}, persistenceError(r.db, err)
 			}
 		} else {
-			return domain.Review{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return entity.PgReview{}, persistenceError(r.db, err)
 		}
 	}
 
 	var createdReview entity.PgReview
-	err = r.db.GetContext(ctx, &createdReview, reviewFindByIDQuery, pgReview.ID)
+	err = r.db.GetContext(ctx, &createdReview, r.db.Rebind(reviewFindByIDQuery), pgReview.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return domain.Review{}, errors.Wrap(errs.ErrNotExist, err.Error())
+			return entity.PgReview{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.Review{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return entity.PgReview{}, persistenceError(r.db, err)
 		}
 	}
 
-	return createdReview.ToDomain(), nil
+	return createdReview, nil
 }
 
+// CreateWithIdempotencyKey creates the review once per key: a retry with
+// the same key returns the review created by the first call. The key is
+// stored in the same transaction as the review.
+func (r *PostgresReviewRepo) CreateWithIdempotencyKey(ctx context.Context, review domain.Review,
+	key string) (domain.Review, error) {
+	if err := r.opts.checkWritable(); err != nil {
+		return domain.Review{}, err
+	}
+
+	pgReview, err := entity.NewPgReview(review)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	if err := pgReview.Validate(); err != nil {
+		return domain.Review{}, err
+	}
+
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return domain.Review{}, errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+
+	now := time.Now()
+	_, err = tx.ExecContext(ctx, tx.Rebind(reviewDeleteExpiredIdempotencyKeyQuery),
+		reviewIdempotencyScope, key, now.Add(-r.opts.idempotencyKeyTTL))
+	if err != nil {
+		tx.Rollback()
+		return domain.Review{}, persistenceError(r.db, err)
+	}
+
+	result, err := tx.ExecContext(ctx, tx.Rebind(reviewInsertIdempotencyKeyQuery),
+		reviewIdempotencyScope, key, pgReview.ID, now)
+	if err != nil {
+		tx.Rollback()
+		return domain.Review{}, persistenceError(r.db, err)
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		tx.Rollback()
+		return domain.Review{}, persistenceError(r.db, err)
+	}
+
+	reviewID := domain.ID(pgReview.ID.String())
+	if inserted == 0 {
+		err = tx.GetContext(ctx, &reviewID, tx.Rebind(reviewFindIdempotencyKeyQuery), reviewIdempotencyScope, key)
+		if err != nil {
+			tx.Rollback()
+			return domain.Review{}, persistenceError(r.db, err)
+		}
+	} else {
+		queryString := entity.InsertQueryString(pgReview, "review")
+		_, err = namedExecContext(ctx, tx, queryString, pgReview)
+		if err != nil {
+			tx.Rollback()
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				if pgErr.Code == PgUniqueViolationCode {
+					return domain.Review{}, uniqueViolationError(pgErr, err)
+				} else {
+					return domain.Review{}, persistenceError(r.db, err)
+				}
+			} else {
+				return domain.Review{}, persistenceError(r.db, err)
+			}
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return domain.Review{}, errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+
+	return r.FindByID(ReadYourWrites(ctx), reviewID)
+}
+
+// UpsertCourseReview creates the review of the user for the course, or
+// replaces the text of the live one they already wrote, keeping its rating
+// as domain.Review does not carry one. A user holds one live review per
+// course, so the conflict is resolved on that unique key in one statement.
+func (r *PostgresReviewRepo) UpsertCourseReview(ctx context.Context, review domain.Review) (domain.Review, error) {
+	pgReview, err := entity.NewPgReview(review)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	upserted, err := r.upsert(ctx, pgReview)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	return upserted.ToDomain(), nil
+}
+
+// UpsertRatedCourseReview is UpsertCourseReview replacing the rating of the
+// review as well, between 1 and 5.
+func (r *PostgresReviewRepo) UpsertRatedCourseReview(ctx context.Context, review domain.Review,
+	rating int) (RatedReview, error) {
+	pgReview, err := entity.NewPgRatedReview(review, rating)
+	if err != nil {
+		return RatedReview{}, err
+	}
+	upserted, err := r.upsert(ctx, pgReview)
+	if err != nil {
+		return RatedReview{}, err
+	}
+	return newRatedReview(upserted), nil
+}
+
+func (r *PostgresReviewRepo) upsert(ctx context.Context, pgReview entity.PgReview) (entity.PgReview, error) {
+	if err := r.opts.checkWritable(); err != nil {
+		return entity.PgReview{}, err
+	}
+	if err := pgReview.Validate(); err != nil {
+		return entity.PgReview{}, err
+	}
+
+	queryString := strings.TrimSuffix(entity.InsertQueryString(pgReview, "review"), " RETURNING *") +
+		reviewUpsertUserCourseReviewSuffix
+	if err := entity.ValidateNamedQuery(pgReview, queryString); err != nil {
+		return entity.PgReview{}, err
+	}
+	query, args, err := sqlx.Named(queryString, pgReview)
+	if err != nil {
+		return entity.PgReview{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+	}
+
+	var upserted entity.PgReview
+	if err = r.db.GetContext(ctx, &upserted, r.db.Rebind(query), args...); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return entity.PgReview{}, uniqueViolationError(pgErr, err)
+		}
+		return entity.PgReview{}, persistenceError(r.db, err)
+	}
+	return upserted, nil
+}
+
+// IncrementHelpful records one more helpful vote on the review.
+func (r *PostgresReviewRepo) IncrementHelpful(ctx context.Context, reviewID domain.ID) error {
+	if err := r.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(reviewIncrementHelpfulQuery), reviewID)
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	incremented, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	if incremented == 0 {
+		return errors.Wrapf(errs.ErrNotExist, "review %s", reviewID)
+	}
+	return nil
+}
+
+// AddReviewResponse stores the instructor response to the review, replacing
+// the previous one if any.
+func (r *PostgresReviewRepo) AddReviewResponse(ctx context.Context, reviewID domain.ID, text string) error {
+	if err := r.opts.checkWritable(); err != nil {
+		return err
+	}
+	if text == "" {
+		return errors.Wrap(entity.ErrValidation, "review response is empty")
+	}
+
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(reviewAddResponseQuery), text, reviewID)
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	responded, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	if responded == 0 {
+		return errors.Wrapf(errs.ErrNotExist, "review %s", reviewID)
+	}
+	return nil
+}
+
+// FlagReview records one more abuse flag on the review.
+func (r *PostgresReviewRepo) FlagReview(ctx context.Context, reviewID domain.ID) error {
+	if err := r.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(reviewFlagQuery), reviewID)
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	flagged, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	if flagged == 0 {
+		return errors.Wrapf(errs.ErrNotExist, "review %s", reviewID)
+	}
+	return nil
+}
+
+// Delete hides the review from the listings and the rating aggregates,
+// the row is kept so that a moderator can restore it.
 func (r *PostgresReviewRepo) Delete(ctx context.Context, reviewID domain.ID) error {
-	_, err := r.db.ExecContext(ctx, reviewDeleteQuery, reviewID)
+	if err := r.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := r.db.ExecContext(ctx, r.db.Rebind(reviewDeleteQuery), reviewID)
 	if err != nil {
 		return errors.Wrap(errs.ErrDeleteFailed, err.Error())
 	}
 	return nil
 }
+
+// RestoreReview brings back a review hidden by Delete. It fails with
+// errs.ErrDuplicate when the user has written another live review of the
+// course since.
+func (r *PostgresReviewRepo) RestoreReview(ctx context.Context, reviewID domain.ID) error {
+	if err := r.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	result, err := r.db.ExecContext(ctx, r.db.Rebind(reviewRestoreQuery), reviewID)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return uniqueViolationError(pgErr, err)
+		}
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	restored, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	if restored == 0 {
+		return errors.Wrapf(errs.ErrNotExist, "deleted review %s", reviewID)
+	}
+	return nil
+}
+
+// FindDeletedReviews lists the reviews hidden by Delete, the most recently
+// deleted first, for moderators to review.
+func (r *PostgresReviewRepo) FindDeletedReviews(ctx context.Context) ([]domain.Review, error) {
+	if err := r.opts.checkRateLimit("review.FindDeletedReviews"); err != nil {
+		return nil, err
+	}
+
+	var pgReviews []entity.PgReview
+	if err := selectRows(ctx, r.db, r.opts, "review.FindDeletedReviews", &pgReviews,
+		reviewFindDeletedReviewsQuery); err != nil {
+		return nil, err
+	}
+	r.opts.observeRows("review.FindDeletedReviews", len(pgReviews))
+
+	reviews := make([]domain.Review, len(pgReviews))
+	for i, review := range pgReviews {
+		reviews[i] = review.ToDomain()
+	}
+	return reviews, nil
+}
+
+func newRatedReview(pgReview entity.PgReview) RatedReview {
+	return RatedReview{
+		Review: pgReview.ToDomain(),
+		Rating: pgReview.Rating,
+	}
+}
@@ -9,42 +9,132 @@ import (
 	"github.com/paw1a/eschool-core/errs"
 	"github.com/paw1a/eschool-repository/postgres/entity"
 	"github.com/pkg/errors"
+	"time"
 )
 
 type PostgresSchoolRepo struct {
-	db *sqlx.DB
+	db   *sqlx.DB
+	opts options
 }
 
-func NewSchoolRepo(db *sqlx.DB) *PostgresSchoolRepo {
+type TeacherWithCount struct {
+	Teacher     domain.User
+	CourseCount int
+}
+
+type ReviewerCount struct {
+	Reviewer    domain.User
+	ReviewCount int
+}
+
+// CourseWithRating is a course with the average rating and the number of its
+// reviews, both 0 for a course without reviews.
+type CourseWithRating struct {
+	Course        domain.Course
+	AverageRating float64
+	ReviewCount   int
+}
+
+type SchoolWithMetric struct {
+	School domain.School
+	Metric int
+}
+
+func NewSchoolRepo(db *sqlx.DB, opts ...Option) *PostgresSchoolRepo {
 	return &PostgresSchoolRepo{
-		db: db,
+		db:   db,
+		opts: newOptions(opts),
 	}
 }
 
 const (
-	schoolFindAllQuery            = "SELECT * FROM public.school"
-	schoolFindByIDQuery           = "SELECT * FROM public.school WHERE id = $1"
-	schoolFindUserSchoolsQuery    = "SELECT * FROM public.school WHERE owner_id = $1"
-	schoolFindSchoolCoursesQuery  = "SELECT * FROM public.course WHERE school_id = $1"
-	schoolFindSchoolTeachersQuery = "SELECT u.* FROM public.user u " +
+	SchoolRoleOwner   = "owner"
+	SchoolRoleTeacher = "teacher"
+)
+
+// SchoolStatus is the platform approval state of a school. A school is
+// created pending and waits in the governance queue until approved.
+type SchoolStatus string
+
+const (
+	SchoolStatusPending  SchoolStatus = "pending"
+	SchoolStatusApproved SchoolStatus = "approved"
+)
+
+const (
+	schoolFindAllQuery             = "SELECT * FROM public.school"
+	schoolFindByIDQuery            = "SELECT * FROM public.school WHERE id = ?"
+	schoolFindByNameQuery          = "SELECT * FROM public.school WHERE name = ? ORDER BY id LIMIT 1"
+	schoolFindByOwnerAndNameQuery  = "SELECT * FROM public.school WHERE owner_id = ? AND name = ? ORDER BY id LIMIT 1"
+	schoolFindBySlugQuery          = "SELECT * FROM public.school WHERE slug = ?"
+	schoolFindSlugQuery            = "SELECT slug FROM public.school WHERE id = ?"
+	schoolFindPageQuery            = "SELECT *, COUNT(*) OVER() AS total FROM public.school ORDER BY id LIMIT ? OFFSET ?"
+	schoolCountQuery               = "SELECT COUNT(*) FROM public.school"
+	schoolFindUserSchoolsQuery     = "SELECT * FROM public.school WHERE owner_id = ?"
+	schoolFindByOwnerIDsQuery      = "SELECT * FROM public.school WHERE owner_id IN (?) ORDER BY id"
+	schoolFindUserSchoolRolesQuery = "SELECT id AS school_id, 'owner' AS role FROM public.school " +
+		"WHERE owner_id = ? UNION SELECT school_id, 'teacher' AS role FROM public.school_teacher " +
+		"WHERE teacher_id = ?"
+	schoolFindTopReviewersQuery = "SELECT u.*, COUNT(r.id) AS review_count FROM public.review r " +
+		"JOIN public.course c on r.course_id = c.id " +
+		"JOIN public.user u on r.user_id = u.id " +
+		"WHERE c.school_id = ? AND r.deleted_at IS NULL GROUP BY u.id ORDER BY review_count DESC, u.id LIMIT ?"
+	schoolFindPopularSchoolsQuery = "SELECT s.*, COUNT(cs.student_id) AS metric FROM public.school s " +
+		"LEFT JOIN public.course c on c.school_id = s.id " +
+		"LEFT JOIN public.course_student cs on cs.course_id = c.id " +
+		"GROUP BY s.id ORDER BY metric DESC, s.id LIMIT ?"
+	schoolFindMostActiveSchoolsQuery = "SELECT s.*, " +
+		"(SELECT COUNT(*) FROM public.course_student cs JOIN public.course c on cs.course_id = c.id " +
+		"WHERE c.school_id = s.id AND cs.enrolled_at >= ?) + " +
+		"(SELECT COUNT(*) FROM public.review r JOIN public.course c on r.course_id = c.id " +
+		"WHERE c.school_id = s.id AND r.created_at >= ? AND r.deleted_at IS NULL) AS metric " +
+		"FROM public.school s ORDER BY metric DESC, s.id LIMIT ?"
+	schoolFindSchoolCoursesByStatusQuery = "SELECT * FROM public.course WHERE school_id = ? AND status = ? ORDER BY id"
+	schoolFindSchoolCoursesQuery         = "SELECT * FROM public.course WHERE school_id = ?"
+	schoolFindSchoolTeachersQuery        = "SELECT u.* FROM public.user u " +
+		"JOIN public.school_teacher st on u.id = st.teacher_id " +
+		"JOIN public.school s on st.school_id = s.id WHERE s.id = ?"
+	schoolFindSchoolCoursesWithRatingsQuery = "SELECT c.*, COALESCE(r.average_rating, 0) AS average_rating, " +
+		"r.review_count FROM public.course c LEFT JOIN LATERAL (SELECT AVG(rating) AS average_rating, " +
+		"COUNT(*) AS review_count FROM public.review WHERE course_id = c.id AND deleted_at IS NULL) r on true " +
+		"WHERE c.school_id = ? ORDER BY c.id LIMIT ? OFFSET ?"
+	schoolFindSchoolTeachersExcludingOwnerQuery = "SELECT u.* FROM public.user u " +
+		"JOIN public.school_teacher st on u.id = st.teacher_id " +
+		"JOIN public.school s on st.school_id = s.id WHERE s.id = ? AND u.id <> s.owner_id"
+	schoolFindByStatusQuery = "SELECT *, COUNT(*) OVER() AS total FROM public.school WHERE status = ? " +
+		"ORDER BY id LIMIT ? OFFSET ?"
+	schoolCountByStatusQuery      = "SELECT COUNT(*) FROM public.school WHERE status = ?"
+	schoolFindStatusQuery         = "SELECT status FROM public.school WHERE id = ?"
+	schoolApproveQuery            = "UPDATE public.school SET status = 'approved' WHERE id = ? AND status = 'pending'"
+	schoolFindTeacherSchoolsQuery = "SELECT s.* FROM public.school s " +
+		"JOIN public.school_teacher st on st.school_id = s.id WHERE st.teacher_id = ? ORDER BY s.id"
+	schoolFindSchoolTeachersWithCourseCountQuery = "SELECT u.*, COUNT(c.id) AS course_count " +
+		"FROM public.user u " +
 		"JOIN public.school_teacher st on u.id = st.teacher_id " +
-		"JOIN public.school s on st.school_id = s.id WHERE s.id = $1"
+		"LEFT JOIN public.course_teacher ct on u.id = ct.teacher_id " +
+		"LEFT JOIN public.course c on ct.course_id = c.id AND c.school_id = st.school_id " +
+		"WHERE st.school_id = ? GROUP BY u.id ORDER BY u.id"
 	schoolContainsTeacherQuery = "SELECT EXISTS (SELECT 1 FROM public.school_teacher " +
-		"WHERE school_id = $1 AND teacher_id = $2)"
+		"WHERE school_id = ? AND teacher_id = ?)"
 	schoolAddTeacherQuery = "INSERT INTO public.school_teacher (teacher_id, school_id) " +
-		"VALUES ($1, $2)"
-	schoolDeleteQuery = "DELETE FROM public.school WHERE id = $1"
+		"VALUES (?, ?)"
+	schoolDeleteQuery = "DELETE FROM public.school WHERE id = ?"
+
+	schoolLockOwnerQuery = "SELECT true FROM public.user WHERE id = ? FOR KEY SHARE"
+
+	schoolApplyCourseDiscountQuery = "UPDATE public.course SET price = price - price * ? / 100 WHERE school_id = ?"
 )
 
 func (s *PostgresSchoolRepo) FindAll(ctx context.Context) ([]domain.School, error) {
+	if err := s.opts.checkRateLimit("school.FindAll"); err != nil {
+		return nil, err
+	}
+
 	var pgSchools []entity.PgSchool
-	if err := s.db.SelectContext(ctx, &pgSchools, schoolFindAllQuery); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, s.db, s.opts, "school.FindAll", &pgSchools, schoolFindAllQuery); err != nil {
+		return nil, err
 	}
+	s.opts.observeRows("school.FindAll", len(pgSchools))
 
 	schools := make([]domain.School, len(pgSchools))
 	for i, school := range pgSchools {
@@ -53,27 +143,162 @@ func (s *PostgresSchoolRepo) FindAll(ctx context.Context) ([]domain.School, erro
 	return schools, nil
 }
 
+// FindSchoolsPage returns a page of schools and the total number of schools
+// in one query. The total is counted separately only for a page past the end.
+func (s *PostgresSchoolRepo) FindSchoolsPage(ctx context.Context, params PageParams) (Page[domain.School], error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolsPage"); err != nil {
+		return Page[domain.School]{}, err
+	}
+
+	var pgSchools []entity.PgSchoolWithTotal
+	if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolsPage", &pgSchools,
+		schoolFindPageQuery, params.Limit, params.Offset); err != nil {
+		return Page[domain.School]{}, err
+	}
+	s.opts.observeRows("school.FindSchoolsPage", len(pgSchools))
+
+	page := Page[domain.School]{
+		Items:  make([]domain.School, len(pgSchools)),
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}
+	for i, school := range pgSchools {
+		page.Items[i] = school.ToDomain()
+	}
+
+	if len(pgSchools) > 0 {
+		page.Total = pgSchools[0].Total
+	} else if params.Offset > 0 {
+		if err := s.db.GetContext(ctx, &page.Total, s.db.Rebind(schoolCountQuery)); err != nil {
+			return Page[domain.School]{}, persistenceError(s.db, err)
+		}
+	}
+	return page, nil
+}
+
+// FindSchoolsByStatus returns a page of the schools in the given approval
+// status, FindSchoolsByStatus(ctx, SchoolStatusPending, page) is the
+// governance queue.
+func (s *PostgresSchoolRepo) FindSchoolsByStatus(ctx context.Context, status SchoolStatus,
+	params PageParams) (Page[domain.School], error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolsByStatus"); err != nil {
+		return Page[domain.School]{}, err
+	}
+
+	if status != SchoolStatusPending && status != SchoolStatusApproved {
+		return Page[domain.School]{}, errors.Wrapf(errs.ErrEnumValueError, "school status %q", status)
+	}
+
+	var pgSchools []entity.PgSchoolWithTotal
+	if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolsByStatus", &pgSchools,
+		schoolFindByStatusQuery, status, params.Limit, params.Offset); err != nil {
+		return Page[domain.School]{}, err
+	}
+	s.opts.observeRows("school.FindSchoolsByStatus", len(pgSchools))
+
+	page := Page[domain.School]{
+		Items:  make([]domain.School, len(pgSchools)),
+		Limit:  params.Limit,
+		Offset: params.Offset,
+	}
+	for i, school := range pgSchools {
+		page.Items[i] = school.ToDomain()
+	}
+
+	if len(pgSchools) > 0 {
+		page.Total = pgSchools[0].Total
+	} else if params.Offset > 0 {
+		if err := s.db.GetContext(ctx, &page.Total, s.db.Rebind(schoolCountByStatusQuery), status); err != nil {
+			return Page[domain.School]{}, persistenceError(s.db, err)
+		}
+	}
+	return page, nil
+}
+
 func (s *PostgresSchoolRepo) FindByID(ctx context.Context, schoolID domain.ID) (domain.School, error) {
 	var pgSchool entity.PgSchool
-	if err := s.db.GetContext(ctx, &pgSchool, schoolFindByIDQuery, schoolID); err != nil {
+	db := s.opts.reader(ctx, s.db)
+	if err := db.GetContext(ctx, &pgSchool, db.Rebind(schoolFindByIDQuery), schoolID); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.School{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.School{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.School{}, persistenceError(db, err)
 		}
 	}
 	return pgSchool.ToDomain(), nil
 }
 
-func (s *PostgresSchoolRepo) FindUserSchools(ctx context.Context, userID domain.ID) ([]domain.School, error) {
-	var pgSchools []entity.PgSchool
-	if err := s.db.SelectContext(ctx, &pgSchools, schoolFindUserSchoolsQuery, userID); err != nil {
+// FindByName finds a school by its exact name. Names are not unique, the
+// school with the lowest id among the ones sharing the name is returned, so
+// a public URL should resolve the school with FindBySlug instead.
+func (s *PostgresSchoolRepo) FindByName(ctx context.Context, name string) (domain.School, error) {
+	var pgSchool entity.PgSchool
+	if err := s.db.GetContext(ctx, &pgSchool, s.db.Rebind(schoolFindByNameQuery), name); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
+			return domain.School{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.School{}, persistenceError(s.db, err)
+		}
+	}
+	return pgSchool.ToDomain(), nil
+}
+
+// FindBySlug resolves a school by its slug, the unique URL name the database
+// derives from the school name and id.
+func (s *PostgresSchoolRepo) FindBySlug(ctx context.Context, slug string) (domain.School, error) {
+	var pgSchool entity.PgSchool
+	if err := s.db.GetContext(ctx, &pgSchool, s.db.Rebind(schoolFindBySlugQuery), slug); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.School{}, errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return domain.School{}, persistenceError(s.db, err)
 		}
 	}
+	return pgSchool.ToDomain(), nil
+}
+
+// FindSchoolSlug returns the slug of the school, which domain.School does
+// not carry, to build its public URL. The slug changes with the name.
+func (s *PostgresSchoolRepo) FindSchoolSlug(ctx context.Context, schoolID domain.ID) (string, error) {
+	var slug string
+	if err := s.db.GetContext(ctx, &slug, s.db.Rebind(schoolFindSlugQuery), schoolID); err != nil {
+		if err == sql.ErrNoRows {
+			return "", errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return "", persistenceError(s.db, err)
+		}
+	}
+	return slug, nil
+}
+
+// FindSchoolByOwnerAndName finds the school with the name among the schools
+// of the owner, a school of another owner with that name is not returned.
+// When the owner has several schools with the name, the one with the lowest
+// id is returned.
+func (s *PostgresSchoolRepo) FindSchoolByOwnerAndName(ctx context.Context, ownerID domain.ID,
+	name string) (domain.School, error) {
+	var pgSchool entity.PgSchool
+	if err := s.db.GetContext(ctx, &pgSchool, s.db.Rebind(schoolFindByOwnerAndNameQuery), ownerID, name); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.School{}, errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return domain.School{}, persistenceError(s.db, err)
+		}
+	}
+	return pgSchool.ToDomain(), nil
+}
+
+func (s *PostgresSchoolRepo) FindUserSchools(ctx context.Context, userID domain.ID) ([]domain.School, error) {
+	if err := s.opts.checkRateLimit("school.FindUserSchools"); err != nil {
+		return nil, err
+	}
+
+	var pgSchools []entity.PgSchool
+	if err := selectRows(ctx, s.db, s.opts, "school.FindUserSchools", &pgSchools,
+		schoolFindUserSchoolsQuery, userID); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindUserSchools", len(pgSchools))
 
 	schools := make([]domain.School, len(pgSchools))
 	for i, school := range pgSchools {
@@ -82,32 +307,165 @@ func (s *PostgresSchoolRepo) FindUserSchools(ctx context.Context, userID domain.
 	return schools, nil
 }
 
-func (s *PostgresSchoolRepo) FindSchoolCourses(ctx context.Context, schoolID domain.ID) ([]domain.Course, error) {
-	var pgCourses []entity.PgCourse
-	if err := s.db.SelectContext(ctx, &pgCourses, schoolFindSchoolCoursesQuery, schoolID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
+// FindTeacherSchools lists the schools the user teaches at, the inverse of
+// FindSchoolTeachers.
+func (s *PostgresSchoolRepo) FindTeacherSchools(ctx context.Context, teacherID domain.ID) ([]domain.School, error) {
+	if err := s.opts.checkRateLimit("school.FindTeacherSchools"); err != nil {
+		return nil, err
+	}
+
+	var pgSchools []entity.PgSchool
+	if err := selectRows(ctx, s.db, s.opts, "school.FindTeacherSchools", &pgSchools,
+		schoolFindTeacherSchoolsQuery, teacherID); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindTeacherSchools", len(pgSchools))
+
+	schools := make([]domain.School, len(pgSchools))
+	for i, school := range pgSchools {
+		schools[i] = school.ToDomain()
+	}
+	return schools, nil
+}
+
+// FindSchoolsByOwnerIDs returns the schools of every given owner keyed by
+// owner, owners without schools are absent from the map. The owners are
+// looked up 1000 at a time.
+func (s *PostgresSchoolRepo) FindSchoolsByOwnerIDs(ctx context.Context,
+	ownerIDs []domain.ID) (map[domain.ID][]domain.School, error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolsByOwnerIDs"); err != nil {
+		return nil, err
+	}
+
+	schools := make(map[domain.ID][]domain.School)
+	if len(ownerIDs) == 0 {
+		return schools, nil
+	}
+
+	var pgSchools []entity.PgSchool
+	for _, chunk := range chunkIDs(ownerIDs, inChunkSize) {
+		query, args, err := sqlx.In(schoolFindByOwnerIDsQuery, chunk)
+		if err != nil {
 			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
 		}
+
+		var pgChunk []entity.PgSchool
+		if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolsByOwnerIDs", &pgChunk, query, args...); err != nil {
+			return nil, err
+		}
+		pgSchools = append(pgSchools, pgChunk...)
+	}
+	s.opts.observeRows("school.FindSchoolsByOwnerIDs", len(pgSchools))
+
+	for _, pgSchool := range pgSchools {
+		school := pgSchool.ToDomain()
+		schools[school.OwnerID] = append(schools[school.OwnerID], school)
+	}
+	return schools, nil
+}
+
+// FindUserSchoolRoles maps every school the user owns or teaches at to the
+// user's role there, owning a school takes precedence over teaching at it.
+func (s *PostgresSchoolRepo) FindUserSchoolRoles(ctx context.Context,
+	userID domain.ID) (map[domain.ID]string, error) {
+	if err := s.opts.checkRateLimit("school.FindUserSchoolRoles"); err != nil {
+		return nil, err
+	}
+
+	var pgRoles []entity.PgSchoolRole
+	if err := selectRows(ctx, s.db, s.opts, "school.FindUserSchoolRoles", &pgRoles,
+		schoolFindUserSchoolRolesQuery, userID, userID); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindUserSchoolRoles", len(pgRoles))
+
+	roles := make(map[domain.ID]string, len(pgRoles))
+	for _, role := range pgRoles {
+		schoolID := domain.ID(role.SchoolID.String())
+		if role.Role == SchoolRoleOwner || roles[schoolID] == "" {
+			roles[schoolID] = role.Role
+		}
+	}
+	return roles, nil
+}
+
+func (s *PostgresSchoolRepo) FindSchoolCourses(ctx context.Context, schoolID domain.ID) ([]domain.Course, error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolCourses"); err != nil {
+		return nil, err
 	}
 
-	courses := make([]domain.Course, len(pgCourses))
-	for i, course := range pgCourses {
-		courses[i] = course.ToDomain()
+	var pgCourses []entity.PgCourse
+	if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolCourses", &pgCourses,
+		schoolFindSchoolCoursesQuery, schoolID); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindSchoolCourses", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
+}
+
+// FindSchoolCoursesWithRatings returns the school courses, each with the
+// rating summary of its reviews, computed in the same query.
+func (s *PostgresSchoolRepo) FindSchoolCoursesWithRatings(ctx context.Context, schoolID domain.ID,
+	page PageParams) ([]CourseWithRating, error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolCoursesWithRatings"); err != nil {
+		return nil, err
+	}
+
+	var pgCourses []entity.PgCourseWithRating
+	if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolCoursesWithRatings", &pgCourses,
+		schoolFindSchoolCoursesWithRatingsQuery, schoolID, page.Limit, page.Offset); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindSchoolCoursesWithRatings", len(pgCourses))
+
+	courses := make([]CourseWithRating, len(pgCourses))
+	for i, pgCourse := range pgCourses {
+		course, err := pgCourse.ToDomainChecked()
+		if err != nil {
+			return nil, err
+		}
+		courses[i] = CourseWithRating{
+			Course:        course,
+			AverageRating: pgCourse.AverageRating,
+			ReviewCount:   pgCourse.ReviewCount,
+		}
 	}
 	return courses, nil
 }
 
+func (s *PostgresSchoolRepo) FindSchoolCoursesByStatus(ctx context.Context, schoolID domain.ID,
+	status domain.CourseStatus) ([]domain.Course, error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolCoursesByStatus"); err != nil {
+		return nil, err
+	}
+
+	pgStatus := entity.NewPgCourseStatus(status)
+	if pgStatus == "" {
+		return nil, errors.Wrapf(errs.ErrEnumValueError, "course status %v", status)
+	}
+
+	var pgCourses []entity.PgCourse
+	if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolCoursesByStatus", &pgCourses,
+		schoolFindSchoolCoursesByStatusQuery, schoolID, pgStatus); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindSchoolCoursesByStatus", len(pgCourses))
+
+	return coursesToDomain(pgCourses)
+}
+
 func (s *PostgresSchoolRepo) FindSchoolTeachers(ctx context.Context, schoolID domain.ID) ([]domain.User, error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolTeachers"); err != nil {
+		return nil, err
+	}
+
 	var pgUsers []entity.PgUser
-	if err := s.db.SelectContext(ctx, &pgUsers, schoolFindSchoolTeachersQuery, schoolID); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolTeachers", &pgUsers,
+		schoolFindSchoolTeachersQuery, schoolID); err != nil {
+		return nil, err
 	}
+	s.opts.observeRows("school.FindSchoolTeachers", len(pgUsers))
 
 	teachers := make([]domain.User, len(pgUsers))
 	for i, teacher := range pgUsers {
@@ -116,9 +474,130 @@ func (s *PostgresSchoolRepo) FindSchoolTeachers(ctx context.Context, schoolID do
 	return teachers, nil
 }
 
+// FindSchoolTeachersExcludingOwner lists the school teachers like
+// FindSchoolTeachers, but leaves out the owner even if they teach there too.
+func (s *PostgresSchoolRepo) FindSchoolTeachersExcludingOwner(ctx context.Context,
+	schoolID domain.ID) ([]domain.User, error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolTeachersExcludingOwner"); err != nil {
+		return nil, err
+	}
+
+	var pgUsers []entity.PgUser
+	if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolTeachersExcludingOwner", &pgUsers,
+		schoolFindSchoolTeachersExcludingOwnerQuery, schoolID); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindSchoolTeachersExcludingOwner", len(pgUsers))
+
+	teachers := make([]domain.User, len(pgUsers))
+	for i, teacher := range pgUsers {
+		teachers[i] = teacher.ToDomain()
+	}
+	return teachers, nil
+}
+
+func (s *PostgresSchoolRepo) FindSchoolTeachersWithCourseCount(ctx context.Context,
+	schoolID domain.ID) ([]TeacherWithCount, error) {
+	if err := s.opts.checkRateLimit("school.FindSchoolTeachersWithCourseCount"); err != nil {
+		return nil, err
+	}
+
+	var pgTeachers []entity.PgTeacherWithCount
+	if err := selectRows(ctx, s.db, s.opts, "school.FindSchoolTeachersWithCourseCount", &pgTeachers,
+		schoolFindSchoolTeachersWithCourseCountQuery, schoolID); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindSchoolTeachersWithCourseCount", len(pgTeachers))
+
+	teachers := make([]TeacherWithCount, len(pgTeachers))
+	for i, teacher := range pgTeachers {
+		teachers[i] = TeacherWithCount{
+			Teacher:     teacher.ToDomain(),
+			CourseCount: teacher.CourseCount,
+		}
+	}
+	return teachers, nil
+}
+
+// FindTopReviewers returns the users who reviewed the school courses the
+// most, with their review counts.
+func (s *PostgresSchoolRepo) FindTopReviewers(ctx context.Context, schoolID domain.ID,
+	limit int) ([]ReviewerCount, error) {
+	if err := s.opts.checkRateLimit("school.FindTopReviewers"); err != nil {
+		return nil, err
+	}
+
+	var pgReviewers []entity.PgReviewerCount
+	if err := selectRows(ctx, s.db, s.opts, "school.FindTopReviewers", &pgReviewers,
+		schoolFindTopReviewersQuery, schoolID, limit); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindTopReviewers", len(pgReviewers))
+
+	reviewers := make([]ReviewerCount, len(pgReviewers))
+	for i, reviewer := range pgReviewers {
+		reviewers[i] = ReviewerCount{
+			Reviewer:    reviewer.ToDomain(),
+			ReviewCount: reviewer.ReviewCount,
+		}
+	}
+	return reviewers, nil
+}
+
+// FindPopularSchools ranks the schools by the number of enrollments across
+// their courses, the metric is the enrollment count. Schools nobody enrolled
+// in rank last.
+func (s *PostgresSchoolRepo) FindPopularSchools(ctx context.Context, limit int) ([]SchoolWithMetric, error) {
+	if err := s.opts.checkRateLimit("school.FindPopularSchools"); err != nil {
+		return nil, err
+	}
+
+	var pgSchools []entity.PgSchoolWithMetric
+	if err := selectRows(ctx, s.db, s.opts, "school.FindPopularSchools", &pgSchools,
+		schoolFindPopularSchoolsQuery, limit); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindPopularSchools", len(pgSchools))
+
+	schools := make([]SchoolWithMetric, len(pgSchools))
+	for i, school := range pgSchools {
+		schools[i] = SchoolWithMetric{
+			School: school.ToDomain(),
+			Metric: school.Metric,
+		}
+	}
+	return schools, nil
+}
+
+// FindMostActiveSchools ranks the schools by their activity since the given
+// time, the metric is the number of enrollments in and reviews of their
+// courses. Schools without activity rank last.
+func (s *PostgresSchoolRepo) FindMostActiveSchools(ctx context.Context, since time.Time,
+	limit int) ([]SchoolWithMetric, error) {
+	if err := s.opts.checkRateLimit("school.FindMostActiveSchools"); err != nil {
+		return nil, err
+	}
+
+	var pgSchools []entity.PgSchoolWithMetric
+	if err := selectRows(ctx, s.db, s.opts, "school.FindMostActiveSchools", &pgSchools,
+		schoolFindMostActiveSchoolsQuery, since.UTC(), since.UTC(), limit); err != nil {
+		return nil, err
+	}
+	s.opts.observeRows("school.FindMostActiveSchools", len(pgSchools))
+
+	schools := make([]SchoolWithMetric, len(pgSchools))
+	for i, school := range pgSchools {
+		schools[i] = SchoolWithMetric{
+			School: school.ToDomain(),
+			Metric: school.Metric,
+		}
+	}
+	return schools, nil
+}
+
 func (s *PostgresSchoolRepo) IsSchoolTeacher(ctx context.Context, schoolID, teacherID domain.ID) (bool, error) {
 	var exists bool
-	err := s.db.GetContext(ctx, &exists, schoolContainsTeacherQuery, schoolID, teacherID)
+	err := s.db.GetContext(ctx, &exists, s.db.Rebind(schoolContainsTeacherQuery), schoolID, teacherID)
 	if err != nil {
 		return false, err
 	}
@@ -126,76 +605,218 @@ func (s *PostgresSchoolRepo) IsSchoolTeacher(ctx context.Context, schoolID, teac
 }
 
 func (s *PostgresSchoolRepo) AddSchoolTeacher(ctx context.Context, schoolID, teacherID domain.ID) error {
-	_, err := s.db.ExecContext(ctx, schoolAddTeacherQuery, teacherID, schoolID)
+	if err := s.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(schoolAddTeacherQuery), teacherID, schoolID)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
 				return errors.Wrap(errs.ErrDuplicate, err.Error())
 			} else {
-				return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return persistenceError(s.db, err)
 			}
 		} else {
-			return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return persistenceError(s.db, err)
 		}
 	}
 	return nil
 }
 
+// ApplyDiscountToSchoolCourses lowers the price of every course of the
+// school by percent, rounding the discount down, and returns the number of
+// discounted courses. percent must be between 1 and 99.
+func (s *PostgresSchoolRepo) ApplyDiscountToSchoolCourses(ctx context.Context, schoolID domain.ID,
+	percent int) (int, error) {
+	if err := s.opts.checkWritable(); err != nil {
+		return 0, err
+	}
+	if percent <= 0 || percent >= 100 {
+		return 0, errors.Wrapf(ErrInvalidDiscount, "%d%%", percent)
+	}
+
+	var discounted int64
+	err := WithinTx(ctx, s.db, nil, func(tx *sqlx.Tx) error {
+		result, err := tx.ExecContext(ctx, tx.Rebind(schoolApplyCourseDiscountQuery), percent, schoolID)
+		if err != nil {
+			return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+		}
+		discounted, err = result.RowsAffected()
+		if err != nil {
+			return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int(discounted), nil
+}
+
 func (s *PostgresSchoolRepo) Create(ctx context.Context, school domain.School) (domain.School, error) {
-	var pgSchool = entity.NewPgSchool(school)
+	if err := s.opts.checkWritable(); err != nil {
+		return domain.School{}, err
+	}
+
+	pgSchool, err := entity.NewPgSchool(school)
+	if err != nil {
+		return domain.School{}, err
+	}
+	if err := pgSchool.Validate(); err != nil {
+		return domain.School{}, err
+	}
 	queryString := entity.InsertQueryString(pgSchool, "school")
-	_, err := s.db.NamedExecContext(ctx, queryString, pgSchool)
+	_, err = namedExecContext(ctx, s.db, queryString, pgSchool)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
-				return domain.School{}, errors.Wrap(errs.ErrDuplicate, err.Error())
+				return domain.School{}, uniqueViolationError(pgErr, err)
 			} else {
-				return domain.School{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return domain.School{}, persistenceError(s.db, err)
 			}
 		} else {
-			return domain.School{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.School{}, persistenceError(s.db, err)
 		}
 	}
 
 	var createdSchool entity.PgSchool
-	err = s.db.GetContext(ctx, &createdSchool, schoolFindByIDQuery, pgSchool.ID)
+	err = s.db.GetContext(ctx, &createdSchool, s.db.Rebind(schoolFindByIDQuery), pgSchool.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.School{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.School{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.School{}, persistenceError(s.db, err)
 		}
 	}
 
 	return createdSchool.ToDomain(), nil
 }
 
+// CreateSchool creates the school after checking that its owner exists. The
+// owner row is locked until the school is inserted, so that the owner cannot
+// be deleted in between, and a missing owner is reported as ErrOwnerNotExist.
+func (s *PostgresSchoolRepo) CreateSchool(ctx context.Context, school domain.School) (domain.School, error) {
+	if err := s.opts.checkWritable(); err != nil {
+		return domain.School{}, err
+	}
+
+	pgSchool, err := entity.NewPgSchool(school)
+	if err != nil {
+		return domain.School{}, err
+	}
+	if err := pgSchool.Validate(); err != nil {
+		return domain.School{}, err
+	}
+
+	var createdSchool entity.PgSchool
+	err = WithinTx(ctx, s.db, nil, func(tx *sqlx.Tx) error {
+		var ownerExists bool
+		err := tx.GetContext(ctx, &ownerExists, tx.Rebind(schoolLockOwnerQuery), pgSchool.OwnerID)
+		if err != nil {
+			if err == sql.ErrNoRows {
+				return errors.Wrapf(ErrOwnerNotExist, "owner %s", pgSchool.OwnerID)
+			} else {
+				return persistenceError(s.db, err)
+			}
+		}
+
+		queryString := entity.InsertQueryString(pgSchool, "school")
+		if _, err = namedExecContext(ctx, tx, queryString, pgSchool); err != nil {
+			var pgErr *pgconn.PgError
+			if errors.As(err, &pgErr) {
+				switch pgErr.Code {
+				case PgUniqueViolationCode:
+					return uniqueViolationError(pgErr, err)
+				case PgForeignKeyViolationCode:
+					return errors.Wrapf(ErrOwnerNotExist, "owner %s: %s", pgSchool.OwnerID, err.Error())
+				}
+			}
+			return persistenceError(s.db, err)
+		}
+
+		if err = tx.GetContext(ctx, &createdSchool, tx.Rebind(schoolFindByIDQuery), pgSchool.ID); err != nil {
+			if err == sql.ErrNoRows {
+				return errors.Wrap(errs.ErrNotExist, err.Error())
+			} else {
+				return persistenceError(s.db, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return domain.School{}, err
+	}
+	return createdSchool.ToDomain(), nil
+}
+
 func (s *PostgresSchoolRepo) Update(ctx context.Context, school domain.School) (domain.School, error) {
-	var pgSchool = entity.NewPgSchool(school)
+	if err := s.opts.checkWritable(); err != nil {
+		return domain.School{}, err
+	}
+
+	pgSchool, err := entity.NewPgSchool(school)
+	if err != nil {
+		return domain.School{}, err
+	}
 	queryString := entity.UpdateQueryString(pgSchool, "school")
-	_, err := s.db.NamedExecContext(ctx, queryString, pgSchool)
+	_, err = namedExecContext(ctx, s.db, queryString, pgSchool)
 	if err != nil {
 		return domain.School{}, errors.Wrap(errs.ErrUpdateFailed, err.Error())
 	}
 
 	var updatedSchool entity.PgSchool
-	err = s.db.GetContext(ctx, &updatedSchool, schoolFindByIDQuery, pgSchool.ID)
+	err = s.db.GetContext(ctx, &updatedSchool, s.db.Rebind(schoolFindByIDQuery), pgSchool.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.School{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.School{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.School{}, persistenceError(s.db, err)
 		}
 	}
 	return updatedSchool.ToDomain(), nil
 }
 
 func (s *PostgresSchoolRepo) Delete(ctx context.Context, schoolID domain.ID) error {
-	_, err := s.db.ExecContext(ctx, schoolDeleteQuery, schoolID)
+	if err := s.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := s.db.ExecContext(ctx, s.db.Rebind(schoolDeleteQuery), schoolID)
 	if err != nil {
 		return errors.Wrap(errs.ErrDeleteFailed, err.Error())
 	}
 	return nil
 }
+
+// ApproveSchool moves a pending school to approved, approving a school that
+// is not pending fails with ErrIllegalTransition.
+func (s *PostgresSchoolRepo) ApproveSchool(ctx context.Context, schoolID domain.ID) error {
+	if err := s.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	result, err := s.db.ExecContext(ctx, s.db.Rebind(schoolApproveQuery), schoolID)
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	approved, err := result.RowsAffected()
+	if err != nil {
+		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
+	}
+	if approved > 0 {
+		return nil
+	}
+
+	var status string
+	if err = s.db.GetContext(ctx, &status, s.db.Rebind(schoolFindStatusQuery), schoolID); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return persistenceError(s.db, err)
+		}
+	}
+	return errors.Wrapf(ErrIllegalTransition, "school status %s to %s", status, SchoolStatusApproved)
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/pkg/errors"
+)
+
+// selectRows runs the query of the finder labeled method, such as
+// "user.FindAll", into dest. Every finder reads through it, so that the
+// WithMaxResultRows cap applies to all of them: the query is limited to one
+// row past the cap and ErrResultTooLarge is returned instead of a cut result,
+// and that WithQueryLogger logs all of them.
+func selectRows[T any](ctx context.Context, db *sqlx.DB, opts options, method string, dest *[]T,
+	query string, args ...interface{}) error {
+	opts.logQuery(method, args...)
+	if err := db.SelectContext(ctx, dest, db.Rebind(opts.limitResultRows(query)), args...); err != nil {
+		if err == sql.ErrNoRows {
+			return errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return persistenceError(db, err)
+		}
+	}
+	return opts.checkResultRows(method, len(*dest))
+}
@@ -0,0 +1,19 @@
+package repository
+
+import "strings"
+
+// orderByClause renders the ORDER BY clause of a listing from its sort
+// columns, each given with its direction such as "created_at DESC". Every
+// column is followed by NULLS FIRST or NULLS LAST, so that the rows with a
+// NULL sort column land where asked whatever the direction. No columns
+// render no clause.
+func orderByClause(columns []string, nullsFirst bool) string {
+	if len(columns) == 0 {
+		return ""
+	}
+	nulls := " NULLS LAST"
+	if nullsFirst {
+		nulls = " NULLS FIRST"
+	}
+	return " ORDER BY " + strings.Join(columns, nulls+", ") + nulls
+}
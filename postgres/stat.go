@@ -12,51 +12,55 @@ import (
 )
 
 type PostgresStatRepo struct {
-	db *sqlx.DB
+	db   *sqlx.DB
+	opts options
 }
 
-func NewStatRepo(db *sqlx.DB) *PostgresStatRepo {
+func NewStatRepo(db *sqlx.DB, opts ...Option) *PostgresStatRepo {
 	return &PostgresStatRepo{
-		db: db,
+		db:   db,
+		opts: newOptions(opts),
 	}
 }
 
 const (
-	statFindByUserLessonQuery = "SELECT * FROM public.lesson_stat WHERE user_id = $1 AND lesson_id = $2"
-	statFindByUserTestQuery   = "SELECT * FROM public.test_stat WHERE user_id = $1 AND test_id = $2"
-	statFindLessonTestsQuery  = "SELECT * FROM public.test WHERE lesson_id = $1"
+	statFindByUserLessonQuery = "SELECT * FROM public.lesson_stat WHERE user_id = ? AND lesson_id = ?"
+	statFindByUserTestQuery   = "SELECT * FROM public.test_stat WHERE user_id = ? AND test_id = ?"
+	statFindLessonTestsQuery  = "SELECT * FROM public.test WHERE lesson_id = ?"
 )
 
 func (p *PostgresStatRepo) FindLessonStat(ctx context.Context,
 	userID, lessonID domain.ID) (domain.LessonStat, error) {
+	if err := p.opts.checkRateLimit("stat.FindLessonStat"); err != nil {
+		return domain.LessonStat{}, err
+	}
+
 	var pgLessonStat entity.PgLessonStat
-	if err := p.db.GetContext(ctx, &pgLessonStat, statFindByUserLessonQuery, userID, lessonID); err != nil {
+	if err := p.db.GetContext(ctx, &pgLessonStat, p.db.Rebind(statFindByUserLessonQuery), userID, lessonID); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.LessonStat{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.LessonStat{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.LessonStat{}, persistenceError(p.db, err)
 		}
 	}
 	lessonStat := pgLessonStat.ToDomain()
 
 	var pgTests []entity.PgTest
-	if err := p.db.SelectContext(ctx, &pgTests, statFindLessonTestsQuery, lessonID); err != nil {
-		if err == sql.ErrNoRows {
-			return lessonStat, nil
-		} else {
-			return domain.LessonStat{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, p.db, p.opts, "stat.FindLessonStat", &pgTests, statFindLessonTestsQuery,
+		lessonID); err != nil {
+		return domain.LessonStat{}, err
 	}
+	p.opts.observeRows("stat.FindLessonStat", len(pgTests))
 
 	testStats := make([]domain.TestStat, len(pgTests))
 	for i, pgTest := range pgTests {
 		test := pgTest.ToDomain()
 		var pgTestStat entity.PgTestStat
-		if err := p.db.GetContext(ctx, &pgTestStat, statFindByUserTestQuery, userID, test.ID); err != nil {
+		if err := p.db.GetContext(ctx, &pgTestStat, p.db.Rebind(statFindByUserTestQuery), userID, test.ID); err != nil {
 			if err == sql.ErrNoRows {
 				return domain.LessonStat{}, errors.Wrap(errs.ErrNotExist, err.Error())
 			} else {
-				return domain.LessonStat{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return domain.LessonStat{}, persistenceError(p.db, err)
 			}
 		}
 		testStats[i] = pgTestStat.ToDomain()
@@ -67,14 +71,22 @@ func (p *PostgresStatRepo) FindLessonStat(ctx context.Context,
 }
 
 func (p *PostgresStatRepo) CreateLessonStat(ctx context.Context, stat domain.LessonStat) error {
-	tx, err := p.db.Beginx()
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	tx, err := p.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return errors.Wrap(errs.ErrTransactionError, err.Error())
 	}
 
-	var pgLessonStat = entity.NewPgLessonStat(stat)
+	pgLessonStat, err := entity.NewPgLessonStat(stat)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 	queryString := entity.InsertQueryString(pgLessonStat, "lesson_stat")
-	_, err = tx.NamedExecContext(ctx, queryString, pgLessonStat)
+	_, err = namedExecContext(ctx, tx, queryString, pgLessonStat)
 	if err != nil {
 		tx.Rollback()
 		var pgErr *pgconn.PgError
@@ -82,17 +94,21 @@ func (p *PostgresStatRepo) CreateLessonStat(ctx context.Context, stat domain.Les
 			if pgErr.Code == PgUniqueViolationCode {
 				return errors.Wrap(errs.ErrDuplicate, err.Error())
 			} else {
-				return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return persistenceError(p.db, err)
 			}
 		} else {
-			return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return persistenceError(p.db, err)
 		}
 	}
 
 	for _, testStat := range stat.TestStats {
-		var pgTestStat = entity.NewPgTestStat(testStat)
+		pgTestStat, err := entity.NewPgTestStat(testStat)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 		queryString = entity.InsertQueryString(pgTestStat, "test_stat")
-		_, err = tx.NamedExecContext(ctx, queryString, pgTestStat)
+		_, err = namedExecContext(ctx, tx, queryString, pgTestStat)
 		if err != nil {
 			tx.Rollback()
 			var pgErr *pgconn.PgError
@@ -100,10 +116,10 @@ func (p *PostgresStatRepo) CreateLessonStat(ctx context.Context, stat domain.Les
 				if pgErr.Code == PgUniqueViolationCode {
 					return errors.Wrap(errs.ErrDuplicate, err.Error())
 				} else {
-					return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+					return persistenceError(p.db, err)
 				}
 			} else {
-				return errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return persistenceError(p.db, err)
 			}
 		}
 	}
@@ -117,23 +133,35 @@ func (p *PostgresStatRepo) CreateLessonStat(ctx context.Context, stat domain.Les
 }
 
 func (p *PostgresStatRepo) UpdateLessonStat(ctx context.Context, stat domain.LessonStat) error {
-	tx, err := p.db.Beginx()
+	if err := p.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	tx, err := p.db.BeginTxx(ctx, nil)
 	if err != nil {
 		return errors.Wrap(errs.ErrTransactionError, err.Error())
 	}
 
-	var pgLessonStat = entity.NewPgLessonStat(stat)
+	pgLessonStat, err := entity.NewPgLessonStat(stat)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
 	queryString := entity.UpdateQueryString(pgLessonStat, "lesson_stat")
-	_, err = tx.NamedExecContext(ctx, queryString, pgLessonStat)
+	_, err = namedExecContext(ctx, tx, queryString, pgLessonStat)
 	if err != nil {
 		tx.Rollback()
 		return errors.Wrap(errs.ErrUpdateFailed, err.Error())
 	}
 
 	for _, testStat := range stat.TestStats {
-		var pgTestStat = entity.NewPgTestStat(testStat)
+		pgTestStat, err := entity.NewPgTestStat(testStat)
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
 		queryString = entity.UpdateQueryString(pgTestStat, "test_stat")
-		_, err = tx.NamedExecContext(ctx, queryString, pgTestStat)
+		_, err = namedExecContext(ctx, tx, queryString, pgTestStat)
 		if err != nil {
 			tx.Rollback()
 			return errors.Wrap(errs.ErrUpdateFailed, err.Error())
@@ -0,0 +1,107 @@
+package repository
+
+// The benchmarks run against the same containerized Postgres as the tests:
+// the container is started from the test migrations, so Docker must be
+// available. Run them with
+//
+//	go test ./postgres/test -run '^$' -bench . -benchmem
+//
+// Every benchmark gets a fresh container, so timings do not depend on rows
+// left behind by other benchmarks.
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/domain"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"testing"
+)
+
+const benchmarkUsersCount = 1000
+
+func newBenchmarkDB(b *testing.B) *sqlx.DB {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			b.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	db, err := newPostgresDB(url)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		db.Close()
+	})
+	return db
+}
+
+func newBenchmarkUser(i int) domain.User {
+	return domain.User{
+		ID:       domain.ID(uuid.NewString()),
+		Name:     fmt.Sprintf("name%d", i),
+		Surname:  fmt.Sprintf("surname%d", i),
+		Email:    fmt.Sprintf("%s@bench.com", uuid.NewString()),
+		Password: "password",
+	}
+}
+
+func BenchmarkUserFindByID(b *testing.B) {
+	ctx := context.Background()
+	repo := repository.NewUserRepo(newBenchmarkDB(b))
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FindByID(ctx, users[0].ID); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUserFindAll(b *testing.B) {
+	ctx := context.Background()
+	repo := repository.NewUserRepo(newBenchmarkDB(b))
+	for i := len(users); i < benchmarkUsersCount; i++ {
+		if _, err := repo.Create(ctx, newBenchmarkUser(i)); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.FindAll(ctx); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkUserCreate(b *testing.B) {
+	ctx := context.Background()
+	repo := repository.NewUserRepo(newBenchmarkDB(b))
+	newUsers := make([]domain.User, b.N)
+	for i := range newUsers {
+		newUsers[i] = newBenchmarkUser(i)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := repo.Create(ctx, newUsers[i]); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
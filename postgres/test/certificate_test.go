@@ -2,7 +2,10 @@ package repository
 
 import (
 	"context"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
 	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
 	repository "github.com/paw1a/eschool-repository/postgres"
 	"github.com/stretchr/testify/require"
 	"testing"
@@ -148,4 +151,495 @@ func TestCertificateRepository(t *testing.T) {
 		certificate.CreatedAt = createdCertificate.CreatedAt
 		require.Equal(t, certificate, createdCertificate)
 	})
+
+	t.Run("test find certificates expiring before", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		_, err = repo.Create(ctx, createdCertificate)
+		if err != nil {
+			t.Errorf("failed to create certificate: %v", err)
+		}
+
+		found, err := repo.FindCertificatesExpiringBefore(ctx, time.Now().AddDate(0, 0, 30))
+		if err != nil {
+			t.Errorf("failed to find expiring certificates: %v", err)
+		}
+		require.Equal(t, len(found), 2)
+		require.Equal(t, certificates[0].ID, found[0].Certificate.ID)
+		require.Equal(t, certificates[1].ID, found[1].Certificate.ID)
+		require.True(t, found[0].ExpiresAt.Time.Before(found[1].ExpiresAt.Time))
+
+		found, err = repo.FindCertificatesExpiringBefore(ctx, time.Now())
+		if err != nil {
+			t.Errorf("failed to find expired certificates: %v", err)
+		}
+		require.Equal(t, len(found), 1)
+		require.Equal(t, certificates[0].ID, found[0].Certificate.ID)
+	})
+
+	t.Run("test find all certificates by cursor", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		seen := make(map[domain.ID]bool)
+		cursor := ""
+		for page := 0; ; page++ {
+			found, next, err := repo.FindAllByCursor(ctx, cursor, 1)
+			if err != nil {
+				t.Fatalf("failed to find certificates by cursor: %v", err)
+			}
+			for _, certificate := range found {
+				require.False(t, seen[certificate.ID], "duplicate certificate %s", certificate.ID)
+				seen[certificate.ID] = true
+			}
+			if page == 0 {
+				_, err = repo.Create(ctx, createdCertificate)
+				if err != nil {
+					t.Errorf("failed to create certificate: %v", err)
+				}
+			}
+			if next == "" {
+				break
+			}
+			cursor = next
+		}
+
+		for _, certificate := range certificates {
+			require.True(t, seen[certificate.ID], "skipped certificate %s", certificate.ID)
+		}
+		require.True(t, seen[createdCertificate.ID])
+	})
+
+	t.Run("test find certificate by verification hash", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		certificate, err := repo.FindByVerificationHash(ctx, "a3f1c9e2b7d84f60")
+		if err != nil {
+			t.Errorf("failed to find certificate by verification hash: %v", err)
+		}
+		certificate.CreatedAt = certificates[0].CreatedAt
+		require.Equal(t, certificates[0], certificate)
+
+		_, err = repo.FindByVerificationHash(ctx, "unknown")
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test find certificates by grade", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		found, err := repo.FindCertificatesByGrade(ctx, domain.GoldCertificate, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Errorf("failed to find certificates by grade: %v", err)
+		}
+		require.Equal(t, len(found), 1)
+		require.Equal(t, certificates[0].ID, found[0].ID)
+		require.Equal(t, domain.GoldCertificate, found[0].Grade)
+
+		found, err = repo.FindCertificatesByGrade(ctx, domain.SilverCertificate, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Errorf("failed to find certificates by grade: %v", err)
+		}
+		require.Empty(t, found)
+
+		_, err = repo.FindCertificatesByGrade(ctx, domain.CertificateGrade(42), repository.PageParams{Limit: 10})
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test find user certificates page", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.certificate SET created_at = now() - interval '1 day' "+
+			"WHERE id = $1", certificates[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewCertificateRepo(db)
+		page, err := repo.FindUserCertificatesPage(ctx, certificates[0].UserID, repository.PageParams{Limit: 1})
+		if err != nil {
+			t.Errorf("failed to find user certificates page: %v", err)
+		}
+		require.Equal(t, 2, page.Total)
+		require.Equal(t, len(page.Items), 1)
+		require.Equal(t, certificates[1].ID, page.Items[0].ID)
+
+		page, err = repo.FindUserCertificatesPage(ctx, certificates[0].UserID, repository.PageParams{Limit: 1, Offset: 1})
+		if err != nil {
+			t.Errorf("failed to find user certificates page: %v", err)
+		}
+		require.Equal(t, 2, page.Total)
+		require.Equal(t, len(page.Items), 1)
+		require.Equal(t, certificates[0].ID, page.Items[0].ID)
+
+		page, err = repo.FindUserCertificatesPage(ctx, certificates[0].UserID, repository.PageParams{Limit: 1, Offset: 2})
+		if err != nil {
+			t.Errorf("failed to find user certificates page: %v", err)
+		}
+		require.Equal(t, 2, page.Total)
+		require.Empty(t, page.Items)
+	})
+
+	t.Run("test find user certificates with course", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		found, err := repo.FindUserCertificatesWithCourse(ctx, certificates[0].UserID)
+		if err != nil {
+			t.Fatalf("failed to find user certificates with course: %v", err)
+		}
+		require.Len(t, found, 2)
+		titles := make(map[domain.ID][2]string)
+		for _, certificate := range found {
+			titles[certificate.Certificate.ID] = [2]string{certificate.CourseName, certificate.SchoolName}
+		}
+		require.Equal(t, map[domain.ID][2]string{
+			certificates[0].ID: {"course1", "school1"},
+			certificates[1].ID: {"course2", "school1"},
+		}, titles)
+	})
+
+	t.Run("test find user certificates ordered", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.certificate SET created_at = now() - interval '1 day' "+
+			"WHERE id = $1", certificates[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewCertificateRepo(db)
+		for _, tc := range []struct {
+			order repository.CertificateOrder
+			ids   []domain.ID
+		}{
+			{repository.CertificateOrderDefault, []domain.ID{certificates[0].ID, certificates[1].ID}},
+			{repository.CertificateOrderNewest, []domain.ID{certificates[0].ID, certificates[1].ID}},
+			{repository.CertificateOrderOldest, []domain.ID{certificates[1].ID, certificates[0].ID}},
+			{repository.CertificateOrderCourseName, []domain.ID{certificates[0].ID, certificates[1].ID}},
+		} {
+			found, err := repo.FindUserCertificatesOrdered(ctx, certificates[0].UserID, tc.order)
+			if err != nil {
+				t.Fatalf("failed to find user certificates by %q: %v", tc.order, err)
+			}
+			ids := make([]domain.ID, len(found))
+			for i, certificate := range found {
+				ids[i] = certificate.ID
+			}
+			require.Equal(t, tc.ids, ids, tc.order)
+		}
+
+		_, err = repo.FindUserCertificatesOrdered(ctx, certificates[0].UserID, "grade")
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test find user certificates in school", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		_, err = repo.Create(ctx, createdCertificate)
+		if err != nil {
+			t.Fatalf("failed to create certificate: %v", err)
+		}
+
+		found, err := repo.FindUserCertificatesInSchool(ctx, createdCertificate.UserID, schools[1].ID)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Equal(t, createdCertificate.ID, found[0].ID)
+
+		found, err = repo.FindUserCertificatesInSchool(ctx, createdCertificate.UserID, schools[0].ID)
+		require.NoError(t, err)
+		ids := make([]domain.ID, len(found))
+		for i, certificate := range found {
+			ids[i] = certificate.ID
+		}
+		require.ElementsMatch(t, []domain.ID{certificates[0].ID, certificates[1].ID}, ids)
+	})
+
+	t.Run("test count certificates by course", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		_, err = repo.Create(ctx, createdCertificate)
+		if err != nil {
+			t.Fatalf("failed to create certificate: %v", err)
+		}
+		secondCertificate := certificates[0]
+		secondCertificate.ID = domain.ID(uuid.NewString())
+		secondCertificate.UserID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cb")
+		_, err = repo.Create(ctx, secondCertificate)
+		if err != nil {
+			t.Fatalf("failed to create certificate: %v", err)
+		}
+
+		noCertificatesCourseID := domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd")
+		counts, err := repo.CountCertificatesByCourse(ctx, []domain.ID{
+			certificates[0].CourseID,
+			certificates[1].CourseID,
+			createdCertificate.CourseID,
+			noCertificatesCourseID,
+		})
+		require.NoError(t, err)
+		require.Equal(t, map[domain.ID]int{
+			certificates[0].CourseID:    2,
+			certificates[1].CourseID:    1,
+			createdCertificate.CourseID: 1,
+		}, counts)
+	})
+
+	t.Run("test issue certificate with expiry", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		expiresAt := time.Now().AddDate(0, 0, 5)
+		issued, err := repo.IssueCertificate(ctx, createdCertificate, null.TimeFrom(expiresAt))
+		if err != nil {
+			t.Fatalf("failed to issue certificate: %v", err)
+		}
+		require.Equal(t, createdCertificate.ID, issued.Certificate.ID)
+		require.True(t, issued.ExpiresAt.Valid)
+		require.WithinDuration(t, expiresAt, issued.ExpiresAt.Time, time.Millisecond)
+
+		neverExpiring := createdCertificate
+		neverExpiring.ID = domain.ID(uuid.NewString())
+		neverExpiring.CourseID = domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd")
+		issued, err = repo.IssueCertificate(ctx, neverExpiring, null.Time{})
+		if err != nil {
+			t.Fatalf("failed to issue certificate: %v", err)
+		}
+		require.False(t, issued.ExpiresAt.Valid)
+
+		found, err := repo.FindCertificatesExpiringBefore(ctx, time.Now().AddDate(0, 0, 30))
+		if err != nil {
+			t.Errorf("failed to find expiring certificates: %v", err)
+		}
+		require.Len(t, found, 3)
+		require.Equal(t, certificates[0].ID, found[0].Certificate.ID)
+		require.Equal(t, createdCertificate.ID, found[1].Certificate.ID)
+		require.WithinDuration(t, expiresAt, found[1].ExpiresAt.Time, time.Millisecond)
+		require.Equal(t, certificates[1].ID, found[2].Certificate.ID)
+	})
+
+	t.Run("test issued certificate is found by its verification hash", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		issued, err := repo.IssueCertificate(ctx, createdCertificate, null.Time{})
+		if err != nil {
+			t.Fatalf("failed to issue certificate: %v", err)
+		}
+		require.Len(t, issued.VerificationHash, 64)
+
+		certificate, err := repo.FindByVerificationHash(ctx, issued.VerificationHash)
+		if err != nil {
+			t.Errorf("failed to find certificate by verification hash: %v", err)
+		}
+		require.Equal(t, issued.Certificate, certificate)
+
+		other := createdCertificate
+		other.ID = domain.ID(uuid.NewString())
+		other.CourseID = domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd")
+		otherIssued, err := repo.IssueCertificate(ctx, other, null.Time{})
+		if err != nil {
+			t.Fatalf("failed to issue certificate: %v", err)
+		}
+		require.NotEqual(t, issued.VerificationHash, otherIssued.VerificationHash)
+	})
+
+	t.Run("test find user certificates sorted with never expiring first or last", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.certificate SET expires_at = NULL WHERE id = $1", certificates[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewCertificateRepo(db)
+		for _, tc := range []struct {
+			nullsFirst bool
+			ids        []domain.ID
+		}{
+			{false, []domain.ID{certificates[1].ID, certificates[0].ID}},
+			{true, []domain.ID{certificates[0].ID, certificates[1].ID}},
+		} {
+			found, err := repo.FindUserCertificatesSorted(ctx, certificates[0].UserID,
+				repository.CertificateSort{Order: repository.CertificateOrderExpiry, NullsFirst: tc.nullsFirst})
+			if err != nil {
+				t.Fatalf("failed to find user certificates by expiry: %v", err)
+			}
+			ids := make([]domain.ID, len(found))
+			for i, certificate := range found {
+				ids[i] = certificate.ID
+			}
+			require.Equal(t, tc.ids, ids, "nulls first %v", tc.nullsFirst)
+		}
+	})
+
+	t.Run("test find certificates expiring before a zoned cutoff", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCertificateRepo(db)
+		_, err = repo.Create(ctx, createdCertificate)
+		if err != nil {
+			t.Errorf("failed to create certificate: %v", err)
+		}
+		_, err = db.ExecContext(ctx, "update public.certificate set expires_at = $1 where id = $2",
+			time.Now().UTC().Add(3*time.Hour), createdCertificate.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		cutoff := time.Now().In(time.FixedZone("UTC+5", 5*60*60))
+		found, err := repo.FindCertificatesExpiringBefore(ctx, cutoff)
+		if err != nil {
+			t.Errorf("failed to find expired certificates: %v", err)
+		}
+		require.Equal(t, len(found), 1)
+		require.Equal(t, certificates[0].ID, found[0].Certificate.ID)
+	})
 }
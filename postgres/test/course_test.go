@@ -2,10 +2,14 @@ package repository
 
 import (
 	"context"
+	"github.com/google/uuid"
 	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
 	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 var courses = []domain.Course{
@@ -288,4 +292,495 @@ func TestCourseRepository(t *testing.T) {
 			t.Errorf("failed to delete course: %v", err)
 		}
 	})
+
+	t.Run("test find user completed courses", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCourseRepo(db)
+		found, err := repo.FindUserCompletedCourses(ctx, studentCoursesID)
+		if err != nil {
+			t.Errorf("failed to find user completed courses: %v", err)
+		}
+		require.Equal(t, len(found), 2)
+		require.Equal(t, courses[0], found[0])
+		require.Equal(t, courses[1], found[1])
+
+		found, err = repo.FindUserCompletedCourses(ctx, newUserID)
+		if err != nil {
+			t.Errorf("failed to find user completed courses: %v", err)
+		}
+		require.NotNil(t, found)
+		require.Empty(t, found)
+	})
+
+	t.Run("test find course by id fields", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCourseRepo(db)
+		fields, err := repo.FindByIDFields(ctx, courses[0].ID, "name", "price")
+		if err != nil {
+			t.Errorf("failed to find course fields: %v", err)
+		}
+		require.Equal(t, map[string]any{"name": courses[0].Name, "price": courses[0].Price}, fields)
+
+		_, err = repo.FindByIDFields(ctx, courses[0].ID, "name", "password")
+		require.ErrorIs(t, err, entity.ErrUnknownColumn)
+
+		_, err = repo.FindByIDFields(ctx, createdCourse.ID, "name")
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test set course status", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCourseRepo(db)
+		transitions := []domain.CourseStatus{
+			domain.CourseReady,
+			domain.CourseDraft,
+			domain.CourseReady,
+			domain.CoursePublished,
+		}
+		for _, status := range transitions {
+			err = repo.SetCourseStatus(ctx, courses[0].ID, status)
+			if err != nil {
+				t.Errorf("failed to set course status: %v", err)
+			}
+			course, err := repo.FindByID(ctx, courses[0].ID)
+			if err != nil {
+				t.Errorf("failed to find course with id: %v", err)
+			}
+			require.Equal(t, status, course.Status)
+		}
+
+		err = repo.SetCourseStatus(ctx, courses[0].ID, domain.CourseDraft)
+		require.ErrorIs(t, err, repository.ErrIllegalTransition)
+		course, err := repo.FindByID(ctx, courses[0].ID)
+		if err != nil {
+			t.Errorf("failed to find course with id: %v", err)
+		}
+		require.Equal(t, domain.CoursePublished, course.Status)
+	})
+
+	t.Run("test find courses without reviews", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCourseRepo(db)
+		found, err := repo.FindCoursesWithoutReviews(ctx, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Errorf("failed to find courses without reviews: %v", err)
+		}
+		require.Equal(t, len(found), 2)
+		require.Equal(t, "course3", found[0].Name)
+		require.Equal(t, "course4", found[1].Name)
+
+		reviewRepo := repository.NewReviewRepo(db)
+		_, err = reviewRepo.Create(ctx, domain.Review{
+			ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021cd"),
+			UserID:   studentCoursesID,
+			CourseID: found[0].ID,
+			Text:     "review4 text",
+		})
+		if err != nil {
+			t.Errorf("failed to create review: %v", err)
+		}
+
+		found, err = repo.FindCoursesWithoutReviews(ctx, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Errorf("failed to find courses without reviews: %v", err)
+		}
+		require.Equal(t, len(found), 1)
+		require.Equal(t, "course4", found[0].Name)
+	})
+
+	t.Run("test find course with unknown status", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "ALTER TYPE course_status ADD VALUE 'archived'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, "UPDATE public.course SET status = 'archived' WHERE name = 'course1'")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewCourseRepo(db)
+		_, err = repo.FindByID(ctx, courses[0].ID)
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+		_, err = repo.FindAll(ctx)
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test find course for update lock strength", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCourseRepo(db)
+		insertStudent := func(courseID domain.ID) error {
+			insertCtx, cancel := context.WithTimeout(ctx, time.Second)
+			defer cancel()
+			_, err := db.ExecContext(insertCtx, "INSERT INTO public.course_student (student_id, course_id) "+
+				"VALUES ($1, $2)", newUserID, courseID)
+			return err
+		}
+
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		course, err := repo.FindByIDForUpdate(ctx, tx, courses[0].ID, repository.LockForNoKeyUpdate)
+		if err != nil {
+			t.Errorf("failed to find course for update: %v", err)
+		}
+		require.Equal(t, courses[0], course)
+		require.NoError(t, insertStudent(courses[0].ID))
+		tx.Rollback()
+
+		tx, err = db.BeginTxx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = repo.FindByIDForUpdate(ctx, tx, courses[1].ID, repository.LockForUpdate)
+		if err != nil {
+			t.Errorf("failed to find course for update: %v", err)
+		}
+		require.Error(t, insertStudent(courses[1].ID))
+		tx.Rollback()
+
+		tx, err = db.BeginTxx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		_, err = repo.FindByIDForUpdate(ctx, tx, courses[0].ID, repository.RowLock("EXCLUSIVE"))
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test find course detail", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCourseRepo(db)
+		detail, err := repo.FindCourseDetail(ctx, courses[0].ID)
+		if err != nil {
+			t.Fatalf("failed to find course detail: %v", err)
+		}
+		require.Equal(t, courses[0], detail.Course)
+		require.Equal(t, schools[0], detail.School)
+		require.Equal(t, 4.5, detail.AverageRating)
+		require.Equal(t, 2, detail.ReviewCount)
+
+		_, err = repo.FindCourseDetail(ctx, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7027ff"))
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test find courses by language", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCourseRepo(db)
+		page := repository.PageParams{Limit: 10}
+		for _, lang := range []string{"english", "English"} {
+			found, err := repo.FindCoursesByLanguage(ctx, lang, page)
+			if err != nil {
+				t.Fatalf("failed to find courses by language %s: %v", lang, err)
+			}
+			ids := make([]domain.ID, len(found))
+			for i, course := range found {
+				ids[i] = course.ID
+			}
+			require.Equal(t, []domain.ID{
+				domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd"),
+				courses[1].ID,
+			}, ids, lang)
+		}
+
+		found, err := repo.FindCoursesByLanguage(ctx, "german", page)
+		if err != nil {
+			t.Fatalf("failed to find courses by language german: %v", err)
+		}
+		require.Empty(t, found)
+	})
+
+	t.Run("test find courses not updated since", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		for id, age := range map[domain.ID]string{
+			courses[0].ID: "2 years",
+			domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cc"): "400 days",
+			courses[1].ID: "1 month",
+		} {
+			_, err = db.ExecContext(ctx, "UPDATE public.course SET updated_at = now() - $1::interval WHERE id = $2", age, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		repo := repository.NewCourseRepo(db)
+		found, err := repo.FindCoursesNotUpdatedSince(ctx, time.Now().AddDate(-1, 0, 0), repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Fatalf("failed to find stale courses: %v", err)
+		}
+		ids := make([]domain.ID, len(found))
+		for i, course := range found {
+			ids[i] = course.ID
+		}
+		require.Equal(t, []domain.ID{courses[0].ID, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cc")}, ids)
+	})
+
+	t.Run("test find user enrollments", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.course_student SET enrolled_at = enrolled_at + interval '1 day' "+
+			"WHERE student_id = $1 AND course_id = $2", users[0].ID, courses[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, "INSERT INTO public.lesson_stat (id, score, user_id, lesson_id) "+
+			"VALUES ($1, 10, $2, $3)", uuid.NewString(), users[0].ID, lessons[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewCourseRepo(db)
+		enrollments, err := repo.FindUserEnrollments(ctx, users[0].ID, repository.PageParams{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, enrollments, 2)
+		require.Equal(t, courses[1], enrollments[0].Course)
+		require.Equal(t, 0, enrollments[0].LessonsTotal)
+		require.Equal(t, courses[0], enrollments[1].Course)
+		require.Equal(t, 1, enrollments[1].LessonsCompleted)
+		require.Equal(t, 3, enrollments[1].LessonsTotal)
+		require.True(t, enrollments[0].EnrolledAt.After(enrollments[1].EnrolledAt))
+
+		enrollments, err = repo.FindUserEnrollments(ctx, users[0].ID, repository.PageParams{Limit: 1, Offset: 1})
+		require.NoError(t, err)
+		require.Len(t, enrollments, 1)
+		require.Equal(t, courses[0], enrollments[0].Course)
+	})
+
+	t.Run("test find teacher courses in school", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		otherSchoolID := domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cd")
+		otherSchoolCourseID := domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cc")
+		_, err = db.ExecContext(ctx, "INSERT INTO public.course_teacher (teacher_id, course_id) VALUES ($1, $2)",
+			teacherCoursesID, otherSchoolCourseID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewCourseRepo(db)
+		found, err := repo.FindTeacherCoursesInSchool(ctx, teacherCoursesID, courses[0].SchoolID)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Course{courses[0], courses[1]}, found)
+
+		found, err = repo.FindTeacherCoursesInSchool(ctx, teacherCoursesID, otherSchoolID)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Equal(t, otherSchoolCourseID, found[0].ID)
+	})
+
+	t.Run("test find courses by tags", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewCourseRepo(db)
+		err = repo.SetCourseTags(ctx, courses[0].ID, []string{"python", "beginner"})
+		require.NoError(t, err)
+		err = repo.SetCourseTags(ctx, courses[1].ID, []string{"python"})
+		require.NoError(t, err)
+
+		found, err := repo.FindCoursesByTags(ctx, []string{"python", "beginner"}, true)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Course{courses[0]}, found)
+
+		found, err = repo.FindCoursesByTags(ctx, []string{"beginner", "go"}, false)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Course{courses[0]}, found)
+
+		found, err = repo.FindCoursesByTags(ctx, []string{"python"}, false)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Course{courses[0], courses[1]}, found)
+
+		found, err = repo.FindCoursesByTags(ctx, []string{"rust"}, true)
+		require.NoError(t, err)
+		require.Empty(t, found)
+
+		found, err = repo.FindCoursesByTags(ctx, []string{"rust"}, false)
+		require.NoError(t, err)
+		require.Empty(t, found)
+
+		updatedCourse := courses[0]
+		updatedCourse.Name = "updated"
+		_, err = repo.Update(ctx, updatedCourse)
+		require.NoError(t, err)
+		found, err = repo.FindCoursesByTags(ctx, []string{"beginner"}, true)
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Equal(t, courses[0].ID, found[0].ID)
+
+		err = repo.SetCourseTags(ctx, domain.ID(uuid.NewString()), []string{"python"})
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test find user completed courses with a course certified twice", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "INSERT INTO public.certificate "+
+			"(id, name, score, grade, created_at, user_id, course_id) "+
+			"VALUES ('30e18bc1-4352-4937-9a3b-03cf0b7027d2', 'course 1 cert', 100, 'gold', now(), $1, $2)",
+			studentCoursesID, courses[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewCourseRepo(db)
+		found, err := repo.FindUserCompletedCourses(ctx, studentCoursesID)
+		if err != nil {
+			t.Errorf("failed to find user completed courses: %v", err)
+		}
+		require.Equal(t, len(found), 2)
+		require.Equal(t, courses[0], found[0])
+		require.Equal(t, courses[1], found[1])
+	})
 }
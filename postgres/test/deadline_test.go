@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"context"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestDeadlineBudget(t *testing.T) {
+	t.Run("test sequential calls get proportional deadlines", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+		defer cancel()
+		budget := repository.NewDeadlineBudget(4)
+
+		var shares []time.Duration
+		for i := 0; i < 4; i++ {
+			remaining := time.Until(deadlineOf(t, ctx))
+			queryCtx, queryCancel := budget.Next(ctx)
+			share := time.Until(deadlineOf(t, queryCtx))
+			queryCancel()
+
+			require.InDelta(t, float64(remaining)/float64(4-i), float64(share), float64(10*time.Millisecond))
+			shares = append(shares, share)
+			time.Sleep(20 * time.Millisecond)
+		}
+		for i := 1; i < len(shares); i++ {
+			require.Greater(t, shares[i], shares[i-1])
+		}
+	})
+
+	t.Run("test slow call is cut at its share", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+		defer cancel()
+		budget := repository.NewDeadlineBudget(2)
+
+		queryCtx, queryCancel := budget.Next(ctx)
+		defer queryCancel()
+		<-queryCtx.Done()
+		require.ErrorIs(t, queryCtx.Err(), context.DeadlineExceeded)
+		require.NoError(t, ctx.Err())
+
+		queryCtx, queryCancel = budget.Next(ctx)
+		defer queryCancel()
+		require.Equal(t, deadlineOf(t, ctx), deadlineOf(t, queryCtx))
+	})
+
+	t.Run("test no deadline is not limited", func(t *testing.T) {
+		budget := repository.NewDeadlineBudget(3)
+		queryCtx, queryCancel := budget.Next(context.Background())
+		defer queryCancel()
+		_, ok := queryCtx.Deadline()
+		require.False(t, ok)
+	})
+}
+
+func deadlineOf(t *testing.T, ctx context.Context) time.Time {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		t.Fatal("context has no deadline")
+	}
+	return deadline
+}
@@ -0,0 +1,207 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+type pgMismatchedEntity struct {
+	ID      string `db:"id"`
+	Name    string `db:"name"`
+	Surname string
+}
+
+type pgMatchedEntity struct {
+	ID   string `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestValidateNamedQuery(t *testing.T) {
+	t.Run("test generated query is valid", func(t *testing.T) {
+		pgUser, err := entity.NewPgUser(users[0])
+		require.NoError(t, err)
+		err = entity.ValidateNamedQuery(pgUser, entity.InsertQueryString(pgUser, "user"))
+		require.NoError(t, err)
+		err = entity.ValidateNamedQuery(pgUser, entity.UpdateQueryString(pgUser, "user"))
+		require.NoError(t, err)
+	})
+
+	t.Run("test readonly column is not written", func(t *testing.T) {
+		pgUser, err := entity.NewPgUser(users[0])
+		require.NoError(t, err)
+		require.NotContains(t, entity.InsertQueryString(pgUser, "user"), "created_at")
+		require.NotContains(t, entity.UpdateQueryString(pgUser, "user"), "created_at")
+	})
+
+	t.Run("test field without db tag", func(t *testing.T) {
+		var pgEntity pgMismatchedEntity
+		err := entity.ValidateNamedQuery(pgEntity, entity.InsertQueryString(pgEntity, "user"))
+		require.ErrorIs(t, err, entity.ErrInvalidNamedQuery)
+		require.ErrorContains(t, err, "Surname")
+	})
+
+	t.Run("test unbound named parameter", func(t *testing.T) {
+		var pgEntity pgMatchedEntity
+		query := "UPDATE public.user SET name = :name, surname = :surname WHERE id = :id"
+		err := entity.ValidateNamedQuery(pgEntity, query)
+		require.ErrorIs(t, err, entity.ErrInvalidNamedQuery)
+		require.ErrorContains(t, err, ":surname")
+	})
+}
+
+func TestPatchQueryString(t *testing.T) {
+	t.Run("test columns are sorted and bound in order", func(t *testing.T) {
+		query, args, err := entity.PatchQueryString(entity.PgUser{}, "user",
+			map[string]any{"surname": "Smith", "city": "Moscow"})
+		require.NoError(t, err)
+		require.Equal(t, "UPDATE public.user SET city = ?, surname = ? WHERE id = ? RETURNING *", query)
+		require.Equal(t, []any{"Moscow", "Smith"}, args)
+	})
+
+	t.Run("test id and readonly columns are rejected", func(t *testing.T) {
+		for _, column := range []string{"id", "created_at", "role"} {
+			_, _, err := entity.PatchQueryString(entity.PgUser{}, "user", map[string]any{column: "x"})
+			require.ErrorIs(t, err, entity.ErrUnknownColumn)
+			require.ErrorContains(t, err, column)
+		}
+	})
+
+	t.Run("test empty patch", func(t *testing.T) {
+		_, _, err := entity.PatchQueryString(entity.PgUser{}, "user", nil)
+		require.ErrorIs(t, err, entity.ErrValidation)
+	})
+}
+
+func TestValidateRequiredFields(t *testing.T) {
+	t.Run("test user without email", func(t *testing.T) {
+		user := createdUser
+		user.Email = ""
+		pgUser, err := entity.NewPgUser(user)
+		require.NoError(t, err)
+		err = pgUser.Validate()
+		require.ErrorIs(t, err, entity.ErrValidation)
+		require.ErrorContains(t, err, "user.email")
+
+		pgUser, err = entity.NewPgUser(createdUser)
+		require.NoError(t, err)
+		require.NoError(t, pgUser.Validate())
+	})
+
+	t.Run("test school without name", func(t *testing.T) {
+		school := createdSchool
+		school.Name = " "
+		pgSchool, err := entity.NewPgSchool(school)
+		require.NoError(t, err)
+		err = pgSchool.Validate()
+		require.ErrorIs(t, err, entity.ErrValidation)
+		require.ErrorContains(t, err, "school.name")
+	})
+
+	t.Run("test course without language", func(t *testing.T) {
+		course := createdCourse
+		course.Language = ""
+		pgCourse, err := entity.NewPgCourse(course)
+		require.NoError(t, err)
+		err = pgCourse.Validate()
+		require.ErrorIs(t, err, entity.ErrValidation)
+		require.ErrorContains(t, err, "course.language")
+	})
+
+	t.Run("test lesson without title", func(t *testing.T) {
+		lesson := createdLesson
+		lesson.Title = ""
+		pgLesson, err := entity.NewPgLesson(lesson)
+		require.NoError(t, err)
+		err = pgLesson.Validate()
+		require.ErrorIs(t, err, entity.ErrValidation)
+		require.ErrorContains(t, err, "lesson.title")
+	})
+
+	t.Run("test review without text", func(t *testing.T) {
+		review := createdReview
+		review.Text = ""
+		pgReview, err := entity.NewPgReview(review)
+		require.NoError(t, err)
+		err = pgReview.Validate()
+		require.ErrorIs(t, err, entity.ErrValidation)
+		require.ErrorContains(t, err, "review.text")
+	})
+
+	t.Run("test review rating out of range", func(t *testing.T) {
+		pgReview, err := entity.NewPgRatedReview(createdReview, 6)
+		require.NoError(t, err)
+		err = pgReview.Validate()
+		require.ErrorIs(t, err, entity.ErrValidation)
+		require.ErrorContains(t, err, "review.rating")
+	})
+
+	t.Run("test certificate without name", func(t *testing.T) {
+		certificate := createdCertificate
+		certificate.Name = ""
+		pgCertificate, err := entity.NewPgCertificate(certificate)
+		require.NoError(t, err)
+		err = pgCertificate.Validate()
+		require.ErrorIs(t, err, entity.ErrValidation)
+		require.ErrorContains(t, err, "certificate.name")
+	})
+}
+
+func TestValidateEntityID(t *testing.T) {
+	t.Run("test malformed id is rejected", func(t *testing.T) {
+		user := createdUser
+		user.ID = "not-a-uuid"
+		_, err := entity.NewPgUser(user)
+		require.ErrorIs(t, err, entity.ErrValidation)
+		require.ErrorContains(t, err, "not-a-uuid")
+
+		review := createdReview
+		review.ID = "30e18bc1-4354"
+		_, err = entity.NewPgRatedReview(review, 5)
+		require.ErrorIs(t, err, entity.ErrValidation)
+	})
+
+	t.Run("test empty id is generated", func(t *testing.T) {
+		user := createdUser
+		user.ID = ""
+		pgUser, err := entity.NewPgUser(user)
+		require.NoError(t, err)
+		require.NotEqual(t, uuid.Nil, pgUser.ID)
+	})
+}
+
+func TestToDomainChecked(t *testing.T) {
+	t.Run("test valid enums are converted", func(t *testing.T) {
+		pgCourse, err := entity.NewPgCourse(courses[0])
+		require.NoError(t, err)
+		course, err := pgCourse.ToDomainChecked()
+		require.NoError(t, err)
+		require.Equal(t, courses[0], course)
+	})
+
+	t.Run("test invalid course status", func(t *testing.T) {
+		pgCourse, err := entity.NewPgCourse(courses[0])
+		require.NoError(t, err)
+		pgCourse.Status = "archived"
+		_, err = pgCourse.ToDomainChecked()
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test invalid lesson type", func(t *testing.T) {
+		pgLesson, err := entity.NewPgLesson(createdLesson)
+		require.NoError(t, err)
+		pgLesson.Type = "audio"
+		_, err = pgLesson.ToDomainChecked()
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test invalid certificate grade", func(t *testing.T) {
+		pgCertificate, err := entity.NewPgCertificate(createdCertificate)
+		require.NoError(t, err)
+		pgCertificate.Grade = "platinum"
+		_, err = pgCertificate.ToDomainChecked()
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestExplainDisabled(t *testing.T) {
+	explainer := repository.NewExplainer(nil)
+	_, err := explainer.ExplainQuery(context.Background(), "user.FindByID", users[0].ID)
+	require.True(t, errors.Is(err, repository.ErrExplainDisabled))
+}
+
+func TestExplainer(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test explain find by id", func(t *testing.T) {
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		explainer := repository.NewExplainer(db, repository.WithExplain())
+		plan, err := explainer.ExplainQuery(ctx, "user.FindByID", users[0].ID)
+		require.NoError(t, err)
+		require.Contains(t, plan, "user")
+		require.Contains(t, plan, "Execution Time")
+	})
+
+	t.Run("test explain unknown method", func(t *testing.T) {
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		explainer := repository.NewExplainer(db, repository.WithExplain())
+		_, err = explainer.ExplainQuery(ctx, "user.Create", users[0].ID)
+		require.True(t, errors.Is(err, repository.ErrUnknownMethod))
+	})
+}
@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/stretchr/testify/require"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakePinger struct {
+	down atomic.Bool
+}
+
+func (p *fakePinger) PingContext(ctx context.Context) error {
+	if p.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestHealthWatcher(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("test state transitions after threshold and recovery", func(t *testing.T) {
+		pinger := &fakePinger{}
+		watcher := repository.NewHealthWatcher(pinger, time.Second, 3)
+		states := watcher.Subscribe()
+		require.True(t, watcher.IsHealthy())
+
+		pinger.down.Store(true)
+		require.True(t, watcher.Check(ctx))
+		require.True(t, watcher.Check(ctx))
+		require.Empty(t, states)
+		require.False(t, watcher.Check(ctx))
+		require.False(t, watcher.IsHealthy())
+		require.False(t, <-states)
+
+		require.False(t, watcher.Check(ctx))
+		require.Empty(t, states)
+
+		pinger.down.Store(false)
+		require.True(t, watcher.Check(ctx))
+		require.True(t, watcher.IsHealthy())
+		require.True(t, <-states)
+	})
+
+	t.Run("test background pings detect outage", func(t *testing.T) {
+		pinger := &fakePinger{}
+		pinger.down.Store(true)
+		watcher := repository.NewHealthWatcher(pinger, 5*time.Millisecond, 2)
+		states := watcher.Subscribe()
+		watcher.Start(ctx)
+		defer watcher.Close()
+
+		select {
+		case healthy := <-states:
+			require.False(t, healthy)
+		case <-time.After(time.Second):
+			t.Fatal("watcher did not report the outage")
+		}
+	})
+}
@@ -0,0 +1,276 @@
+package repository
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/paw1a/eschool-repository/memory"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+// The contract below is run against both the postgres repositories and the
+// memory fakes, so that the fakes stay interchangeable in service tests.
+
+type contractUserRepo interface {
+	FindByID(ctx context.Context, userID domain.ID) (domain.User, error)
+	FindByEmail(ctx context.Context, email string) (domain.User, error)
+	Create(ctx context.Context, user domain.User) (domain.User, error)
+	Update(ctx context.Context, user domain.User) (domain.User, error)
+	Delete(ctx context.Context, userID domain.ID) error
+}
+
+type contractSchoolRepo interface {
+	FindByID(ctx context.Context, schoolID domain.ID) (domain.School, error)
+	FindSchoolCourses(ctx context.Context, schoolID domain.ID) ([]domain.Course, error)
+	IsSchoolTeacher(ctx context.Context, schoolID, teacherID domain.ID) (bool, error)
+	AddSchoolTeacher(ctx context.Context, schoolID, teacherID domain.ID) error
+	Create(ctx context.Context, school domain.School) (domain.School, error)
+}
+
+type contractCourseRepo interface {
+	FindByID(ctx context.Context, courseID domain.ID) (domain.Course, error)
+	AddCourseStudent(ctx context.Context, studentID, courseID domain.ID) error
+	Create(ctx context.Context, course domain.Course) (domain.Course, error)
+	UpdateStatus(ctx context.Context, courseID domain.ID, status domain.CourseStatus) error
+}
+
+type contractReviewRepo interface {
+	FindByID(ctx context.Context, reviewID domain.ID) (domain.Review, error)
+	FindCourseReviews(ctx context.Context, courseID domain.ID) ([]domain.Review, error)
+	Create(ctx context.Context, review domain.Review) (domain.Review, error)
+	Delete(ctx context.Context, reviewID domain.ID) error
+}
+
+type contractRepos struct {
+	users   contractUserRepo
+	schools contractSchoolRepo
+	courses contractCourseRepo
+	reviews contractReviewRepo
+}
+
+func newContractID() domain.ID {
+	return domain.ID(uuid.NewString())
+}
+
+func testRepositoryContract(t *testing.T, repos contractRepos) {
+	ctx := context.Background()
+
+	user := domain.User{
+		ID:       newContractID(),
+		Name:     "contractName",
+		Surname:  "contractSurname",
+		Email:    "contract@mail.com",
+		Password: "password",
+	}
+
+	t.Run("user crud", func(t *testing.T) {
+		_, err := repos.users.FindByID(ctx, user.ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+
+		created, err := repos.users.Create(ctx, user)
+		require.NoError(t, err)
+		require.Equal(t, user, created)
+
+		found, err := repos.users.FindByEmail(ctx, user.Email)
+		require.NoError(t, err)
+		require.Equal(t, user, found)
+
+		_, err = repos.users.Create(ctx, user)
+		require.ErrorIs(t, err, errs.ErrDuplicate)
+		require.ErrorIs(t, err, repository.ErrIDConflict)
+
+		sameEmail := user
+		sameEmail.ID = newContractID()
+		_, err = repos.users.Create(ctx, sameEmail)
+		require.ErrorIs(t, err, errs.ErrDuplicate)
+		require.NotErrorIs(t, err, repository.ErrIDConflict)
+
+		noEmail := user
+		noEmail.ID = newContractID()
+		noEmail.Email = ""
+		_, err = repos.users.Create(ctx, noEmail)
+		require.ErrorIs(t, err, entity.ErrValidation)
+
+		user.Name = "contractUpdatedName"
+		updated, err := repos.users.Update(ctx, user)
+		require.NoError(t, err)
+		require.Equal(t, user, updated)
+
+		missing := user
+		missing.ID = newContractID()
+		missing.Email = "missing@mail.com"
+		_, err = repos.users.Update(ctx, missing)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+
+		require.NoError(t, repos.users.Delete(ctx, missing.ID))
+	})
+
+	school := domain.School{
+		ID:          newContractID(),
+		OwnerID:     user.ID,
+		Name:        "contractSchool",
+		Description: "contract description",
+	}
+	course := domain.Course{
+		ID:       newContractID(),
+		SchoolID: school.ID,
+		Name:     "contractCourse",
+		Level:    1,
+		Price:    100,
+		Language: "english",
+		Status:   domain.CourseDraft,
+	}
+
+	t.Run("school and course crud", func(t *testing.T) {
+		created, err := repos.schools.Create(ctx, school)
+		require.NoError(t, err)
+		require.Equal(t, school, created)
+
+		sameName := school
+		sameName.ID = newContractID()
+		_, err = repos.schools.Create(ctx, sameName)
+		require.NoError(t, err)
+		_, err = repos.schools.Create(ctx, sameName)
+		require.ErrorIs(t, err, repository.ErrIDConflict)
+
+		require.NoError(t, repos.schools.AddSchoolTeacher(ctx, school.ID, user.ID))
+		err = repos.schools.AddSchoolTeacher(ctx, school.ID, user.ID)
+		require.ErrorIs(t, err, errs.ErrDuplicate)
+		isTeacher, err := repos.schools.IsSchoolTeacher(ctx, school.ID, user.ID)
+		require.NoError(t, err)
+		require.True(t, isTeacher)
+
+		_, err = repos.courses.Create(ctx, course)
+		require.NoError(t, err)
+		_, err = repos.courses.Create(ctx, course)
+		require.ErrorIs(t, err, repository.ErrIDConflict)
+
+		courses, err := repos.schools.FindSchoolCourses(ctx, school.ID)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Course{course}, courses)
+
+		require.NoError(t, repos.courses.AddCourseStudent(ctx, user.ID, course.ID))
+		err = repos.courses.AddCourseStudent(ctx, user.ID, course.ID)
+		require.ErrorIs(t, err, errs.ErrDuplicate)
+
+		course.Status = domain.CourseReady
+		require.NoError(t, repos.courses.UpdateStatus(ctx, course.ID, course.Status))
+		found, err := repos.courses.FindByID(ctx, course.ID)
+		require.NoError(t, err)
+		require.Equal(t, course, found)
+
+		err = repos.courses.UpdateStatus(ctx, newContractID(), domain.CourseReady)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("review soft delete", func(t *testing.T) {
+		review := domain.Review{
+			ID:       newContractID(),
+			UserID:   user.ID,
+			CourseID: course.ID,
+			Text:     "contract review",
+		}
+		_, err := repos.reviews.Create(ctx, review)
+		require.NoError(t, err)
+
+		reviews, err := repos.reviews.FindCourseReviews(ctx, course.ID)
+		require.NoError(t, err)
+		require.Equal(t, []domain.Review{review}, reviews)
+
+		require.NoError(t, repos.reviews.Delete(ctx, review.ID))
+		reviews, err = repos.reviews.FindCourseReviews(ctx, course.ID)
+		require.NoError(t, err)
+		require.Empty(t, reviews)
+
+		found, err := repos.reviews.FindByID(ctx, review.ID)
+		require.NoError(t, err)
+		require.Equal(t, review, found)
+	})
+
+	t.Run("review with generated id", func(t *testing.T) {
+		review := domain.Review{
+			UserID:   user.ID,
+			CourseID: course.ID,
+			Text:     "contract review without id",
+		}
+		created, err := repos.reviews.Create(ctx, review)
+		require.NoError(t, err)
+		require.NotEmpty(t, created.ID)
+		review.ID = created.ID
+		require.Equal(t, review, created)
+
+		found, err := repos.reviews.FindByID(ctx, created.ID)
+		require.NoError(t, err)
+		require.Equal(t, review, found)
+
+		another := domain.Review{
+			UserID:   user.ID,
+			CourseID: course.ID,
+			Text:     "another contract review without id",
+		}
+		_, err = repos.reviews.Create(ctx, another)
+		require.ErrorIs(t, err, errs.ErrDuplicate)
+		require.NotErrorIs(t, err, repository.ErrIDConflict)
+
+		require.NoError(t, repos.reviews.Delete(ctx, created.ID))
+		_, err = repos.reviews.Create(ctx, review)
+		require.ErrorIs(t, err, repository.ErrIDConflict)
+
+		rewritten, err := repos.reviews.Create(ctx, another)
+		require.NoError(t, err)
+		require.NotEqual(t, created.ID, rewritten.ID)
+	})
+
+	t.Run("user delete", func(t *testing.T) {
+		require.NoError(t, repos.users.Delete(ctx, user.ID))
+		_, err := repos.users.FindByID(ctx, user.ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+}
+
+func TestMemoryRepositoryContract(t *testing.T) {
+	store := memory.NewStore()
+	testRepositoryContract(t, contractRepos{
+		users:   memory.NewUserRepo(store),
+		schools: memory.NewSchoolRepo(store),
+		courses: memory.NewCourseRepo(store),
+		reviews: memory.NewReviewRepo(store),
+	})
+}
+
+func TestPostgresRepositoryContract(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newPostgresDB(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	testRepositoryContract(t, contractRepos{
+		users:   repository.NewUserRepo(db),
+		schools: repository.NewSchoolRepo(db),
+		courses: repository.NewCourseRepo(db),
+		reviews: repository.NewReviewRepo(db),
+	})
+}
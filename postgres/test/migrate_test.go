@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"context"
+	"github.com/paw1a/eschool-core/errs"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	container, err := runPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := newPostgresDB(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	t.Run("test migrate fresh database", func(t *testing.T) {
+		err = repository.Migrate(ctx, db)
+		if err != nil {
+			t.Fatalf("failed to migrate database: %v", err)
+		}
+		err = repository.Migrate(ctx, db)
+		if err != nil {
+			t.Fatalf("failed to migrate already migrated database: %v", err)
+		}
+
+		repo := repository.NewUserRepo(db)
+		user, err := repo.Create(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to create user: %v", err)
+		}
+		require.Equal(t, createdUser, user)
+
+		user.Name = updatedUser.Name
+		user, err = repo.Update(ctx, user)
+		if err != nil {
+			t.Errorf("failed to update user: %v", err)
+		}
+		require.Equal(t, updatedUser.Name, user.Name)
+
+		err = repo.Delete(ctx, user.ID)
+		if err != nil {
+			t.Errorf("failed to delete user: %v", err)
+		}
+		_, err = repo.FindByID(ctx, user.ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test rollback migrations", func(t *testing.T) {
+		for {
+			var version int
+			err = db.GetContext(ctx, &version, "SELECT COALESCE(MAX(version), 0) FROM public.schema_migrations")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if version == 0 {
+				break
+			}
+			err = repository.Rollback(ctx, db)
+			if err != nil {
+				t.Fatalf("failed to rollback migration %d: %v", version, err)
+			}
+		}
+
+		var exists bool
+		err = db.GetContext(ctx, &exists, "SELECT to_regclass('public.user') IS NOT NULL")
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.False(t, exists)
+	})
+}
@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"github.com/jmoiron/sqlx"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestOutboxRepository(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test relay events enqueued by committed transactions", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewOutboxRepo(db)
+		issued := repository.OutboxEvent{
+			Type:    "CertificateIssued",
+			Payload: []byte(`{"certificate_id": "30e18bc1-4352-4937-9a3b-03cf0b7027ca"}`),
+		}
+		err = repository.WithinTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "UPDATE public.certificate SET score = 130 WHERE id = $1",
+				certificates[0].ID)
+			if err != nil {
+				return err
+			}
+			return repo.EnqueueEvent(ctx, tx, issued)
+		})
+		if err != nil {
+			t.Fatalf("failed to enqueue event: %v", err)
+		}
+
+		rolledBack := errors.New("rolled back")
+		err = repository.WithinTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+			if err := repo.EnqueueEvent(ctx, tx, repository.OutboxEvent{
+				Type:    "CertificateRevoked",
+				Payload: []byte(`{}`),
+			}); err != nil {
+				return err
+			}
+			return rolledBack
+		})
+		require.ErrorIs(t, err, rolledBack)
+
+		var relayed []repository.OutboxEvent
+		count, err := repo.RelayOutbox(ctx, func(event repository.OutboxEvent) error {
+			relayed = append(relayed, event)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to relay outbox: %v", err)
+		}
+		require.Equal(t, 1, count)
+		require.Len(t, relayed, 1)
+		require.Equal(t, issued.Type, relayed[0].Type)
+		require.JSONEq(t, string(issued.Payload), string(relayed[0].Payload))
+
+		count, err = repo.RelayOutbox(ctx, func(event repository.OutboxEvent) error {
+			t.Errorf("event %d published twice", event.ID)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to relay outbox: %v", err)
+		}
+		require.Equal(t, 0, count)
+	})
+
+	t.Run("test failed publish is retried", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewOutboxRepo(db)
+		err = repository.WithinTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+			for _, eventType := range []string{"first", "second"} {
+				err := repo.EnqueueEvent(ctx, tx, repository.OutboxEvent{Type: eventType, Payload: []byte(`{}`)})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to enqueue events: %v", err)
+		}
+
+		brokerDown := errors.New("broker down")
+		count, err := repo.RelayOutbox(ctx, func(event repository.OutboxEvent) error {
+			if event.Type == "second" {
+				return brokerDown
+			}
+			return nil
+		})
+		require.ErrorIs(t, err, brokerDown)
+		require.Equal(t, 1, count)
+
+		var relayed []string
+		count, err = repo.RelayOutbox(ctx, func(event repository.OutboxEvent) error {
+			relayed = append(relayed, event.Type)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("failed to relay outbox: %v", err)
+		}
+		require.Equal(t, 1, count)
+		require.Equal(t, []string{"second"}, relayed)
+	})
+}
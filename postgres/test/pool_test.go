@@ -0,0 +1,137 @@
+package repository
+
+import (
+	"context"
+	"github.com/jackc/pgconn"
+	"github.com/paw1a/eschool-core/errs"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectionPool(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test exhausted pool returns service unavailable", func(t *testing.T) {
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		db.SetMaxOpenConns(1)
+
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		timeoutCtx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+		defer cancel()
+
+		repo := repository.NewUserRepo(db)
+		_, err = repo.FindByID(timeoutCtx, users[0].ID)
+		require.ErrorIs(t, err, repository.ErrServiceUnavailable)
+	})
+
+	t.Run("test application name is set on session", func(t *testing.T) {
+		db, err := repository.NewPostgresDB(ctx, url, repository.WithApplicationName("eschool-test"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		var applicationName string
+		err = db.GetContext(ctx, &applicationName, "SELECT current_setting('application_name')")
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "eschool-test", applicationName)
+	})
+
+	t.Run("test statement timeout aborts long query", func(t *testing.T) {
+		db, err := repository.NewPostgresDB(ctx, url,
+			repository.WithStatementTimeout(100*time.Millisecond),
+			repository.WithLockTimeout(200*time.Millisecond))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		var lockTimeout string
+		err = db.GetContext(ctx, &lockTimeout, "SELECT current_setting('lock_timeout')")
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, "200ms", lockTimeout)
+
+		_, err = db.ExecContext(ctx, "SELECT pg_sleep(5)")
+		var pgErr *pgconn.PgError
+		require.True(t, errors.As(err, &pgErr))
+		require.Equal(t, "57014", pgErr.Code)
+	})
+
+	t.Run("test open circuit breaker fast-fails repository calls", func(t *testing.T) {
+		breaker := repository.NewCircuitBreaker(2, time.Minute)
+		db, err := repository.NewPostgresDB(ctx, url, repository.WithCircuitBreaker(breaker))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		admin, err := newPostgresDB(strings.Replace(url, "/eschool?", "/postgres?", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer admin.Close()
+
+		// An outage is simulated by refusing new connections to the database and
+		// terminating the open ones.
+		_, err = admin.ExecContext(ctx, "ALTER DATABASE eschool ALLOW_CONNECTIONS false")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_, err := admin.ExecContext(ctx, "ALTER DATABASE eschool ALLOW_CONNECTIONS true")
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		_, err = admin.ExecContext(ctx, "SELECT pg_terminate_backend(pid) FROM pg_stat_activity "+
+			"WHERE datname = 'eschool'")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewUserRepo(db)
+		for i := 0; i < 5 && !breaker.IsOpen(); i++ {
+			_, err = repo.FindByID(ctx, users[0].ID)
+			require.ErrorIs(t, err, errs.ErrPersistenceFailed)
+		}
+		require.True(t, breaker.IsOpen())
+
+		for i := 0; i < 3; i++ {
+			_, err = repo.FindByID(ctx, users[0].ID)
+			require.ErrorIs(t, err, repository.ErrServiceUnavailable)
+		}
+	})
+}
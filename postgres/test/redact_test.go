@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"github.com/paw1a/eschool-core/domain"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"testing"
+)
+
+func TestRedactArgs(t *testing.T) {
+	ctx := context.Background()
+	db, err := newSQLiteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	var record bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&record, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	repo := repository.NewUserRepo(db, repository.WithQueryLogger(logger))
+
+	user := users[0]
+	user.Password = "s3cret-password-hash"
+	_, err = repo.Create(ctx, user)
+	require.NoError(t, err)
+
+	t.Run("test create args are redacted in log record", func(t *testing.T) {
+		record.Reset()
+		_, err := repo.Create(ctx, user)
+		require.Error(t, err)
+		require.Contains(t, record.String(), "user.Create")
+		require.NotContains(t, record.String(), user.Email)
+		require.NotContains(t, record.String(), user.Password)
+		require.Contains(t, record.String(), repository.RedactedArg)
+	})
+
+	t.Run("test credentials are redacted in log record", func(t *testing.T) {
+		record.Reset()
+		_, err := repo.FindByCredentials(ctx, user.Email, user.Password)
+		require.NoError(t, err)
+		require.Contains(t, record.String(), "user.FindByCredentials")
+		require.NotContains(t, record.String(), user.Email)
+		require.NotContains(t, record.String(), user.Password)
+		require.Contains(t, record.String(), repository.RedactedArg)
+	})
+
+	t.Run("test non sensitive args are visible in log record", func(t *testing.T) {
+		record.Reset()
+		_, err := repo.FindByIDsOrdered(ctx, []domain.ID{user.ID})
+		require.NoError(t, err)
+		require.Contains(t, record.String(), "user.FindByIDsOrdered")
+		require.Contains(t, record.String(), user.ID.String())
+		require.NotContains(t, record.String(), repository.RedactedArg)
+	})
+
+	t.Run("test nothing is logged without a logger", func(t *testing.T) {
+		record.Reset()
+		_, err := repository.NewUserRepo(db).FindByEmail(ctx, user.Email)
+		require.NoError(t, err)
+		require.Empty(t, record.String())
+	})
+
+	t.Run("test args are not modified", func(t *testing.T) {
+		args := []interface{}{user.Email}
+		redacted := repository.RedactArgs("user.FindByEmail", args...)
+		require.Equal(t, []interface{}{repository.RedactedArg}, redacted)
+		require.Equal(t, []interface{}{user.Email}, args)
+	})
+}
@@ -2,10 +2,18 @@ package repository
 
 import (
 	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
 	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
 	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
 	"github.com/stretchr/testify/require"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 )
 
 var courseID = domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7027ca")
@@ -197,4 +205,1240 @@ func TestReviewRepository(t *testing.T) {
 			t.Errorf("failed to delete review: %v", err)
 		}
 	})
+
+	t.Run("test get course rating histogram", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		histogram, err := repo.GetCourseRatingHistogram(ctx, courseID)
+		if err != nil {
+			t.Errorf("failed to get course rating histogram: %v", err)
+		}
+
+		require.Equal(t, map[int]int{1: 0, 2: 0, 3: 0, 4: 1, 5: 1}, histogram)
+	})
+
+	t.Run("test find user reviews with context", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		otherSchoolReview := domain.Review{
+			ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021ce"),
+			UserID:   userID,
+			CourseID: domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cc"),
+			Text:     "review4 text",
+		}
+		_, err = repo.Create(ctx, otherSchoolReview)
+		if err != nil {
+			t.Errorf("failed to create review: %v", err)
+		}
+
+		found, err := repo.FindUserReviewsWithContext(ctx, userID, 10, 0)
+		if err != nil {
+			t.Errorf("failed to find user reviews with context: %v", err)
+		}
+		require.Equal(t, len(found), 3)
+		require.Equal(t, reviews[0], found[0].Review)
+		require.Equal(t, "course1", found[0].CourseName)
+		require.Equal(t, "school1", found[0].SchoolName)
+		require.Equal(t, reviews[2], found[1].Review)
+		require.Equal(t, "course2", found[1].CourseName)
+		require.Equal(t, "school1", found[1].SchoolName)
+		require.Equal(t, otherSchoolReview, found[2].Review)
+		require.Equal(t, "course3", found[2].CourseName)
+		require.Equal(t, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034cd"), found[2].SchoolID)
+		require.Equal(t, "school2", found[2].SchoolName)
+
+		found, err = repo.FindUserReviewsWithContext(ctx, userID, 1, 2)
+		if err != nil {
+			t.Errorf("failed to find user reviews with context: %v", err)
+		}
+		require.Equal(t, len(found), 1)
+		require.Equal(t, otherSchoolReview, found[0].Review)
+	})
+
+	t.Run("test create with idempotency key", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		first, err := repo.CreateWithIdempotencyKey(ctx, createdReview, "create-review-key")
+		if err != nil {
+			t.Errorf("failed to create review: %v", err)
+		}
+		retried := createdReview
+		retried.ID = domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021cf")
+		second, err := repo.CreateWithIdempotencyKey(ctx, retried, "create-review-key")
+		if err != nil {
+			t.Errorf("failed to create review: %v", err)
+		}
+		require.Equal(t, createdReview, first)
+		require.Equal(t, first, second)
+
+		var count int
+		err = db.GetContext(ctx, &count, "SELECT count(*) FROM public.review WHERE id IN ($1, $2)",
+			createdReview.ID, retried.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("test flag review", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		err = repo.FlagReview(ctx, reviews[0].ID)
+		if err != nil {
+			t.Errorf("failed to flag review: %v", err)
+		}
+		err = repo.FlagReview(ctx, reviews[2].ID)
+		if err != nil {
+			t.Errorf("failed to flag review: %v", err)
+		}
+
+		var flagCount int
+		err = db.GetContext(ctx, &flagCount, "SELECT flag_count FROM public.review WHERE id = $1", reviews[2].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, 2, flagCount)
+
+		err = repo.FlagReview(ctx, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021ff"))
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test find flagged reviews", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		flagged, err := repo.FindFlaggedReviews(ctx, 1, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Errorf("failed to find flagged reviews: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[1], reviews[2]}, flagged)
+
+		flagged, err = repo.FindFlaggedReviews(ctx, 2, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Errorf("failed to find flagged reviews: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[1]}, flagged)
+
+		flagged, err = repo.FindFlaggedReviews(ctx, 4, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Errorf("failed to find flagged reviews: %v", err)
+		}
+		require.Empty(t, flagged)
+	})
+
+	t.Run("test find course reviews ordered by helpful", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		found, err := repo.FindCourseReviewsOrdered(ctx, courseID, repository.ReviewOrderHelpful)
+		if err != nil {
+			t.Errorf("failed to find course reviews: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[1], reviews[0]}, found)
+
+		for i := 0; i < 3; i++ {
+			err = repo.IncrementHelpful(ctx, reviews[0].ID)
+			if err != nil {
+				t.Errorf("failed to increment helpful: %v", err)
+			}
+		}
+
+		found, err = repo.FindCourseReviewsOrdered(ctx, courseID, repository.ReviewOrderHelpful)
+		if err != nil {
+			t.Errorf("failed to find course reviews: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[0], reviews[1]}, found)
+
+		err = repo.IncrementHelpful(ctx, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021ff"))
+		require.ErrorIs(t, err, errs.ErrNotExist)
+
+		_, err = repo.FindCourseReviewsOrdered(ctx, courseID, repository.ReviewOrder("oldest"))
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test find priority moderation queue", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		for i := 0; i < 5; i++ {
+			err = repo.FlagReview(ctx, reviews[0].ID)
+			if err != nil {
+				t.Errorf("failed to flag review: %v", err)
+			}
+		}
+
+		found, err := repo.FindPriorityModerationQueue(ctx, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Errorf("failed to find moderation queue: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[1], reviews[0], reviews[2]}, found)
+	})
+
+	t.Run("test count course reviews", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		count, err := repo.CountCourseReviews(ctx, courseID)
+		if err != nil {
+			t.Errorf("failed to count course reviews: %v", err)
+		}
+		require.Equal(t, 2, count)
+
+		count, err = repo.CountCourseReviews(ctx, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd"))
+		if err != nil {
+			t.Errorf("failed to count course reviews: %v", err)
+		}
+		require.Equal(t, 0, count)
+	})
+
+	t.Run("test write ahead buffer replays creates after outage", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		admin, err := newPostgresDB(strings.Replace(url, "/eschool?", "/postgres?", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer admin.Close()
+
+		// An outage is simulated by refusing new connections to the database and
+		// terminating the open ones.
+		setReachable := func(reachable bool) {
+			_, err := admin.ExecContext(ctx, fmt.Sprintf("ALTER DATABASE eschool ALLOW_CONNECTIONS %t", reachable))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reachable {
+				_, err = admin.ExecContext(ctx, "SELECT pg_terminate_backend(pid) FROM pg_stat_activity "+
+					"WHERE datname = 'eschool'")
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		defer setReachable(true)
+
+		repo := repository.NewReviewRepo(db)
+		buffer := repository.NewWriteAheadBuffer(repo, filepath.Join(t.TempDir(), "pending_reviews.jsonl"))
+		secondReview := domain.Review{
+			ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021ce"),
+			UserID:   userID,
+			CourseID: domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd"),
+			Text:     "review5 text",
+		}
+
+		setReachable(false)
+		_, err = buffer.Create(ctx, createdReview, "first-review")
+		require.ErrorIs(t, err, repository.ErrWriteBuffered)
+		_, err = buffer.Create(ctx, secondReview, "second-review")
+		require.ErrorIs(t, err, repository.ErrWriteBuffered)
+		_, err = buffer.Create(ctx, createdReview, "first-review")
+		require.ErrorIs(t, err, repository.ErrWriteBuffered)
+		_, err = buffer.FlushPending(ctx)
+		require.ErrorIs(t, err, repository.ErrWriteBuffered)
+
+		setReachable(true)
+		flushed, err := buffer.FlushPending(ctx)
+		if err != nil {
+			t.Errorf("failed to flush pending reviews: %v", err)
+		}
+		require.Equal(t, 3, flushed)
+
+		for _, review := range []domain.Review{createdReview, secondReview} {
+			found, err := repo.FindByID(ctx, review.ID)
+			if err != nil {
+				t.Errorf("failed to find review: %v", err)
+			}
+			require.Equal(t, review, found)
+		}
+		var count int
+		err = db.GetContext(ctx, &count, "SELECT count(*) FROM public.review WHERE id IN ($1, $2)",
+			createdReview.ID, secondReview.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, 2, count)
+
+		flushed, err = buffer.FlushPending(ctx)
+		require.NoError(t, err)
+		require.Equal(t, 0, flushed)
+	})
+
+	t.Run("test find reviews for course in window", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.review SET created_at = now() - interval '10 days' "+
+			"WHERE id = $1", reviews[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewReviewRepo(db)
+		burst := []domain.Review{reviews[1]}
+		for i, reviewID := range []string{"30e18bc1-4354-4937-9a4d-03cf0b7021d0", "30e18bc1-4354-4937-9a4d-03cf0b7021d1"} {
+			review := domain.Review{
+				ID:       domain.ID(reviewID),
+				UserID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+				CourseID: courseID,
+				Text:     fmt.Sprintf("burst review %d", i),
+			}
+			_, err = repo.Create(ctx, review)
+			if err != nil {
+				t.Errorf("failed to create review: %v", err)
+			}
+			burst = append(burst, review)
+			// the user rewrites the review, deleted reviews stay in the window
+			if i == 0 {
+				err = repo.Delete(ctx, review.ID)
+				if err != nil {
+					t.Errorf("failed to delete review: %v", err)
+				}
+			}
+		}
+
+		from := time.Now().Add(-24 * time.Hour)
+		to := time.Now().Add(24 * time.Hour)
+		found, err := repo.FindReviewsForCourseInWindow(ctx, courseID, from, to)
+		if err != nil {
+			t.Errorf("failed to find reviews in window: %v", err)
+		}
+		require.ElementsMatch(t, burst, found)
+
+		count, err := repo.CountDistinctReviewersInWindow(ctx, courseID, from, to)
+		if err != nil {
+			t.Errorf("failed to count reviewers in window: %v", err)
+		}
+		require.Equal(t, 2, count)
+
+		found, err = repo.FindReviewsForCourseInWindow(ctx, courseID, from.Add(-14*24*time.Hour), from)
+		if err != nil {
+			t.Errorf("failed to find reviews in window: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[0]}, found)
+
+		count, err = repo.CountDistinctReviewersInWindow(ctx, courseID, from.Add(-14*24*time.Hour), from)
+		if err != nil {
+			t.Errorf("failed to count reviewers in window: %v", err)
+		}
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("test soft deleted review is excluded from average and restorable", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		average, err := repo.GetCourseAverageRating(ctx, courseID)
+		if err != nil {
+			t.Fatalf("failed to get course average rating: %v", err)
+		}
+		require.Equal(t, 4.5, average)
+
+		err = repo.Delete(ctx, reviews[0].ID)
+		if err != nil {
+			t.Fatalf("failed to delete review: %v", err)
+		}
+
+		average, err = repo.GetCourseAverageRating(ctx, courseID)
+		if err != nil {
+			t.Fatalf("failed to get course average rating: %v", err)
+		}
+		require.Equal(t, 4.0, average)
+
+		found, err := repo.FindCourseReviews(ctx, courseID)
+		if err != nil {
+			t.Fatalf("failed to find course reviews: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[1]}, found)
+
+		deleted, err := repo.FindDeletedReviews(ctx)
+		if err != nil {
+			t.Fatalf("failed to find deleted reviews: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[0]}, deleted)
+
+		err = repo.RestoreReview(ctx, reviews[0].ID)
+		if err != nil {
+			t.Fatalf("failed to restore review: %v", err)
+		}
+
+		average, err = repo.GetCourseAverageRating(ctx, courseID)
+		if err != nil {
+			t.Fatalf("failed to get course average rating: %v", err)
+		}
+		require.Equal(t, 4.5, average)
+
+		err = repo.RestoreReview(ctx, reviews[0].ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test find course reviews by cursor", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		var found []domain.Review
+		var cursor string
+		for {
+			page, err := repo.FindCourseReviewsCursor(ctx, courseID, cursor, 1)
+			if err != nil {
+				t.Fatalf("failed to find course reviews page: %v", err)
+			}
+			found = append(found, page.Reviews...)
+			if cursor == "" {
+				_, err = repo.Create(ctx, domain.Review{
+					ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021cf"),
+					UserID:   users[2].ID,
+					CourseID: courseID,
+					Text:     "concurrent review text",
+				})
+				if err != nil {
+					t.Fatalf("failed to create review: %v", err)
+				}
+			}
+			if page.NextCursor == "" {
+				break
+			}
+			cursor = page.NextCursor
+		}
+		require.Equal(t, []domain.Review{reviews[1], reviews[0]}, found)
+
+		page, err := repo.FindCourseReviewsCursor(ctx, courseID, "", 10)
+		if err != nil {
+			t.Fatalf("failed to find course reviews page: %v", err)
+		}
+		require.Len(t, page.Reviews, 3)
+		require.Equal(t, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021cf"), page.Reviews[0].ID)
+		require.Empty(t, page.NextCursor)
+
+		_, err = repo.FindCourseReviewsCursor(ctx, courseID, "not a cursor", 1)
+		require.ErrorIs(t, err, repository.ErrInvalidCursor)
+	})
+
+	t.Run("test get course rating trend", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		course4ID := domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd")
+		rated := []struct {
+			id        string
+			rating    int
+			createdAt time.Time
+		}{
+			{"30e18bc1-4354-4937-9a4d-03cf0b7031ca", 4, time.Date(2024, time.January, 3, 10, 0, 0, 0, time.UTC)},
+			{"30e18bc1-4354-4937-9a4d-03cf0b7031cb", 2, time.Date(2024, time.January, 28, 10, 0, 0, 0, time.UTC)},
+			{"30e18bc1-4354-4937-9a4d-03cf0b7031cc", 5, time.Date(2024, time.March, 15, 10, 0, 0, 0, time.UTC)},
+			{"30e18bc1-4354-4937-9a4d-03cf0b7031cd", 1, time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)},
+		}
+		reviewers, err := insertReviewers(ctx, db, len(rated))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, review := range rated {
+			_, err = db.ExecContext(ctx, "INSERT INTO public.review (id, text, course_id, user_id, rating, created_at) "+
+				"VALUES ($1, 'trend review', $2, $3, $4, $5)",
+				review.id, course4ID, reviewers[i], review.rating, review.createdAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		repo := repository.NewReviewRepo(db)
+		trend, err := repo.GetCourseRatingTrend(ctx, course4ID,
+			time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("failed to get course rating trend: %v", err)
+		}
+		require.Equal(t, []repository.MonthlyRating{
+			{Month: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), AverageRating: 3, ReviewCount: 2},
+			{Month: time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC), AverageRating: 5, ReviewCount: 1},
+		}, trend)
+	})
+
+	t.Run("test find course reviews since checkpoint", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		for id, createdAt := range map[domain.ID]time.Time{
+			reviews[0].ID: time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+			reviews[1].ID: time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC),
+		} {
+			_, err = db.ExecContext(ctx, "UPDATE public.review SET created_at = $1 WHERE id = $2", createdAt, id)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		latest := domain.Review{
+			ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d2"),
+			UserID:   domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027cc"),
+			CourseID: courseID,
+			Text:     "latest review",
+		}
+		_, err = db.ExecContext(ctx, "INSERT INTO public.review (id, text, course_id, user_id, created_at) "+
+			"VALUES ($1, $2, $3, $4, $5)", latest.ID, latest.Text, latest.CourseID, latest.UserID,
+			time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewReviewRepo(db)
+		err = repo.Delete(ctx, reviews[1].ID)
+		if err != nil {
+			t.Fatalf("failed to delete review: %v", err)
+		}
+
+		found, err := repo.FindCourseReviewsSince(ctx, courseID, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC))
+		if err != nil {
+			t.Fatalf("failed to find reviews since checkpoint: %v", err)
+		}
+		require.Len(t, found, 2)
+		require.Equal(t, latest, found[0].Review)
+		require.False(t, found[0].DeletedAt.Valid)
+		require.Equal(t, reviews[1], found[1].Review)
+		require.True(t, found[1].DeletedAt.Valid)
+		require.Equal(t, null.IntFrom(4), found[1].Rating)
+	})
+
+	t.Run("test upsert course review", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		created := domain.Review{
+			ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d3"),
+			UserID:   users[2].ID,
+			CourseID: courseID,
+			Text:     "first impression",
+		}
+		review, err := repo.UpsertCourseReview(ctx, created)
+		if err != nil {
+			t.Fatalf("failed to upsert review: %v", err)
+		}
+		require.Equal(t, created, review)
+
+		edited := domain.Review{
+			ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d4"),
+			UserID:   users[2].ID,
+			CourseID: courseID,
+			Text:     "second thoughts",
+		}
+		review, err = repo.UpsertCourseReview(ctx, edited)
+		if err != nil {
+			t.Fatalf("failed to upsert review: %v", err)
+		}
+		edited.ID = created.ID
+		require.Equal(t, edited, review)
+
+		count, err := repo.CountCourseReviews(ctx, courseID)
+		if err != nil {
+			t.Errorf("failed to count course reviews: %v", err)
+		}
+		require.Equal(t, 3, count)
+
+		_, err = repo.FindByID(ctx, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d4"))
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test add review response", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		page := repository.PageParams{Limit: 10}
+		pending, err := repo.FindCourseReviewsWithoutResponse(ctx, courseID, page)
+		if err != nil {
+			t.Fatalf("failed to find reviews without response: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[0], reviews[1]}, pending)
+
+		err = repo.AddReviewResponse(ctx, reviews[0].ID, "thank you for the feedback")
+		if err != nil {
+			t.Fatalf("failed to add review response: %v", err)
+		}
+
+		pending, err = repo.FindCourseReviewsWithoutResponse(ctx, courseID, page)
+		if err != nil {
+			t.Fatalf("failed to find reviews without response: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[1]}, pending)
+
+		err = repo.AddReviewResponse(ctx, reviews[1].ID, "")
+		require.ErrorIs(t, err, entity.ErrValidation)
+
+		err = repo.AddReviewResponse(ctx, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021ff"), "response")
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test find substantive course reviews", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		substantive := domain.Review{
+			ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d5"),
+			UserID:   users[2].ID,
+			CourseID: courseID,
+			Text:     "clear lectures and useful practice tasks",
+		}
+		repo := repository.NewReviewRepo(db)
+		_, err = repo.Create(ctx, substantive)
+		if err != nil {
+			t.Fatalf("failed to create review: %v", err)
+		}
+
+		page := repository.PageParams{Limit: 10}
+		found, err := repo.FindSubstantiveCourseReviews(ctx, courseID, 0, page)
+		if err != nil {
+			t.Fatalf("failed to find substantive reviews: %v", err)
+		}
+		require.Equal(t, []domain.Review{substantive}, found)
+
+		found, err = repo.FindSubstantiveCourseReviews(ctx, courseID, len(reviews[0].Text), page)
+		if err != nil {
+			t.Fatalf("failed to find substantive reviews: %v", err)
+		}
+		require.ElementsMatch(t, []domain.Review{substantive, reviews[0], reviews[1]}, found)
+
+		repo = repository.NewReviewRepo(db, repository.WithMinReviewLength(len(substantive.Text)+1))
+		found, err = repo.FindSubstantiveCourseReviews(ctx, courseID, 0, page)
+		if err != nil {
+			t.Fatalf("failed to find substantive reviews: %v", err)
+		}
+		require.Empty(t, found)
+	})
+
+	t.Run("test find course reviews excluding user", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		found, err := repo.FindCourseReviewsExcludingUser(ctx, courseID, reviews[0].UserID, repository.PageParams{Limit: 10})
+		if err != nil {
+			t.Fatalf("failed to find course reviews excluding user: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[1]}, found)
+	})
+
+	t.Run("test find unanswered reviews older than", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.review SET created_at = now() - interval '100 hours'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, "UPDATE public.review SET created_at = now() - interval '1 hour' "+
+			"WHERE course_id = $1", courses[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewReviewRepo(db)
+		err = repo.AddReviewResponse(ctx, reviews[1].ID, "thank you")
+		require.NoError(t, err)
+
+		breaches, err := repo.FindUnansweredReviewsOlderThan(ctx, 72*time.Hour, repository.PageParams{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, breaches, 1)
+		require.Equal(t, reviews[0], breaches[0].Review)
+		require.Equal(t, courses[0].Name, breaches[0].CourseName)
+		require.Equal(t, schools[0].ID, breaches[0].SchoolID)
+		require.Equal(t, schools[0].Name, breaches[0].SchoolName)
+	})
+
+	t.Run("test get course average ratings concurrently", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		courseIDs := []domain.ID{reviews[0].CourseID, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7027cb")}
+		for len(courseIDs) < 200 {
+			courseIDs = append(courseIDs, domain.ID(uuid.NewString()))
+		}
+
+		repo := repository.NewReviewRepo(db)
+		ratings, err := repo.GetCourseAverageRatings(ctx, courseIDs, 4)
+		require.NoError(t, err)
+		require.Len(t, ratings, 200)
+		require.Equal(t, 4.5, ratings[courseIDs[0]])
+		require.Equal(t, 3.0, ratings[courseIDs[1]])
+		require.Equal(t, 0.0, ratings[courseIDs[199]])
+
+		for len(courseIDs) < 5000 {
+			courseIDs = append(courseIDs, domain.ID(uuid.NewString()))
+		}
+		cancelCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, err = repo.GetCourseAverageRatings(cancelCtx, courseIDs, 4)
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("test find course reviews by month", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		course4ID := domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd")
+		written := []struct {
+			id        domain.ID
+			createdAt time.Time
+		}{
+			{"30e18bc1-4354-4937-9a4d-03cf0b7032ca", time.Date(2024, time.January, 3, 10, 0, 0, 0, time.UTC)},
+			{"30e18bc1-4354-4937-9a4d-03cf0b7032cb", time.Date(2024, time.January, 31, 23, 0, 0, 0, time.UTC)},
+			{"30e18bc1-4354-4937-9a4d-03cf0b7032cc", time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)},
+			{"30e18bc1-4354-4937-9a4d-03cf0b7032cd", time.Date(2024, time.May, 1, 10, 0, 0, 0, time.UTC)},
+		}
+		reviewers, err := insertReviewers(ctx, db, len(written))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, review := range written {
+			_, err = db.ExecContext(ctx, "INSERT INTO public.review (id, text, course_id, user_id, created_at) "+
+				"VALUES ($1, 'timeline review', $2, $3, $4)", review.id, course4ID, reviewers[i], review.createdAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		repo := repository.NewReviewRepo(db)
+		months, err := repo.FindCourseReviewsByMonth(ctx, course4ID,
+			time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, time.May, 1, 0, 0, 0, 0, time.UTC))
+		require.NoError(t, err)
+
+		ids := make(map[string][]domain.ID)
+		for month, reviews := range months {
+			for _, review := range reviews {
+				ids[month] = append(ids[month], review.ID)
+			}
+		}
+		require.Equal(t, map[string][]domain.ID{
+			"2024-01": {written[0].id, written[1].id},
+			"2024-03": {written[2].id},
+		}, ids)
+	})
+
+	t.Run("test find low rating reviews for school", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.review SET rating = 2 WHERE id = $1", reviews[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, "UPDATE public.review SET rating = 1, created_at = now() - interval '10 days' "+
+			"WHERE course_id = $1", courses[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, "INSERT INTO public.review (id, text, course_id, user_id, rating) "+
+			"VALUES ($1, 'other school review', $2, $3, 1)",
+			uuid.NewString(), "30e18bc1-4354-4937-9a4d-03cf0b7026cc", users[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewReviewRepo(db)
+		found, err := repo.FindLowRatingReviewsForSchool(ctx, schools[0].ID, 2, time.Now().AddDate(0, 0, -7))
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Equal(t, reviews[1].ID, found[0].Review.ID)
+		require.Equal(t, courses[0].Name, found[0].CourseName)
+		require.Equal(t, schools[0].ID, found[0].SchoolID)
+
+		found, err = repo.FindLowRatingReviewsForSchool(ctx, schools[0].ID, 2, time.Now().AddDate(0, 0, -30))
+		require.NoError(t, err)
+		require.Len(t, found, 2)
+	})
+
+	t.Run("test create rated review", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		created, err := repo.CreateRatedReview(ctx, createdReview, 2)
+		if err != nil {
+			t.Fatalf("failed to create rated review: %v", err)
+		}
+		require.Equal(t, createdReview, created.Review)
+		require.Equal(t, int64(2), created.Rating.Int64)
+
+		found, err := repo.FindRatedByID(ctx, createdReview.ID)
+		if err != nil {
+			t.Errorf("failed to find rated review: %v", err)
+		}
+		require.Equal(t, created, found)
+
+		histogram, err := repo.GetCourseRatingHistogram(ctx, createdReview.CourseID)
+		if err != nil {
+			t.Errorf("failed to get course rating histogram: %v", err)
+		}
+		require.Equal(t, map[int]int{1: 0, 2: 1, 3: 1, 4: 0, 5: 0}, histogram)
+
+		found, err = repo.FindRatedByID(ctx, reviews[0].ID)
+		if err != nil {
+			t.Errorf("failed to find rated review: %v", err)
+		}
+		require.Equal(t, reviews[0], found.Review)
+		require.Equal(t, int64(5), found.Rating.Int64)
+
+		review := createdReview
+		review.ID = domain.ID(uuid.NewString())
+		_, err = repo.CreateRatedReview(ctx, review, 0)
+		require.ErrorIs(t, err, entity.ErrValidation)
+	})
+
+	t.Run("test find course reviews deleted after checkpoint", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		checkpoint := time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC)
+		_, err = db.ExecContext(ctx, "UPDATE public.review SET created_at = $1 WHERE course_id = $2",
+			time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC), courseID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewReviewRepo(db)
+		found, err := repo.FindCourseReviewsSince(ctx, courseID, checkpoint)
+		if err != nil {
+			t.Fatalf("failed to find reviews since checkpoint: %v", err)
+		}
+		require.Empty(t, found)
+
+		err = repo.Delete(ctx, reviews[0].ID)
+		if err != nil {
+			t.Fatalf("failed to delete review: %v", err)
+		}
+		transient := domain.Review{
+			ID:       domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d8"),
+			UserID:   users[2].ID,
+			CourseID: courseID,
+			Text:     "transient review",
+		}
+		transientCreatedAt := time.Date(2024, time.March, 1, 10, 0, 0, 0, time.UTC)
+		_, err = db.ExecContext(ctx, "INSERT INTO public.review (id, text, course_id, user_id, rating, created_at) "+
+			"VALUES ($1, $2, $3, $4, 1, $5)", transient.ID, transient.Text, transient.CourseID, transient.UserID,
+			transientCreatedAt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = repo.Delete(ctx, transient.ID)
+		if err != nil {
+			t.Fatalf("failed to delete review: %v", err)
+		}
+
+		found, err = repo.FindCourseReviewsSince(ctx, courseID, checkpoint)
+		if err != nil {
+			t.Fatalf("failed to find reviews since checkpoint: %v", err)
+		}
+		require.Len(t, found, 2)
+
+		// counted before the checkpoint, to subtract
+		require.Equal(t, reviews[0], found[0].Review)
+		require.Equal(t, null.IntFrom(5), found[0].Rating)
+		require.True(t, found[0].CreatedAt.Before(checkpoint))
+		require.True(t, found[0].DeletedAt.Valid)
+		require.True(t, found[0].DeletedAt.Time.After(checkpoint))
+
+		// written and deleted after the checkpoint, never counted
+		require.Equal(t, transient, found[1].Review)
+		require.Equal(t, null.IntFrom(1), found[1].Rating)
+		require.Equal(t, transientCreatedAt, found[1].CreatedAt)
+		require.True(t, found[1].DeletedAt.Valid)
+	})
+
+	t.Run("test upsert course review keeps its rating", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		rated, err := repo.FindRatedByID(ctx, reviews[0].ID)
+		if err != nil {
+			t.Fatalf("failed to find rated review: %v", err)
+		}
+		require.Equal(t, null.IntFrom(5), rated.Rating)
+
+		edited := reviews[0]
+		edited.ID = domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d5")
+		edited.Text = "edited text of a rated review"
+		review, err := repo.UpsertCourseReview(ctx, edited)
+		if err != nil {
+			t.Fatalf("failed to upsert review: %v", err)
+		}
+		require.Equal(t, reviews[0].ID, review.ID)
+		require.Equal(t, edited.Text, review.Text)
+
+		rated, err = repo.FindRatedByID(ctx, reviews[0].ID)
+		if err != nil {
+			t.Fatalf("failed to find rated review: %v", err)
+		}
+		require.Equal(t, edited.Text, rated.Review.Text)
+		require.Equal(t, null.IntFrom(5), rated.Rating)
+	})
+
+	t.Run("test find course reviews sorted with unrated first or last", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.review SET rating = NULL WHERE id = $1", reviews[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewReviewRepo(db)
+		found, err := repo.FindCourseReviewsSorted(ctx, courseID, repository.ReviewSort{Order: repository.ReviewOrderRating})
+		if err != nil {
+			t.Fatalf("failed to find course reviews by rating: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[1], reviews[0]}, found)
+
+		found, err = repo.FindCourseReviewsSorted(ctx, courseID,
+			repository.ReviewSort{Order: repository.ReviewOrderRating, NullsFirst: true})
+		if err != nil {
+			t.Fatalf("failed to find course reviews by rating: %v", err)
+		}
+		require.Equal(t, []domain.Review{reviews[0], reviews[1]}, found)
+	})
+
+	t.Run("test upsert rated course review replaces its rating", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		edited := reviews[0]
+		edited.ID = domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d6")
+		edited.Text = "edited text of a re-rated review"
+		upserted, err := repo.UpsertRatedCourseReview(ctx, edited, 2)
+		if err != nil {
+			t.Fatalf("failed to upsert rated review: %v", err)
+		}
+		require.Equal(t, reviews[0].ID, upserted.Review.ID)
+		require.Equal(t, edited.Text, upserted.Review.Text)
+		require.Equal(t, null.IntFrom(2), upserted.Rating)
+
+		found, err := repo.FindRatedByID(ctx, reviews[0].ID)
+		if err != nil {
+			t.Fatalf("failed to find rated review: %v", err)
+		}
+		require.Equal(t, upserted, found)
+
+		_, err = repo.FindByID(ctx, edited.ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test user holds one live review per course", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewReviewRepo(db)
+		second := reviews[0]
+		second.ID = domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7021d7")
+		second.Text = "second review of the same course"
+		_, err = repo.Create(ctx, second)
+		require.ErrorIs(t, err, errs.ErrDuplicate)
+		require.NotErrorIs(t, err, repository.ErrIDConflict)
+
+		err = repo.Delete(ctx, reviews[0].ID)
+		if err != nil {
+			t.Fatalf("failed to delete review: %v", err)
+		}
+		created, err := repo.Create(ctx, second)
+		if err != nil {
+			t.Fatalf("failed to create review: %v", err)
+		}
+		require.Equal(t, second, created)
+	})
 }
@@ -2,11 +2,14 @@ package repository
 
 import (
 	"context"
+	"github.com/google/uuid"
 	"github.com/guregu/null"
 	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
 	repository "github.com/paw1a/eschool-repository/postgres"
 	"github.com/stretchr/testify/require"
 	"testing"
+	"time"
 )
 
 var schools = []domain.School{
@@ -267,4 +270,715 @@ func TestSchoolRepository(t *testing.T) {
 			t.Errorf("failed to delete school: %v", err)
 		}
 	})
+
+	t.Run("test find school teachers with course count", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		found, err := repo.FindSchoolTeachersWithCourseCount(ctx, schools[0].ID)
+		if err != nil {
+			t.Errorf("failed to find school teachers with course count: %v", err)
+		}
+		require.Equal(t, len(found), 2)
+		require.Equal(t, teachers[0], found[0].Teacher)
+		require.Equal(t, 0, found[0].CourseCount)
+		require.Equal(t, teachers[1], found[1].Teacher)
+		require.Equal(t, 2, found[1].CourseCount)
+	})
+
+	t.Run("test find schools page", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		page, err := repo.FindSchoolsPage(ctx, repository.PageParams{Limit: 1, Offset: 0})
+		if err != nil {
+			t.Errorf("failed to find schools page: %v", err)
+		}
+		require.Equal(t, len(schools), page.Total)
+		require.Equal(t, []domain.School{schools[0]}, page.Items)
+
+		page, err = repo.FindSchoolsPage(ctx, repository.PageParams{Limit: 1, Offset: 1})
+		if err != nil {
+			t.Errorf("failed to find schools page: %v", err)
+		}
+		require.Equal(t, len(schools), page.Total)
+		require.Equal(t, []domain.School{schools[1]}, page.Items)
+
+		page, err = repo.FindSchoolsPage(ctx, repository.PageParams{Limit: 1, Offset: 10})
+		if err != nil {
+			t.Errorf("failed to find schools page: %v", err)
+		}
+		require.Equal(t, len(schools), page.Total)
+		require.Empty(t, page.Items)
+	})
+
+	t.Run("test find schools by owner ids", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		ownerIDs := []domain.ID{schools[0].OwnerID, schools[1].OwnerID, newTeacherID}
+		found, err := repo.FindSchoolsByOwnerIDs(ctx, ownerIDs)
+		if err != nil {
+			t.Errorf("failed to find schools by owner ids: %v", err)
+		}
+		require.Equal(t, map[domain.ID][]domain.School{
+			schools[0].OwnerID: {schools[0]},
+			schools[1].OwnerID: {schools[1]},
+		}, found)
+	})
+
+	t.Run("test find school by name", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		school, err := repo.FindByName(ctx, schools[0].Name)
+		if err != nil {
+			t.Errorf("failed to find school by name: %v", err)
+		}
+		require.Equal(t, schools[0], school)
+
+		_, err = repo.FindByName(ctx, "missing school")
+		require.ErrorIs(t, err, errs.ErrNotExist)
+
+		duplicate := createdSchool
+		duplicate.Name = schools[0].Name
+		_, err = repo.Create(ctx, duplicate)
+		if err != nil {
+			t.Fatalf("failed to create school with a duplicate name: %v", err)
+		}
+		school, err = repo.FindByName(ctx, schools[0].Name)
+		if err != nil {
+			t.Errorf("failed to find school by name: %v", err)
+		}
+		require.Equal(t, schools[0], school)
+	})
+
+	t.Run("test find school by slug", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		slug, err := repo.FindSchoolSlug(ctx, schools[0].ID)
+		if err != nil {
+			t.Fatalf("failed to find school slug: %v", err)
+		}
+		require.Equal(t, "school1-0b7034cc", slug)
+
+		school, err := repo.FindBySlug(ctx, slug)
+		if err != nil {
+			t.Errorf("failed to find school by slug: %v", err)
+		}
+		require.Equal(t, schools[0], school)
+
+		duplicate := createdSchool
+		duplicate.Name = schools[0].Name
+		_, err = repo.Create(ctx, duplicate)
+		if err != nil {
+			t.Fatalf("failed to create school with a duplicate name: %v", err)
+		}
+		duplicateSlug, err := repo.FindSchoolSlug(ctx, duplicate.ID)
+		if err != nil {
+			t.Fatalf("failed to find school slug: %v", err)
+		}
+		require.NotEqual(t, slug, duplicateSlug)
+		school, err = repo.FindBySlug(ctx, duplicateSlug)
+		if err != nil {
+			t.Errorf("failed to find school by slug: %v", err)
+		}
+		require.Equal(t, duplicate.ID, school.ID)
+
+		_, err = repo.FindBySlug(ctx, "missing-school")
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test find user school roles", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		roles, err := repo.FindUserSchoolRoles(ctx, teachers[1].ID)
+		if err != nil {
+			t.Errorf("failed to find user school roles: %v", err)
+		}
+		require.Equal(t, map[domain.ID]string{
+			schools[0].ID: repository.SchoolRoleTeacher,
+			schools[1].ID: repository.SchoolRoleOwner,
+		}, roles)
+
+		roles, err = repo.FindUserSchoolRoles(ctx, teachers[0].ID)
+		if err != nil {
+			t.Errorf("failed to find user school roles: %v", err)
+		}
+		require.Equal(t, map[domain.ID]string{
+			schools[0].ID: repository.SchoolRoleOwner,
+		}, roles)
+	})
+
+	t.Run("test find top reviewers", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		reviewers, err := repo.FindTopReviewers(ctx, schools[0].ID, 10)
+		if err != nil {
+			t.Errorf("failed to find top reviewers: %v", err)
+		}
+		require.Equal(t, len(reviewers), 2)
+		require.Equal(t, teachers[0], reviewers[0].Reviewer)
+		require.Equal(t, 2, reviewers[0].ReviewCount)
+		require.Equal(t, teachers[1], reviewers[1].Reviewer)
+		require.Equal(t, 1, reviewers[1].ReviewCount)
+
+		reviewers, err = repo.FindTopReviewers(ctx, schools[0].ID, 1)
+		if err != nil {
+			t.Errorf("failed to find top reviewers: %v", err)
+		}
+		require.Equal(t, len(reviewers), 1)
+		require.Equal(t, teachers[0], reviewers[0].Reviewer)
+
+		reviewers, err = repo.FindTopReviewers(ctx, schools[1].ID, 10)
+		if err != nil {
+			t.Errorf("failed to find top reviewers: %v", err)
+		}
+		require.Empty(t, reviewers)
+	})
+
+	t.Run("test find school courses by status", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		draft, err := repo.FindSchoolCoursesByStatus(ctx, schools[0].ID, domain.CourseDraft)
+		if err != nil {
+			t.Errorf("failed to find school courses by status: %v", err)
+		}
+		require.Equal(t, len(draft), 1)
+		require.Equal(t, "course1", draft[0].Name)
+
+		published, err := repo.FindSchoolCoursesByStatus(ctx, schools[0].ID, domain.CoursePublished)
+		if err != nil {
+			t.Errorf("failed to find school courses by status: %v", err)
+		}
+		require.Equal(t, len(published), 1)
+		require.Equal(t, "course2", published[0].Name)
+
+		ready, err := repo.FindSchoolCoursesByStatus(ctx, schools[0].ID, domain.CourseReady)
+		if err != nil {
+			t.Errorf("failed to find school courses by status: %v", err)
+		}
+		require.Empty(t, ready)
+
+		_, err = repo.FindSchoolCoursesByStatus(ctx, schools[0].ID, domain.CourseStatus(42))
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test rows observer sees finder row count", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		observed := make(map[string]int)
+		repo := repository.NewSchoolRepo(db, repository.WithRowsObserver(func(method string, rows int) {
+			observed[method] = rows
+		}))
+		courses, err := repo.FindSchoolCourses(ctx, schools[0].ID)
+		if err != nil {
+			t.Fatalf("failed to find school courses: %v", err)
+		}
+		require.Len(t, courses, 2)
+		require.Equal(t, map[string]int{"school.FindSchoolCourses": 2}, observed)
+	})
+
+	t.Run("test find popular schools", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		popular, err := repo.FindPopularSchools(ctx, 10)
+		if err != nil {
+			t.Fatalf("failed to find popular schools: %v", err)
+		}
+		require.Equal(t, []repository.SchoolWithMetric{
+			{School: schools[0], Metric: 2},
+			{School: schools[1], Metric: 0},
+		}, popular)
+
+		courseRepo := repository.NewCourseRepo(db)
+		for _, userID := range []domain.ID{users[0].ID, users[1].ID, users[2].ID} {
+			err = courseRepo.AddCourseStudent(ctx, userID, domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7026cd"))
+			if err != nil {
+				t.Fatalf("failed to add course student: %v", err)
+			}
+		}
+
+		popular, err = repo.FindPopularSchools(ctx, 1)
+		if err != nil {
+			t.Fatalf("failed to find popular schools: %v", err)
+		}
+		require.Equal(t, []repository.SchoolWithMetric{{School: schools[1], Metric: 3}}, popular)
+	})
+
+	t.Run("test apply discount to school courses", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		discounted, err := repo.ApplyDiscountToSchoolCourses(ctx, schools[0].ID, 10)
+		if err != nil {
+			t.Fatalf("failed to apply discount: %v", err)
+		}
+		require.Equal(t, 2, discounted)
+
+		courses, err := repo.FindSchoolCourses(ctx, schools[0].ID)
+		if err != nil {
+			t.Fatalf("failed to find school courses: %v", err)
+		}
+		prices := make(map[domain.ID]int64)
+		for _, course := range courses {
+			prices[course.ID] = course.Price
+		}
+		require.Equal(t, map[domain.ID]int64{
+			domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7027ca"): 1080,
+			domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7027cb"): 1350,
+		}, prices)
+
+		for _, percent := range []int{0, 100, -5} {
+			_, err = repo.ApplyDiscountToSchoolCourses(ctx, schools[0].ID, percent)
+			require.ErrorIs(t, err, repository.ErrInvalidDiscount)
+		}
+	})
+
+	t.Run("test find school teachers excluding owner", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		isTeacher, err := repo.IsSchoolTeacher(ctx, schools[0].ID, schools[0].OwnerID)
+		if err != nil {
+			t.Fatalf("failed to check school teacher: %v", err)
+		}
+		require.True(t, isTeacher)
+
+		teachers, err := repo.FindSchoolTeachersExcludingOwner(ctx, schools[0].ID)
+		if err != nil {
+			t.Fatalf("failed to find school teachers: %v", err)
+		}
+		require.Equal(t, []domain.User{users[1]}, teachers)
+	})
+
+	t.Run("test find teacher schools", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "INSERT INTO public.school_teacher (teacher_id, school_id) VALUES ($1, $2)",
+			users[1].ID, schools[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewSchoolRepo(db)
+		found, err := repo.FindTeacherSchools(ctx, users[1].ID)
+		if err != nil {
+			t.Errorf("failed to find teacher schools: %v", err)
+		}
+		require.Equal(t, []domain.School{schools[0], schools[1]}, found)
+
+		found, err = repo.FindTeacherSchools(ctx, users[2].ID)
+		if err != nil {
+			t.Errorf("failed to find teacher schools: %v", err)
+		}
+		require.Empty(t, found)
+	})
+
+	t.Run("test approve pending school", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		params := repository.PageParams{Limit: 10}
+		pending, err := repo.FindSchoolsByStatus(ctx, repository.SchoolStatusPending, params)
+		if err != nil {
+			t.Fatalf("failed to find pending schools: %v", err)
+		}
+		require.Equal(t, []domain.School{schools[1]}, pending.Items)
+		require.Equal(t, 1, pending.Total)
+
+		err = repo.ApproveSchool(ctx, schools[1].ID)
+		if err != nil {
+			t.Fatalf("failed to approve school: %v", err)
+		}
+
+		pending, err = repo.FindSchoolsByStatus(ctx, repository.SchoolStatusPending, params)
+		if err != nil {
+			t.Fatalf("failed to find pending schools: %v", err)
+		}
+		require.Empty(t, pending.Items)
+
+		approved, err := repo.FindSchoolsByStatus(ctx, repository.SchoolStatusApproved, params)
+		if err != nil {
+			t.Fatalf("failed to find approved schools: %v", err)
+		}
+		require.Equal(t, []domain.School{schools[0], schools[1]}, approved.Items)
+
+		err = repo.ApproveSchool(ctx, schools[1].ID)
+		require.ErrorIs(t, err, repository.ErrIllegalTransition)
+
+		err = repo.ApproveSchool(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7034ff"))
+		require.ErrorIs(t, err, errs.ErrNotExist)
+
+		_, err = repo.FindSchoolsByStatus(ctx, "rejected", params)
+		require.ErrorIs(t, err, errs.ErrEnumValueError)
+	})
+
+	t.Run("test find school by owner and name", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		school, err := repo.FindSchoolByOwnerAndName(ctx, schools[0].OwnerID, schools[0].Name)
+		if err != nil {
+			t.Fatalf("failed to find school by owner and name: %v", err)
+		}
+		require.Equal(t, schools[0], school)
+
+		_, err = repo.FindSchoolByOwnerAndName(ctx, schools[1].OwnerID, schools[0].Name)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+
+		_, err = repo.FindSchoolByOwnerAndName(ctx, schools[0].OwnerID, "missing school")
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test create school with existing owner", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewSchoolRepo(db)
+		school, err := repo.CreateSchool(ctx, createdSchool)
+		require.NoError(t, err)
+		require.Equal(t, createdSchool, school)
+	})
+
+	t.Run("test create school with missing owner", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		school := createdSchool
+		school.OwnerID = domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027ff")
+		repo := repository.NewSchoolRepo(db)
+		_, err = repo.CreateSchool(ctx, school)
+		require.ErrorIs(t, err, repository.ErrOwnerNotExist)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+		require.Contains(t, err.Error(), school.OwnerID.String())
+
+		_, err = repo.FindByID(ctx, school.ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test create school with concurrently deleted owner", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		tx, err := db.BeginTxx(ctx, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = tx.ExecContext(ctx, "DELETE FROM public.user WHERE id = $1", newTeacherID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		school := createdSchool
+		school.OwnerID = newTeacherID
+		created := make(chan error, 1)
+		go func() {
+			_, err := repository.NewSchoolRepo(db).CreateSchool(ctx, school)
+			created <- err
+		}()
+
+		// the owner lock waits for the deleting transaction
+		time.Sleep(200 * time.Millisecond)
+		require.Empty(t, created)
+		if err = tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		require.ErrorIs(t, <-created, repository.ErrOwnerNotExist)
+	})
+
+	t.Run("test find school courses with ratings", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		unreviewedCourseID := domain.ID("30e18bc1-4354-4937-9a4d-03cf0b7027ce")
+		_, err = db.ExecContext(ctx, "INSERT INTO public.course (id, school_id, name, level, price, language, status) "+
+			"VALUES ($1, $2, 'course5', 1, 100, 'english', 'draft')", unreviewedCourseID, schools[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewSchoolRepo(db)
+		found, err := repo.FindSchoolCoursesWithRatings(ctx, schools[0].ID, repository.PageParams{Limit: 10})
+		require.NoError(t, err)
+		require.Len(t, found, 3)
+		require.Equal(t, courses[0], found[0].Course)
+		require.Equal(t, 4.5, found[0].AverageRating)
+		require.Equal(t, 2, found[0].ReviewCount)
+		require.Equal(t, courses[1], found[1].Course)
+		require.Equal(t, 3.0, found[1].AverageRating)
+		require.Equal(t, 1, found[1].ReviewCount)
+		require.Equal(t, unreviewedCourseID, found[2].Course.ID)
+		require.Equal(t, 0.0, found[2].AverageRating)
+		require.Equal(t, 0, found[2].ReviewCount)
+
+		found, err = repo.FindSchoolCoursesWithRatings(ctx, schools[0].ID, repository.PageParams{Limit: 1, Offset: 2})
+		require.NoError(t, err)
+		require.Len(t, found, 1)
+		require.Equal(t, unreviewedCourseID, found[0].Course.ID)
+	})
+
+	t.Run("test find most active schools", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.course_student SET enrolled_at = now() - interval '60 days'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = db.ExecContext(ctx, "UPDATE public.review SET created_at = now() - interval '60 days'")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// one recent enrollment in school1 and two recent reviews in school2
+		_, err = db.ExecContext(ctx, "INSERT INTO public.course_student (student_id, course_id) VALUES ($1, $2)",
+			users[1].ID, courses[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, userID := range []domain.ID{users[0].ID, users[1].ID} {
+			_, err = db.ExecContext(ctx, "INSERT INTO public.review (id, text, course_id, user_id) "+
+				"VALUES ($1, 'recent review', $2, $3)",
+				uuid.NewString(), "30e18bc1-4354-4937-9a4d-03cf0b7026cc", userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		repo := repository.NewSchoolRepo(db)
+		active, err := repo.FindMostActiveSchools(ctx, time.Now().AddDate(0, 0, -30), 10)
+		require.NoError(t, err)
+		require.Len(t, active, 2)
+		require.Equal(t, schools[1].ID, active[0].School.ID)
+		require.Equal(t, 2, active[0].Metric)
+		require.Equal(t, schools[0].ID, active[1].School.ID)
+		require.Equal(t, 1, active[1].Metric)
+
+		active, err = repo.FindMostActiveSchools(ctx, time.Now().AddDate(0, 0, -90), 1)
+		require.NoError(t, err)
+		require.Len(t, active, 1)
+		require.Equal(t, schools[0].ID, active[0].School.ID)
+		require.Equal(t, 6, active[0].Metric)
+	})
 }
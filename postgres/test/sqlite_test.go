@@ -0,0 +1,302 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"github.com/guregu/null"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+const sqliteUserSchema = `
+	CREATE TABLE public.user (
+		id text primary key,
+		email text unique not null,
+		password text not null,
+		name text not null,
+		surname text not null,
+		phone text,
+		city text,
+		avatar_url text,
+		created_at timestamp not null default current_timestamp,
+		updated_at timestamp not null default current_timestamp
+	)`
+
+// newSQLiteDB opens an in-memory SQLite database with the tables attached
+// under the public schema name, so the repository queries run unchanged.
+func newSQLiteDB() (*sqlx.DB, error) {
+	db, err := sqlx.Connect("sqlite3", ":memory:")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	for _, query := range []string{"ATTACH DATABASE ':memory:' AS public", sqliteUserSchema} {
+		if _, err = db.Exec(query); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func TestSQLiteUserRepository(t *testing.T) {
+	ctx := context.Background()
+	db, err := newSQLiteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	repo := repository.NewUserRepo(db)
+
+	user, err := repo.Create(ctx, createdUser)
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	require.Equal(t, createdUser, user)
+
+	user, err = repo.FindByEmail(ctx, createdUser.Email)
+	if err != nil {
+		t.Errorf("failed to find user by email: %v", err)
+	}
+	require.Equal(t, createdUser, user)
+
+	updated := updatedUser
+	updated.ID = createdUser.ID
+	user, err = repo.Update(ctx, updated)
+	if err != nil {
+		t.Errorf("failed to update user: %v", err)
+	}
+	require.Equal(t, updated, user)
+
+	err = repo.Delete(ctx, createdUser.ID)
+	if err != nil {
+		t.Errorf("failed to delete user: %v", err)
+	}
+	_, err = repo.FindByID(ctx, createdUser.ID)
+	require.ErrorIs(t, err, errs.ErrNotExist)
+
+	invalidUser := createdUser
+	invalidUser.Name = ""
+	_, err = repo.Create(ctx, invalidUser)
+	require.ErrorIs(t, err, entity.ErrValidation)
+}
+
+func TestSQLiteMaxResultRows(t *testing.T) {
+	ctx := context.Background()
+	db, err := newSQLiteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	ids := make([]domain.ID, 20)
+	for i := range ids {
+		ids[i] = domain.ID(uuid.NewString())
+		_, err = db.Exec("INSERT INTO public.user (id, email, password, name, surname) "+
+			"VALUES (?, ?, 'pass', 'Max', 'Rows')",
+			ids[i], fmt.Sprintf("user%d@mail.ru", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("test result over the cap", func(t *testing.T) {
+		repo := repository.NewUserRepo(db, repository.WithMaxResultRows(10))
+		_, err := repo.FindAll(ctx)
+		require.ErrorIs(t, err, repository.ErrResultTooLarge)
+	})
+
+	t.Run("test result at the cap", func(t *testing.T) {
+		repo := repository.NewUserRepo(db, repository.WithMaxResultRows(20))
+		users, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		require.Len(t, users, 20)
+	})
+
+	t.Run("test cap applies to every finder", func(t *testing.T) {
+		repo := repository.NewUserRepo(db, repository.WithMaxResultRows(10))
+		_, err := repo.FindByIDsOrdered(ctx, ids)
+		require.ErrorIs(t, err, repository.ErrResultTooLarge)
+
+		_, _, err = repo.FindUsersModifiedSince(ctx, repository.UserSyncCursor{}, 15)
+		require.ErrorIs(t, err, repository.ErrResultTooLarge)
+
+		users, _, err := repo.FindUsersModifiedSince(ctx, repository.UserSyncCursor{}, 5)
+		require.NoError(t, err)
+		require.Len(t, users, 5)
+	})
+}
+
+func TestSQLiteRateLimit(t *testing.T) {
+	ctx := context.Background()
+	db, err := newSQLiteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	userID := uuid.NewString()
+	_, err = db.Exec("INSERT INTO public.user (id, email, password, name, surname) "+
+		"VALUES (?, 'limited@mail.ru', 'pass', 'Rate', 'Limit')", userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test calls over the rate are rejected", func(t *testing.T) {
+		repo := repository.NewUserRepo(db, repository.WithRateLimit("user.FindAll", 0.001, 2))
+		for i := 0; i < 2; i++ {
+			users, err := repo.FindAll(ctx)
+			require.NoError(t, err)
+			require.Len(t, users, 1)
+		}
+		_, err := repo.FindAll(ctx)
+		require.ErrorIs(t, err, repository.ErrRateLimited)
+
+		users, err := repo.FindByIDsOrdered(ctx, []domain.ID{domain.ID(userID)})
+		require.NoError(t, err)
+		require.Len(t, users, 1)
+	})
+
+	t.Run("test tokens refill at the rate", func(t *testing.T) {
+		repo := repository.NewUserRepo(db, repository.WithRateLimit("user.FindAll", 50, 1))
+		_, err := repo.FindAll(ctx)
+		require.NoError(t, err)
+		_, err = repo.FindAll(ctx)
+		require.ErrorIs(t, err, repository.ErrRateLimited)
+
+		time.Sleep(40 * time.Millisecond)
+		_, err = repo.FindAll(ctx)
+		require.NoError(t, err)
+	})
+}
+
+func TestSQLiteEmptyBatchInput(t *testing.T) {
+	ctx := context.Background()
+	db, err := newSQLiteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// every query fails on a closed database, so a batch method returning
+	// without an error has not run any
+	db.Close()
+
+	userRepo := repository.NewUserRepo(db)
+	_, err = userRepo.FindAll(ctx)
+	require.Error(t, err)
+
+	for _, ids := range [][]domain.ID{nil, {}} {
+		users, err := userRepo.FindByIDsOrdered(ctx, ids)
+		require.NoError(t, err)
+		require.NotNil(t, users)
+		require.Empty(t, users)
+
+		exists, err := userRepo.ExistsByIDs(ctx, ids)
+		require.NoError(t, err)
+		require.NotNil(t, exists)
+		require.Empty(t, exists)
+
+		exists, err = repository.NewCourseRepo(db).ExistsByIDs(ctx, ids)
+		require.NoError(t, err)
+		require.NotNil(t, exists)
+		require.Empty(t, exists)
+
+		schools, err := repository.NewSchoolRepo(db).FindSchoolsByOwnerIDs(ctx, ids)
+		require.NoError(t, err)
+		require.NotNil(t, schools)
+		require.Empty(t, schools)
+
+		counts, err := repository.NewCertificateRepo(db).CountCertificatesByCourse(ctx, ids)
+		require.NoError(t, err)
+		require.NotNil(t, counts)
+		require.Empty(t, counts)
+
+		ratings, err := repository.NewReviewRepo(db).GetCourseAverageRatings(ctx, ids, 4)
+		require.NoError(t, err)
+		require.NotNil(t, ratings)
+		require.Empty(t, ratings)
+	}
+
+	for _, tags := range [][]string{nil, {}} {
+		for _, matchAll := range []bool{true, false} {
+			courses, err := repository.NewCourseRepo(db).FindCoursesByTags(ctx, tags, matchAll)
+			require.NoError(t, err)
+			require.NotNil(t, courses)
+			require.Empty(t, courses)
+		}
+	}
+}
+
+type sqliteCourseRow struct {
+	ID   string `db:"id"`
+	Name string `db:"name"`
+}
+
+type sqliteCourseWithSchool struct {
+	sqliteCourseRow
+	SchoolName  string      `db:"school_name"`
+	Description null.String `db:"school_description"`
+	Teachers    int64       `db:"teachers"`
+}
+
+func TestSQLiteScanRows(t *testing.T) {
+	ctx := context.Background()
+	db, err := newSQLiteDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, query := range []string{
+		"CREATE TABLE public.school (id text primary key, name text not null, description text)",
+		"CREATE TABLE public.course (id text primary key, school_id text, name text not null)",
+		"INSERT INTO public.school VALUES ('s1', 'school1', 'first school')",
+		"INSERT INTO public.course VALUES ('c1', 's1', 'course1'), ('c2', NULL, 'course2')",
+	} {
+		if _, err = db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	const joinQuery = `SELECT c.id, c.name, s.name AS school_name,
+		s.description AS school_description, length(s.id) AS teachers
+		FROM public.course c LEFT JOIN public.school s ON s.id = c.school_id ORDER BY c.id`
+
+	t.Run("test joined columns with nulls", func(t *testing.T) {
+		rows, err := entity.SelectDTOs[sqliteCourseWithSchool](ctx, db, joinQuery)
+		require.NoError(t, err)
+		require.Equal(t, []sqliteCourseWithSchool{
+			{
+				sqliteCourseRow: sqliteCourseRow{ID: "c1", Name: "course1"},
+				SchoolName:      "school1",
+				Description:     null.StringFrom("first school"),
+				Teachers:        2,
+			},
+			{
+				sqliteCourseRow: sqliteCourseRow{ID: "c2", Name: "course2"},
+			},
+		}, rows)
+	})
+
+	t.Run("test no rows", func(t *testing.T) {
+		rows, err := entity.SelectDTOs[sqliteCourseWithSchool](ctx, db,
+			"SELECT id, name FROM public.course WHERE id = ?", "c3")
+		require.NoError(t, err)
+		require.Empty(t, rows)
+	})
+
+	t.Run("test unknown column", func(t *testing.T) {
+		_, err := entity.SelectDTOs[sqliteCourseRow](ctx, db, "SELECT id, name, school_id FROM public.course")
+		require.ErrorIs(t, err, entity.ErrUnknownColumn)
+		require.ErrorContains(t, err, "school_id")
+	})
+}
@@ -84,3 +84,35 @@ values ('30e18bc1-4352-4937-9a3b-03cf0b7027cb', 'url', E'opt1\nopt2',
 insert into test (id, task_url, options, answer, score, level, lesson_id)
 values ('30e18bc1-4352-4937-9a3b-03cf0b7027cc', 'url', E'opt1',
         'opt1', 12, 2, '30e18bc1-4352-4937-9a3b-03cf0b7022cc');
+
+-- insert review ratings
+update public.review set rating = 5 where id = '30e18bc1-4354-4937-9a4d-03cf0b7021ca';
+update public.review set rating = 4 where id = '30e18bc1-4354-4937-9a4d-03cf0b7021cb';
+update public.review set rating = 3 where id = '30e18bc1-4354-4937-9a4d-03cf0b7021cc';
+
+-- insert certificate expiry dates
+update public.certificate set expires_at = now() - interval '1 day'
+where id = '30e18bc1-4352-4937-9a3b-03cf0b7027ca';
+update public.certificate set expires_at = now() + interval '10 days'
+where id = '30e18bc1-4352-4937-9a3b-03cf0b7027cb';
+
+-- insert certificate verification hashes
+update public.certificate set verification_hash = 'a3f1c9e2b7d84f60'
+where id = '30e18bc1-4352-4937-9a3b-03cf0b7027ca';
+
+-- insert review flags
+update public.review set flag_count = 3 where id = '30e18bc1-4354-4937-9a4d-03cf0b7021cb';
+update public.review set flag_count = 1 where id = '30e18bc1-4354-4937-9a4d-03cf0b7021cc';
+
+-- insert review helpful counts
+update public.review set helpful_count = 2 where id = '30e18bc1-4354-4937-9a4d-03cf0b7021cb';
+
+-- insert user registration dates
+update public.user set created_at = now() - interval '1 year'
+where id in ('30e18bc1-4354-4937-9a3b-03cf0b7027ca', '30e18bc1-4354-4937-9a3b-03cf0b7027cc');
+
+-- insert user modification dates
+update public.user set updated_at = now() - interval '1 day';
+
+-- insert school statuses
+update public.school set status = 'approved' where id = '30e18bc1-4354-4937-9a3b-03cf0b7034cc';
@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jmoiron/sqlx"
+	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithinTx(t *testing.T) {
+	ctx := context.Background()
+	container, err := newPostgresContainer(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clean up the container after the test is complete
+	t.Cleanup(func() {
+		if err := container.Terminate(ctx); err != nil {
+			t.Fatalf("failed to terminate container: %s", err)
+		}
+	})
+
+	url, err := container.ConnectionString(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("test serialization conflict is resolved by retry", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		studentID := users[1].ID
+		certificateCourseID := courses[0].ID
+		certificateIDs := []string{
+			"30e18bc1-4352-4937-9a3b-03cf0b7027d0",
+			"30e18bc1-4352-4937-9a3b-03cf0b7027d1",
+		}
+
+		// Both transactions read before either writes, so the second one to
+		// commit is bound to fail and has to be retried.
+		var reads sync.WaitGroup
+		reads.Add(len(certificateIDs))
+		issue := func(certificateID string) error {
+			attempt := 0
+			return repository.WithinTxRetry(ctx, db, &sql.TxOptions{Isolation: sql.LevelSerializable}, 3,
+				func(tx *sqlx.Tx) error {
+					attempt++
+					var issued bool
+					err := tx.GetContext(ctx, &issued, "SELECT EXISTS (SELECT 1 FROM public.certificate "+
+						"WHERE user_id = $1 AND course_id = $2)", studentID, certificateCourseID)
+					if err != nil {
+						return err
+					}
+					if attempt == 1 {
+						reads.Done()
+						reads.Wait()
+					}
+					if issued {
+						return nil
+					}
+					_, err = tx.ExecContext(ctx, "INSERT INTO public.certificate "+
+						"(id, name, score, grade, created_at, user_id, course_id) "+
+						"VALUES ($1, 'course 1 cert', 100, 'silver', $2, $3, $4)",
+						certificateID, time.Now(), studentID, certificateCourseID)
+					return err
+				})
+		}
+
+		issueErrs := make([]error, len(certificateIDs))
+		var wg sync.WaitGroup
+		for i, certificateID := range certificateIDs {
+			wg.Add(1)
+			go func(i int, certificateID string) {
+				defer wg.Done()
+				issueErrs[i] = issue(certificateID)
+			}(i, certificateID)
+		}
+		wg.Wait()
+
+		for _, err := range issueErrs {
+			require.NoError(t, err)
+		}
+
+		var count int
+		err = db.GetContext(ctx, &count, "SELECT COUNT(*) FROM public.certificate "+
+			"WHERE user_id = $1 AND course_id = $2", studentID, certificateCourseID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, 1, count)
+	})
+
+	t.Run("test serialization failure without retry", func(t *testing.T) {
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		err = repository.WithinTx(ctx, db, nil, func(tx *sqlx.Tx) error {
+			_, err := tx.ExecContext(ctx, "DO $$ BEGIN RAISE EXCEPTION USING ERRCODE = '40001'; END $$")
+			return err
+		})
+		require.ErrorIs(t, err, repository.ErrSerializationFailure)
+	})
+
+	t.Run("test retry with no attempts runs once", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		calls := 0
+		err = repository.WithinTxRetry(ctx, db, nil, 0, func(tx *sqlx.Tx) error {
+			calls++
+			_, err := tx.ExecContext(ctx, "DO $$ BEGIN RAISE EXCEPTION USING ERRCODE = '40001'; END $$")
+			return err
+		})
+		require.ErrorIs(t, err, repository.ErrSerializationFailure)
+		require.Equal(t, 1, calls)
+	})
+}
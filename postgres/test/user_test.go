@@ -2,11 +2,18 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"github.com/google/uuid"
 	"github.com/guregu/null"
 	"github.com/paw1a/eschool-core/domain"
+	"github.com/paw1a/eschool-core/errs"
 	repository "github.com/paw1a/eschool-repository/postgres"
+	"github.com/paw1a/eschool-repository/postgres/entity"
 	"github.com/stretchr/testify/require"
+	"strings"
 	"testing"
+	"time"
 )
 
 var users = []domain.User{
@@ -194,4 +201,973 @@ func TestUserRepository(t *testing.T) {
 			t.Errorf("failed to delete user: %v", err)
 		}
 	})
+
+	t.Run("test find duplicate emails", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		duplicateUser := createdUser
+		duplicateUser.Email = strings.ToUpper(users[0].Email)
+		_, err = repo.Create(ctx, duplicateUser)
+		if err != nil {
+			t.Errorf("failed to create user: %v", err)
+		}
+
+		groups, err := repo.FindDuplicateEmails(ctx)
+		if err != nil {
+			t.Errorf("failed to find duplicate emails: %v", err)
+		}
+		require.Equal(t, len(groups), 1)
+		require.Equal(t, users[0].Email, groups[0].Email)
+		require.Equal(t, []domain.ID{users[0].ID, duplicateUser.ID}, groups[0].UserIDs)
+		require.Equal(t, 2, groups[0].Count)
+	})
+
+	t.Run("test read only guard", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		guard := repository.NewReadOnlyGuard()
+		repo := repository.NewUserRepo(db, repository.WithReadOnlyGuard(guard))
+		guard.SetReadOnly(true)
+
+		_, err = repo.Create(ctx, createdUser)
+		require.ErrorIs(t, err, repository.ErrReadOnly)
+		err = repo.Delete(ctx, users[0].ID)
+		require.ErrorIs(t, err, repository.ErrReadOnly)
+
+		user, err := repo.FindByID(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to find user with id: %v", err)
+		}
+		require.Equal(t, users[0], user)
+
+		guard.SetReadOnly(false)
+		_, err = repo.Create(ctx, createdUser)
+		if err != nil {
+			t.Errorf("failed to create user: %v", err)
+		}
+	})
+
+	t.Run("test erase user data", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		err = repo.EraseUserData(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to erase user data: %v", err)
+		}
+
+		_, err = repo.FindByID(ctx, users[0].ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+		for _, table := range []string{"review", "certificate"} {
+			var count int
+			err = db.GetContext(ctx, &count, "SELECT COUNT(*) FROM public."+table+" WHERE user_id = $1", users[0].ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			require.Equal(t, 0, count, table)
+		}
+		var enrollments int
+		err = db.GetContext(ctx, &enrollments, "SELECT COUNT(*) FROM public.course_student WHERE student_id = $1", users[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, 0, enrollments)
+	})
+
+	t.Run("test erase user data rollback", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		_, err = db.ExecContext(ctx, `
+			CREATE FUNCTION fail_user_delete() RETURNS trigger AS $$
+			BEGIN
+				RAISE EXCEPTION 'injected failure';
+			END;
+			$$ LANGUAGE plpgsql;
+			CREATE TRIGGER fail_user_delete BEFORE DELETE ON public.user
+			FOR EACH ROW EXECUTE FUNCTION fail_user_delete();`)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewUserRepo(db)
+		err = repo.EraseUserData(ctx, users[0].ID)
+		require.ErrorIs(t, err, errs.ErrDeleteFailed)
+
+		user, err := repo.FindByID(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to find user with id: %v", err)
+		}
+		require.Equal(t, users[0], user)
+		var reviewsCount int
+		err = db.GetContext(ctx, &reviewsCount, "SELECT COUNT(*) FROM public.review WHERE user_id = $1", users[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.Equal(t, 2, reviewsCount)
+	})
+
+	t.Run("test find users by surname prefix", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		found, err := repo.FindUsersBySurnamePrefix(ctx, "sh", 10)
+		if err != nil {
+			t.Errorf("failed to find users by surname prefix: %v", err)
+		}
+		require.Equal(t, []domain.User{users[2], users[0]}, found)
+
+		found, err = repo.FindUsersBySurnamePrefix(ctx, "Zz", 10)
+		if err != nil {
+			t.Errorf("failed to find users by surname prefix: %v", err)
+		}
+		require.NotNil(t, found)
+		require.Empty(t, found)
+
+		percentUser := createdUser
+		percentUser.Surname = "%percent"
+		_, err = repo.Create(ctx, percentUser)
+		if err != nil {
+			t.Errorf("failed to create user: %v", err)
+		}
+		found, err = repo.FindUsersBySurnamePrefix(ctx, "%", 10)
+		if err != nil {
+			t.Errorf("failed to find users by surname prefix: %v", err)
+		}
+		require.Equal(t, []domain.User{percentUser}, found)
+	})
+
+	t.Run("test for each user", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		const newUsersCount = 300
+		for i := 0; i < newUsersCount; i++ {
+			user := createdUser
+			user.ID = domain.ID(uuid.NewString())
+			user.Email = fmt.Sprintf("user%d@mail.com", i)
+			_, err = repo.Create(ctx, user)
+			if err != nil {
+				t.Fatalf("failed to create user: %v", err)
+			}
+		}
+
+		seen := make(map[domain.ID]bool)
+		err = repo.ForEach(ctx, func(user domain.User) error {
+			seen[user.ID] = true
+			return nil
+		})
+		if err != nil {
+			t.Errorf("failed to iterate users: %v", err)
+		}
+		require.Equal(t, len(users)+newUsersCount, len(seen))
+	})
+
+	t.Run("test for each user stops on error", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		errStop := errors.New("stop iteration")
+		visited := 0
+		err = repo.ForEach(ctx, func(user domain.User) error {
+			visited++
+			return errStop
+		})
+		require.ErrorIs(t, err, errStop)
+		require.Equal(t, 1, visited)
+	})
+
+	t.Run("test batch writer creates users", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		writer := repository.NewBatchWriter(db, 100, time.Second)
+		results := make([]<-chan repository.BatchResult, 0, 501)
+		for i := 0; i < 500; i++ {
+			results = append(results, writer.Create(ctx, newBenchmarkUser(i)))
+		}
+		duplicate := newBenchmarkUser(500)
+		duplicate.Email = users[0].Email
+		results = append(results, writer.Create(ctx, duplicate))
+		writer.Close(ctx)
+
+		for i := 0; i < 500; i++ {
+			result := <-results[i]
+			require.NoError(t, result.Err)
+		}
+		result := <-results[500]
+		require.ErrorIs(t, result.Err, errs.ErrDuplicate)
+
+		repo := repository.NewUserRepo(db)
+		found, err := repo.FindAll(ctx)
+		if err != nil {
+			t.Errorf("failed to find all users: %v", err)
+		}
+		require.Equal(t, len(users)+500, len(found))
+		require.Equal(t, 501, writer.Stats().Items)
+		require.LessOrEqual(t, writer.Stats().Flushes, 6)
+	})
+
+	t.Run("test find users modified since", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		watermark := repository.UserSyncCursor{UpdatedAt: time.Now().Add(-time.Hour)}
+		found, next, err := repo.FindUsersModifiedSince(ctx, watermark, 10)
+		if err != nil {
+			t.Errorf("failed to find modified users: %v", err)
+		}
+		require.Empty(t, found)
+		require.Equal(t, watermark, next)
+
+		for _, user := range []domain.User{users[2], users[0]} {
+			user.City = null.StringFrom("Kazan")
+			_, err = repo.Update(ctx, user)
+			if err != nil {
+				t.Errorf("failed to update user: %v", err)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		found, next, err = repo.FindUsersModifiedSince(ctx, watermark, 10)
+		if err != nil {
+			t.Errorf("failed to find modified users: %v", err)
+		}
+		require.Equal(t, len(found), 2)
+		require.Equal(t, users[2].ID, found[0].ID)
+		require.Equal(t, users[0].ID, found[1].ID)
+
+		found, _, err = repo.FindUsersModifiedSince(ctx, next, 10)
+		if err != nil {
+			t.Errorf("failed to find modified users: %v", err)
+		}
+		require.Empty(t, found)
+	})
+
+	t.Run("test find public user by id", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		user, err := repo.FindPublicByID(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to find public user: %v", err)
+		}
+		require.Equal(t, users[0].ID, user.ID)
+		require.Equal(t, users[0].Name, user.Name)
+		require.Equal(t, users[0].Surname, user.Surname)
+		require.Empty(t, user.Email)
+		require.Empty(t, user.Password)
+		require.False(t, user.Phone.Valid)
+
+		user, err = repo.FindByID(ctx, users[0].ID)
+		if err != nil {
+			t.Errorf("failed to find user: %v", err)
+		}
+		require.Equal(t, users[0].Email, user.Email)
+		require.Equal(t, users[0].Password, user.Password)
+		require.Equal(t, users[0].Phone, user.Phone)
+	})
+
+	t.Run("test find users by ids ordered", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		ids := []domain.ID{
+			users[2].ID,
+			domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027ff"),
+			users[0].ID,
+			users[1].ID,
+		}
+		found, err := repo.FindByIDsOrdered(ctx, ids)
+		if err != nil {
+			t.Fatalf("failed to find users by ids: %v", err)
+		}
+		require.Equal(t, []domain.User{users[2], users[0], users[1]}, found)
+	})
+
+	t.Run("test read your writes reads from primary despite replica lag", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		admin, err := newPostgresDB(strings.Replace(url, "/eschool?", "/postgres?", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			admin.Close()
+		})
+
+		// The replica is a copy of the database taken before the write below, so
+		// it lags behind the primary for the whole test.
+		_, err = admin.ExecContext(ctx, "CREATE DATABASE eschool_replica TEMPLATE migrated_template")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_, err := admin.ExecContext(ctx, "DROP DATABASE eschool_replica")
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		replica, err := newPostgresDB(strings.Replace(url, "/eschool?", "/eschool_replica?", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer replica.Close()
+
+		repo := repository.NewUserRepo(db, repository.WithReadReplica(replica, time.Minute))
+		_, err = repo.Create(ctx, createdUser)
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+
+		_, err = repo.FindByID(ctx, createdUser.ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+
+		found, err := repo.FindByID(repository.ReadYourWrites(ctx), createdUser.ID)
+		if err != nil {
+			t.Fatalf("failed to find user on primary: %v", err)
+		}
+		require.Equal(t, createdUser, found)
+	})
+
+	t.Run("test users by cohort", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		signups := []time.Time{
+			time.Date(2024, time.January, 1, 10, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 3, 10, 0, 0, 0, time.UTC),
+			time.Date(2024, time.January, 10, 10, 0, 0, 0, time.UTC),
+			time.Date(2024, time.February, 15, 10, 0, 0, 0, time.UTC),
+		}
+		for i, createdAt := range signups {
+			_, err = db.ExecContext(ctx, "INSERT INTO public.user (id, email, password, name, surname, created_at) "+
+				"VALUES ($1, $2, 'pass', 'Cohort', 'User', $3)",
+				uuid.NewString(), fmt.Sprintf("cohort%d@mail.ru", i), createdAt)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+		day := func(month time.Month, day int) time.Time {
+			return time.Date(2024, month, day, 0, 0, 0, 0, time.UTC)
+		}
+
+		repo := repository.NewUserRepo(db)
+		for _, tc := range []struct {
+			bucket string
+			counts []repository.DateCount
+		}{
+			{"day", []repository.DateCount{
+				{Date: day(time.January, 1), Count: 1},
+				{Date: day(time.January, 3), Count: 1},
+				{Date: day(time.January, 10), Count: 1},
+				{Date: day(time.February, 15), Count: 1},
+			}},
+			{"week", []repository.DateCount{
+				{Date: day(time.January, 1), Count: 2},
+				{Date: day(time.January, 8), Count: 1},
+				{Date: day(time.February, 12), Count: 1},
+			}},
+			{"month", []repository.DateCount{
+				{Date: day(time.January, 1), Count: 3},
+				{Date: day(time.February, 1), Count: 1},
+			}},
+		} {
+			counts, err := repo.UsersByCohort(ctx, from, to, tc.bucket)
+			if err != nil {
+				t.Fatalf("failed to count users by %s: %v", tc.bucket, err)
+			}
+			require.Equal(t, tc.counts, counts, tc.bucket)
+		}
+
+		_, err = repo.UsersByCohort(ctx, from, to, "year")
+		require.ErrorIs(t, err, repository.ErrInvalidBucket)
+	})
+
+	t.Run("test get user activity summary", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		activity, err := repo.GetUserActivitySummary(ctx, users[0].ID)
+		if err != nil {
+			t.Fatalf("failed to get user activity summary: %v", err)
+		}
+		require.Equal(t, repository.UserActivity{CoursesCompleted: 2, ReviewsWritten: 2, SchoolsOwned: 1}, activity)
+
+		activity, err = repo.GetUserActivitySummary(ctx, users[2].ID)
+		if err != nil {
+			t.Fatalf("failed to get user activity summary: %v", err)
+		}
+		require.Equal(t, repository.UserActivity{}, activity)
+
+		_, err = repo.GetUserActivitySummary(ctx, createdUser.ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test exists by ids", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		exists, err := repo.ExistsByIDs(ctx, []domain.ID{users[0].ID, createdUser.ID, users[2].ID})
+		if err != nil {
+			t.Fatalf("failed to check users existence: %v", err)
+		}
+		require.Equal(t, map[domain.ID]bool{
+			users[0].ID:    true,
+			createdUser.ID: false,
+			users[2].ID:    true,
+		}, exists)
+
+		exists, err = repo.ExistsByIDs(ctx, nil)
+		if err != nil {
+			t.Fatalf("failed to check users existence: %v", err)
+		}
+		require.Empty(t, exists)
+	})
+
+	t.Run("test find by ids in chunks", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		ids := make([]domain.ID, 0, 5000)
+		for len(ids) < 4997 {
+			ids = append(ids, domain.ID(uuid.NewString()))
+		}
+		ids = append(ids, users[2].ID, users[0].ID, users[1].ID)
+
+		repo := repository.NewUserRepo(db)
+		found, err := repo.FindByIDsOrdered(ctx, ids)
+		require.NoError(t, err)
+		require.Equal(t, []domain.User{users[2], users[0], users[1]}, found)
+
+		exists, err := repo.ExistsByIDs(ctx, ids)
+		require.NoError(t, err)
+		require.Len(t, exists, 5000)
+		require.True(t, exists[users[0].ID])
+		require.False(t, exists[ids[0]])
+	})
+
+	t.Run("test find user by email or id with id", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		user, err := repo.FindUserByEmailOrID(ctx, users[1].ID.String())
+		require.NoError(t, err)
+		require.Equal(t, users[1], user)
+	})
+
+	t.Run("test find user by email or id with email", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		user, err := repo.FindUserByEmailOrID(ctx, " "+users[1].Email+"\n")
+		require.NoError(t, err)
+		require.Equal(t, users[1], user)
+	})
+
+	t.Run("test find user by email or id without match", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		_, err = repo.FindUserByEmailOrID(ctx, "30e18bc1-4354-4937-9a3b-03cf0b7027ff")
+		require.ErrorIs(t, err, errs.ErrNotExist)
+		_, err = repo.FindUserByEmailOrID(ctx, "nobody@mail.ru")
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test import users with failing rows", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		newUser := func(email string) domain.User {
+			user := createdUser
+			user.ID = domain.ID(uuid.NewString())
+			user.Email = email
+			return user
+		}
+		batch := []domain.User{
+			newUser("first@mail.com"),
+			newUser(users[0].Email),
+			newUser("second@mail.com"),
+			newUser("first@mail.com"),
+			newUser(""),
+		}
+
+		repo := repository.NewUserRepo(db)
+		result, err := repo.ImportUsers(ctx, batch)
+		require.NoError(t, err)
+		require.Equal(t, []domain.User{batch[0], batch[2]}, result.Imported)
+		require.Len(t, result.Failed, 3)
+		require.Equal(t, 1, result.Failed[0].Index)
+		require.ErrorIs(t, result.Failed[0].Err, errs.ErrDuplicate)
+		require.Equal(t, 3, result.Failed[1].Index)
+		require.ErrorIs(t, result.Failed[1].Err, errs.ErrDuplicate)
+		require.Equal(t, 4, result.Failed[2].Index)
+		require.Equal(t, batch[4], result.Failed[2].User)
+
+		for _, user := range []domain.User{batch[0], batch[2]} {
+			found, err := repo.FindByID(ctx, user.ID)
+			require.NoError(t, err)
+			require.Equal(t, user, found)
+		}
+		_, err = repo.FindByID(ctx, batch[3].ID)
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test fresh read bypasses the lagging replica", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		admin, err := newPostgresDB(strings.Replace(url, "/eschool?", "/postgres?", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			admin.Close()
+		})
+
+		// The replica is a copy of the database taken before the update below, so
+		// it serves the stale user for the whole test.
+		_, err = admin.ExecContext(ctx, "CREATE DATABASE eschool_stale_replica TEMPLATE migrated_template")
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() {
+			_, err := admin.ExecContext(ctx, "DROP DATABASE eschool_stale_replica")
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+		replica, err := newPostgresDB(strings.Replace(url, "/eschool?", "/eschool_stale_replica?", 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer replica.Close()
+
+		_, err = db.ExecContext(ctx, "UPDATE public.user SET name = 'Edited' WHERE id = $1", users[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewUserRepo(db, repository.WithReadReplica(replica, time.Minute))
+		stale, err := repo.FindByID(ctx, users[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, users[0].Name, stale.Name)
+
+		fresh, err := repo.FindByID(repository.FreshRead(ctx), users[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, "Edited", fresh.Name)
+	})
+
+	t.Run("test patch single field", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		patched, err := repo.Patch(ctx, users[0].ID, map[string]any{"city": "Kazan"})
+		require.NoError(t, err)
+		expected := users[0]
+		expected.City = null.StringFrom("Kazan")
+		require.Equal(t, expected, patched)
+
+		found, err := repo.FindByID(ctx, users[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, expected, found)
+	})
+
+	t.Run("test patch multiple fields", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		patched, err := repo.Patch(ctx, users[1].ID, map[string]any{
+			"name":       "Patched",
+			"surname":    "Surname",
+			"avatar_url": nil,
+		})
+		require.NoError(t, err)
+		expected := users[1]
+		expected.Name = "Patched"
+		expected.Surname = "Surname"
+		expected.AvatarUrl = null.String{}
+		require.Equal(t, expected, patched)
+
+		_, err = repo.Patch(ctx, domain.ID("30e18bc1-4354-4937-9a3b-03cf0b7027ff"), map[string]any{"name": "Nobody"})
+		require.ErrorIs(t, err, errs.ErrNotExist)
+	})
+
+	t.Run("test patch unknown field", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		_, err = repo.Patch(ctx, users[0].ID, map[string]any{"name": "Patched", "is_admin": true})
+		require.ErrorIs(t, err, entity.ErrUnknownColumn)
+
+		found, err := repo.FindByID(ctx, users[0].ID)
+		require.NoError(t, err)
+		require.Equal(t, users[0], found)
+	})
+
+	t.Run("test patch without fields", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		repo := repository.NewUserRepo(db)
+		_, err = repo.Patch(ctx, users[0].ID, map[string]any{})
+		require.ErrorIs(t, err, entity.ErrValidation)
+	})
+
+	t.Run("test find users modified since does not skip users updated at once", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		updatedAt := time.Now().Add(time.Hour).UTC().Truncate(time.Microsecond)
+		_, err = db.ExecContext(ctx, "UPDATE public.user SET updated_at = $1 WHERE id IN ($2, $3)",
+			updatedAt, users[0].ID, users[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		repo := repository.NewUserRepo(db)
+		cursor := repository.UserSyncCursor{UpdatedAt: updatedAt.Add(-time.Second)}
+		found, cursor, err := repo.FindUsersModifiedSince(ctx, cursor, 1)
+		if err != nil {
+			t.Fatalf("failed to find modified users: %v", err)
+		}
+		require.Len(t, found, 1)
+		require.Equal(t, users[0].ID, found[0].ID)
+		require.Equal(t, users[0].ID, cursor.ID)
+
+		found, cursor, err = repo.FindUsersModifiedSince(ctx, cursor, 1)
+		if err != nil {
+			t.Fatalf("failed to find modified users: %v", err)
+		}
+		require.Len(t, found, 1)
+		require.Equal(t, users[1].ID, found[0].ID)
+
+		found, _, err = repo.FindUsersModifiedSince(ctx, cursor, 1)
+		if err != nil {
+			t.Fatalf("failed to find modified users: %v", err)
+		}
+		require.Empty(t, found)
+	})
+
+	t.Run("test user created at is stored in UTC", func(t *testing.T) {
+		t.Cleanup(func() {
+			err = container.Restore(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+		})
+
+		db, err := newPostgresDB(url)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+
+		conn, err := db.Connx(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		_, err = conn.ExecContext(ctx, "SET TIME ZONE 'Asia/Tokyo'")
+		if err != nil {
+			t.Fatal(err)
+		}
+		userID := uuid.NewString()
+		_, err = conn.ExecContext(ctx, "INSERT INTO public.user (id, email, password, name, surname) "+
+			"VALUES ($1, 'tokyo@mail.com', 'password', 'Tokyo', 'Tokyo')", userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var createdAt time.Time
+		err = conn.GetContext(ctx, &createdAt, "SELECT created_at FROM public.user WHERE id = $1", userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		require.WithinDuration(t, time.Now().UTC(), createdAt, time.Minute)
+	})
 }
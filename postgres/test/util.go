@@ -3,21 +3,22 @@ package repository
 import (
 	"context"
 	"fmt"
-	"github.com/golang-migrate/migrate"
-	migratepg "github.com/golang-migrate/migrate/database/postgres"
-	_ "github.com/golang-migrate/migrate/source/file"
+	"github.com/google/uuid"
 	_ "github.com/jackc/pgx/v4/stdlib"
 	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/domain"
+	repository "github.com/paw1a/eschool-repository/postgres"
 	"github.com/testcontainers/testcontainers-go"
 	testpg "github.com/testcontainers/testcontainers-go/modules/postgres"
 	"github.com/testcontainers/testcontainers-go/wait"
+	"os"
 	"path/filepath"
 	"runtime"
 	"time"
 )
 
-func newPostgresContainer(ctx context.Context) (*testpg.PostgresContainer, error) {
-	container, err := testpg.RunContainer(
+func runPostgresContainer(ctx context.Context) (*testpg.PostgresContainer, error) {
+	return testpg.RunContainer(
 		ctx,
 		testpg.WithDatabase("eschool"),
 		testpg.WithUsername("postgres"),
@@ -27,45 +28,76 @@ func newPostgresContainer(ctx context.Context) (*testpg.PostgresContainer, error
 				WithOccurrence(2).
 				WithStartupTimeout(5*time.Second)),
 	)
+}
+
+func newPostgresContainer(ctx context.Context) (*testpg.PostgresContainer, error) {
+	container, err := runPostgresContainer(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to run postgres container: %s", err)
+	}
 
 	_, path, _, ok := runtime.Caller(0)
 	if !ok {
 		return nil, fmt.Errorf("failed to get caller path")
 	}
 
-	sourceUrl := "file://" + filepath.Dir(path) + "/migrations"
 	url, err := container.ConnectionString(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get postgres db url: %s", err)
 	}
 
-	db, err := newPostgresDB(url)
+	seedPath := filepath.Join(filepath.Dir(path), "testdata", "insert_data.sql")
+	err = initPostgresDB(ctx, url, seedPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to connect to postgres db: %s", err)
+		return nil, err
 	}
-	defer db.Close()
 
-	driver, err := migratepg.WithInstance(db.DB, &migratepg.Config{})
+	err = container.Snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make a snapshot of postgres db: %s", err)
+	}
+
+	return container, nil
+}
+
+func initPostgresDB(ctx context.Context, url string, seedPath string) error {
+	db, err := newPostgresDB(url)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get db driver from instance: %s", err)
+		return fmt.Errorf("failed to connect to postgres db: %s", err)
 	}
+	defer db.Close()
 
-	mig, err := migrate.NewWithDatabaseInstance(sourceUrl, "eschool", driver)
+	err = repository.Migrate(ctx, db)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create migrator driver: %s", err)
+		return fmt.Errorf("failed to up migrations: %s", err)
 	}
 
-	err = mig.Up()
+	seed, err := os.ReadFile(seedPath)
 	if err != nil {
-		return nil, fmt.Errorf("failed to up migrations: %s", err)
+		return fmt.Errorf("failed to read test data: %s", err)
 	}
 
-	err = container.Snapshot(ctx)
+	_, err = db.ExecContext(ctx, string(seed))
 	if err != nil {
-		return nil, fmt.Errorf("failed to make a snapshot of postgres db: %s", err)
+		return fmt.Errorf("failed to insert test data: %s", err)
 	}
 
-	return container, nil
+	return nil
+}
+
+// insertReviewers inserts n users for the tests needing more reviewers of a
+// course than the seeded users, a user holds one live review per course.
+func insertReviewers(ctx context.Context, db *sqlx.DB, n int) ([]domain.ID, error) {
+	ids := make([]domain.ID, n)
+	for i := range ids {
+		ids[i] = domain.ID(uuid.NewString())
+		_, err := db.ExecContext(ctx, "INSERT INTO public.user (id, email, password, name, surname) "+
+			"VALUES ($1, $2, 'password', 'Reviewer', 'Reviewer')", ids[i], fmt.Sprintf("reviewer%s@mail.com", ids[i]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert reviewer: %s", err)
+		}
+	}
+	return ids, nil
 }
 
 const (
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"github.com/jackc/pgconn"
+	"github.com/jmoiron/sqlx"
+	"github.com/paw1a/eschool-core/errs"
+	"github.com/pkg/errors"
+)
+
+var PgSerializationFailureCode = "40001"
+
+// ErrSerializationFailure is returned when a serializable transaction lost a
+// conflict with a concurrent one. The transaction can be retried as a whole.
+var ErrSerializationFailure = errors.New("serialization failure")
+
+// WithinTx runs fn in a transaction started with opts, a nil opts starts a
+// read committed read-write transaction. The transaction is committed when
+// fn returns nil and rolled back otherwise.
+func WithinTx(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, fn func(tx *sqlx.Tx) error) error {
+	tx, err := db.BeginTxx(ctx, opts)
+	if err != nil {
+		return errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+
+	if err = fn(tx); err != nil {
+		tx.Rollback()
+		if isSerializationFailure(err) {
+			return errors.Wrap(ErrSerializationFailure, err.Error())
+		}
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		if isSerializationFailure(err) {
+			return errors.Wrap(ErrSerializationFailure, err.Error())
+		}
+		return errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+	return nil
+}
+
+// WithinTxRetry runs WithinTx and starts over, up to attempts times in
+// total, while the transaction fails with ErrSerializationFailure. A
+// non-positive attempts runs fn once. fn must be safe to run again, it sees
+// the rows committed by the winning transaction on the next attempt.
+func WithinTxRetry(ctx context.Context, db *sqlx.DB, opts *sql.TxOptions, attempts int,
+	fn func(tx *sqlx.Tx) error) error {
+	if attempts < 1 {
+		attempts = 1
+	}
+	var err error
+	for i := 0; i < attempts; i++ {
+		err = WithinTx(ctx, db, opts, fn)
+		if !errors.Is(err, ErrSerializationFailure) {
+			return err
+		}
+	}
+	return err
+}
+
+// RowLock is the strength of the row lock taken by the for-update finders.
+type RowLock string
+
+const (
+	LockForUpdate      RowLock = "UPDATE"
+	LockForNoKeyUpdate RowLock = "NO KEY UPDATE"
+	LockForShare       RowLock = "SHARE"
+	LockForKeyShare    RowLock = "KEY SHARE"
+)
+
+// lockClause appends the locking clause of the given strength to query.
+func lockClause(query string, lock RowLock) (string, error) {
+	switch lock {
+	case LockForUpdate, LockForNoKeyUpdate, LockForShare, LockForKeyShare:
+		return query + " FOR " + string(lock), nil
+	}
+	return "", errors.Wrapf(errs.ErrEnumValueError, "row lock %q", lock)
+}
+
+func isSerializationFailure(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == PgSerializationFailureCode
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"github.com/google/uuid"
 	"github.com/jackc/pgconn"
 	"github.com/jmoiron/sqlx"
 	"github.com/paw1a/eschool-core/domain"
@@ -10,36 +11,110 @@ import (
 	"github.com/paw1a/eschool-core/port"
 	"github.com/paw1a/eschool-repository/postgres/entity"
 	"github.com/pkg/errors"
+	"strings"
+	"time"
 )
 
 type PostgresUserRepo struct {
-	db *sqlx.DB
+	db   *sqlx.DB
+	opts options
 }
 
-func NewUserRepo(db *sqlx.DB) *PostgresUserRepo {
+type EmailGroup struct {
+	Email   string
+	UserIDs []domain.ID
+	Count   int
+}
+
+// UserActivity sums up what the user did on the platform, a course counts
+// as completed once the user holds a certificate for it.
+type UserActivity struct {
+	CoursesCompleted int
+	ReviewsWritten   int
+	SchoolsOwned     int
+}
+
+// ImportResult is the outcome of ImportUsers: the imported users and, for
+// every user that could not be imported, the failure.
+type ImportResult struct {
+	Imported []domain.User
+	Failed   []ImportFailure
+}
+
+// ImportFailure is a user ImportUsers rejected, Index is its position in the
+// imported slice.
+type ImportFailure struct {
+	Index int
+	User  domain.User
+	Err   error
+}
+
+type DateCount struct {
+	Date  time.Time
+	Count int
+}
+
+// UserSyncCursor is the (updated_at, id) position of the last user returned
+// by FindUsersModifiedSince. A cursor with an empty ID starts at UpdatedAt,
+// the users updated at exactly UpdatedAt included.
+type UserSyncCursor struct {
+	UpdatedAt time.Time
+	ID        domain.ID
+}
+
+// cohortBuckets are the date_trunc fields UsersByCohort accepts.
+var cohortBuckets = map[string]bool{"day": true, "week": true, "month": true}
+
+func NewUserRepo(db *sqlx.DB, opts ...Option) *PostgresUserRepo {
 	return &PostgresUserRepo{
-		db: db,
+		db:   db,
+		opts: newOptions(opts),
 	}
 }
 
 const (
-	userFindAllQuery           = "SELECT * FROM public.user"
-	userFindByIDQuery          = "SELECT * FROM public.user WHERE id = $1"
-	userFindByEmailQuery       = "SELECT * FROM public.user WHERE email = $1"
-	userFindByCredentialsQuery = "SELECT * FROM public.user WHERE email = $1 AND password = $2"
-	userFindUserInfoQuery      = "SELECT name, surname FROM public.user WHERE id = $1"
-	userDeleteQuery            = "DELETE FROM public.user WHERE id = $1"
+	userFindAllQuery             = "SELECT * FROM public.user"
+	userFindByIDQuery            = "SELECT * FROM public.user WHERE id = ?"
+	userFindByIDsQuery           = "SELECT * FROM public.user WHERE id IN (?)"
+	userExistsByIDsQuery         = "SELECT id FROM public.user WHERE id IN (?)"
+	userFindPublicByIDQuery      = "SELECT id, name, surname, city, avatar_url FROM public.user WHERE id = ?"
+	userFindByEmailQuery         = "SELECT * FROM public.user WHERE email = ?"
+	userFindByCredentialsQuery   = "SELECT * FROM public.user WHERE email = ? AND password = ?"
+	userFindUserInfoQuery        = "SELECT name, surname FROM public.user WHERE id = ?"
+	userFindBySurnamePrefixQuery = "SELECT * FROM public.user WHERE surname ILIKE ? || '%' " +
+		"ORDER BY surname LIMIT ?"
+	userFindModifiedSinceQuery = "SELECT * FROM public.user WHERE (updated_at, id) > (?, ?) " +
+		"ORDER BY updated_at, id LIMIT ?"
+	userFindDuplicateEmailsQuery = "SELECT lower(email) AS email, string_agg(id::text, ',' ORDER BY id) AS user_ids, " +
+		"COUNT(*) AS count FROM public.user GROUP BY lower(email) HAVING COUNT(*) > 1 ORDER BY 1"
+	userActivitySummaryQuery = "SELECT " +
+		"(SELECT COUNT(DISTINCT course_id) FROM public.certificate WHERE user_id = u.id) AS courses_completed, " +
+		"(SELECT COUNT(*) FROM public.review WHERE user_id = u.id AND deleted_at IS NULL) AS reviews_written, " +
+		"(SELECT COUNT(*) FROM public.school WHERE owner_id = u.id) AS schools_owned " +
+		"FROM public.user u WHERE u.id = ?"
+	userCohortQuery = "SELECT date_trunc(?, created_at) AS date, COUNT(*) AS count FROM public.user " +
+		"WHERE created_at >= ? AND created_at < ? GROUP BY 1 ORDER BY 1"
+	userDeleteQuery = "DELETE FROM public.user WHERE id = ?"
+
+	userImportSavepointQuery         = "SAVEPOINT import_user"
+	userImportRollbackSavepointQuery = "ROLLBACK TO SAVEPOINT import_user"
+	userImportReleaseSavepointQuery  = "RELEASE SAVEPOINT import_user"
+
+	userEraseReviewsQuery      = "DELETE FROM public.review WHERE user_id = ?"
+	userEraseEnrollmentsQuery  = "DELETE FROM public.course_student WHERE student_id = ?"
+	userEraseCertificatesQuery = "DELETE FROM public.certificate WHERE user_id = ?"
 )
 
 func (u *PostgresUserRepo) FindAll(ctx context.Context) ([]domain.User, error) {
+	if err := u.opts.checkRateLimit("user.FindAll"); err != nil {
+		return nil, err
+	}
+
 	var pgUsers []entity.PgUser
-	if err := u.db.SelectContext(ctx, &pgUsers, userFindAllQuery); err != nil {
-		if err == sql.ErrNoRows {
-			return nil, errors.Wrap(errs.ErrNotExist, err.Error())
-		} else {
-			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
-		}
+	if err := selectRows(ctx, u.db, u.opts, "user.FindAll", &pgUsers, userFindAllQuery); err != nil {
+		return nil, err
 	}
+	u.opts.observeRows("user.FindAll", len(pgUsers))
 
 	users := make([]domain.User, len(pgUsers))
 	for i, user := range pgUsers {
@@ -48,38 +123,140 @@ func (u *PostgresUserRepo) FindAll(ctx context.Context) ([]domain.User, error) {
 	return users, nil
 }
 
+// ForEach calls fn for every user, reading the rows one at a time instead of
+// loading the whole table. Iteration stops at the first error returned by fn,
+// which is returned as is, or when the context is done.
+func (u *PostgresUserRepo) ForEach(ctx context.Context, fn func(domain.User) error) error {
+	rows, err := u.db.QueryxContext(ctx, u.db.Rebind(userFindAllQuery))
+	if err != nil {
+		return persistenceError(u.db, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err = ctx.Err(); err != nil {
+			return persistenceError(u.db, err)
+		}
+		var pgUser entity.PgUser
+		if err = rows.StructScan(&pgUser); err != nil {
+			return persistenceError(u.db, err)
+		}
+		if err = fn(pgUser.ToDomain()); err != nil {
+			return err
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return persistenceError(u.db, err)
+	}
+	return nil
+}
+
 func (u *PostgresUserRepo) FindByID(ctx context.Context, userID domain.ID) (domain.User, error) {
 	var pgUser entity.PgUser
-	if err := u.db.GetContext(ctx, &pgUser, userFindByIDQuery, userID); err != nil {
+	db := u.opts.reader(ctx, u.db)
+	if err := db.GetContext(ctx, &pgUser, db.Rebind(userFindByIDQuery), userID); err != nil {
+		if err == sql.ErrNoRows {
+			return domain.User{}, errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return domain.User{}, persistenceError(db, err)
+		}
+	}
+	return pgUser.ToDomain(), nil
+}
+
+// FindByIDsOrdered returns the users in the order of the given ids, ids
+// without a user are skipped. Any number of ids can be given, they are
+// looked up 1000 at a time.
+func (u *PostgresUserRepo) FindByIDsOrdered(ctx context.Context, ids []domain.ID) ([]domain.User, error) {
+	if err := u.opts.checkRateLimit("user.FindByIDsOrdered"); err != nil {
+		return nil, err
+	}
+
+	if len(ids) == 0 {
+		return []domain.User{}, nil
+	}
+
+	var pgUsers []entity.PgUser
+	for _, chunk := range chunkIDs(ids, inChunkSize) {
+		query, args, err := sqlx.In(userFindByIDsQuery, chunk)
+		if err != nil {
+			return nil, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+		}
+
+		var pgChunk []entity.PgUser
+		if err := selectRows(ctx, u.db, u.opts, "user.FindByIDsOrdered", &pgChunk, query, args...); err != nil {
+			return nil, err
+		}
+		pgUsers = append(pgUsers, pgChunk...)
+	}
+	u.opts.observeRows("user.FindByIDsOrdered", len(pgUsers))
+
+	found := make(map[domain.ID]domain.User, len(pgUsers))
+	for _, pgUser := range pgUsers {
+		user := pgUser.ToDomain()
+		found[user.ID] = user
+	}
+
+	users := make([]domain.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := found[id]; ok {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// ExistsByIDs reports for every given id whether the user exists.
+func (u *PostgresUserRepo) ExistsByIDs(ctx context.Context, ids []domain.ID) (map[domain.ID]bool, error) {
+	return existsByIDs(ctx, u.db, u.opts, "user.ExistsByIDs", userExistsByIDsQuery, ids)
+}
+
+// FindPublicByID returns the user as shown to other users: the email,
+// password and phone are not read from the database and are left empty.
+func (u *PostgresUserRepo) FindPublicByID(ctx context.Context, userID domain.ID) (domain.User, error) {
+	var pgUser entity.PgUser
+	if err := u.db.GetContext(ctx, &pgUser, u.db.Rebind(userFindPublicByIDQuery), userID); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.User{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.User{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.User{}, persistenceError(u.db, err)
 		}
 	}
 	return pgUser.ToDomain(), nil
 }
 
 func (u *PostgresUserRepo) FindByEmail(ctx context.Context, email string) (domain.User, error) {
+	u.opts.logQuery("user.FindByEmail", email)
 	var pgUser entity.PgUser
-	if err := u.db.GetContext(ctx, &pgUser, userFindByEmailQuery, email); err != nil {
+	if err := u.db.GetContext(ctx, &pgUser, u.db.Rebind(userFindByEmailQuery), email); err != nil {
 		if err == sql.ErrNoRows {
 			return domain.User{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.User{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.User{}, persistenceError(u.db, err)
 		}
 	}
 	return pgUser.ToDomain(), nil
 }
 
+// FindUserByEmailOrID looks the user up by id when the identifier is one and
+// by email otherwise, surrounding whitespace is ignored.
+func (u *PostgresUserRepo) FindUserByEmailOrID(ctx context.Context, identifier string) (domain.User, error) {
+	identifier = strings.TrimSpace(identifier)
+	if id, err := uuid.Parse(identifier); err == nil {
+		return u.FindByID(ctx, domain.ID(id.String()))
+	}
+	return u.FindByEmail(ctx, identifier)
+}
+
 func (u *PostgresUserRepo) FindByCredentials(ctx context.Context, email string, password string) (domain.User, error) {
+	u.opts.logQuery("user.FindByCredentials", email, password)
 	var pgUser entity.PgUser
-	err := u.db.GetContext(ctx, &pgUser, userFindByCredentialsQuery, email, password)
+	err := u.db.GetContext(ctx, &pgUser, u.db.Rebind(userFindByCredentialsQuery), email, password)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.User{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.User{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.User{}, persistenceError(u.db, err)
 		}
 	}
 	return pgUser.ToDomain(), nil
@@ -87,12 +264,12 @@ func (u *PostgresUserRepo) FindByCredentials(ctx context.Context, email string,
 
 func (u *PostgresUserRepo) FindUserInfo(ctx context.Context, userID domain.ID) (port.UserInfo, error) {
 	var pgUser entity.PgUser
-	err := u.db.GetContext(ctx, &pgUser, userFindUserInfoQuery, userID)
+	err := u.db.GetContext(ctx, &pgUser, u.db.Rebind(userFindUserInfoQuery), userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return port.UserInfo{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return port.UserInfo{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return port.UserInfo{}, persistenceError(u.db, err)
 		}
 	}
 	return port.UserInfo{
@@ -101,60 +278,360 @@ func (u *PostgresUserRepo) FindUserInfo(ctx context.Context, userID domain.ID) (
 	}, nil
 }
 
+func (u *PostgresUserRepo) GetUserActivitySummary(ctx context.Context, userID domain.ID) (UserActivity, error) {
+	var pgActivity entity.PgUserActivity
+	err := u.db.GetContext(ctx, &pgActivity, u.db.Rebind(userActivitySummaryQuery), userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return UserActivity{}, errors.Wrap(errs.ErrNotExist, err.Error())
+		} else {
+			return UserActivity{}, persistenceError(u.db, err)
+		}
+	}
+	return UserActivity{
+		CoursesCompleted: pgActivity.CoursesCompleted,
+		ReviewsWritten:   pgActivity.ReviewsWritten,
+		SchoolsOwned:     pgActivity.SchoolsOwned,
+	}, nil
+}
+
+func (u *PostgresUserRepo) FindUsersBySurnamePrefix(ctx context.Context,
+	prefix string, limit int) ([]domain.User, error) {
+	if err := u.opts.checkRateLimit("user.FindUsersBySurnamePrefix"); err != nil {
+		return nil, err
+	}
+
+	var pgUsers []entity.PgUser
+	if err := selectRows(ctx, u.db, u.opts, "user.FindUsersBySurnamePrefix", &pgUsers,
+		userFindBySurnamePrefixQuery, escapeLikePattern(prefix), limit); err != nil {
+		return nil, err
+	}
+	u.opts.observeRows("user.FindUsersBySurnamePrefix", len(pgUsers))
+
+	users := make([]domain.User, len(pgUsers))
+	for i, user := range pgUsers {
+		users[i] = user.ToDomain()
+	}
+	return users, nil
+}
+
+// FindUsersModifiedSince returns up to limit users created or updated after
+// the cursor, oldest change first, and the cursor to pass on the next call:
+// the position of the last returned user, or since when nothing changed.
+// The cursor includes the id, so users sharing an updated_at are not
+// skipped when limit splits them. Users are deleted for real, so deletions
+// do not show up here and have to be propagated separately.
+func (u *PostgresUserRepo) FindUsersModifiedSince(ctx context.Context,
+	since UserSyncCursor, limit int) ([]domain.User, UserSyncCursor, error) {
+	if err := u.opts.checkRateLimit("user.FindUsersModifiedSince"); err != nil {
+		return nil, since, err
+	}
+
+	afterID := since.ID
+	if afterID == "" {
+		afterID = domain.ID(uuid.Nil.String())
+	}
+
+	var pgUsers []entity.PgUser
+	if err := selectRows(ctx, u.db, u.opts, "user.FindUsersModifiedSince", &pgUsers,
+		userFindModifiedSinceQuery, since.UpdatedAt.UTC(), afterID, limit); err != nil {
+		return nil, since, err
+	}
+	u.opts.observeRows("user.FindUsersModifiedSince", len(pgUsers))
+
+	users := make([]domain.User, len(pgUsers))
+	for i, user := range pgUsers {
+		users[i] = user.ToDomain()
+	}
+
+	next := since
+	if len(pgUsers) > 0 {
+		last := pgUsers[len(pgUsers)-1]
+		next = UserSyncCursor{UpdatedAt: last.UpdatedAt, ID: domain.ID(last.ID.String())}
+	}
+	return users, next, nil
+}
+
+// FindDuplicateEmails groups the users whose emails differ only in case, the
+// unique email constraint does not prevent them. Group emails are lowercased.
+func (u *PostgresUserRepo) FindDuplicateEmails(ctx context.Context) ([]EmailGroup, error) {
+	if err := u.opts.checkRateLimit("user.FindDuplicateEmails"); err != nil {
+		return nil, err
+	}
+
+	var pgGroups []entity.PgEmailGroup
+	if err := selectRows(ctx, u.db, u.opts, "user.FindDuplicateEmails", &pgGroups,
+		userFindDuplicateEmailsQuery); err != nil {
+		return nil, err
+	}
+	u.opts.observeRows("user.FindDuplicateEmails", len(pgGroups))
+
+	groups := make([]EmailGroup, len(pgGroups))
+	for i, group := range pgGroups {
+		ids := strings.Split(group.UserIDs, ",")
+		userIDs := make([]domain.ID, len(ids))
+		for j, id := range ids {
+			userIDs[j] = domain.ID(id)
+		}
+		groups[i] = EmailGroup{
+			Email:   group.Email,
+			UserIDs: userIDs,
+			Count:   group.Count,
+		}
+	}
+	return groups, nil
+}
+
+// UsersByCohort counts the users who signed up in [from, to) per day, week
+// or month, oldest bucket first. Buckets without signups are left out.
+func (u *PostgresUserRepo) UsersByCohort(ctx context.Context,
+	from, to time.Time, bucket string) ([]DateCount, error) {
+	if err := u.opts.checkRateLimit("user.UsersByCohort"); err != nil {
+		return nil, err
+	}
+
+	if !cohortBuckets[bucket] {
+		return nil, errors.Wrap(ErrInvalidBucket, bucket)
+	}
+
+	var pgCounts []entity.PgDateCount
+	if err := selectRows(ctx, u.db, u.opts, "user.UsersByCohort", &pgCounts,
+		userCohortQuery, bucket, from.UTC(), to.UTC()); err != nil {
+		return nil, err
+	}
+	u.opts.observeRows("user.UsersByCohort", len(pgCounts))
+
+	counts := make([]DateCount, len(pgCounts))
+	for i, count := range pgCounts {
+		counts[i] = DateCount{
+			Date:  count.Date,
+			Count: count.Count,
+		}
+	}
+	return counts, nil
+}
+
 func (u *PostgresUserRepo) Create(ctx context.Context, user domain.User) (domain.User, error) {
-	var pgUser = entity.NewPgUser(user)
+	if err := u.opts.checkWritable(); err != nil {
+		return domain.User{}, err
+	}
+
+	pgUser, err := entity.NewPgUser(user)
+	if err != nil {
+		return domain.User{}, err
+	}
+	if err := pgUser.Validate(); err != nil {
+		return domain.User{}, err
+	}
 	queryString := entity.InsertQueryString(pgUser, "user")
-	_, err := u.db.NamedExecContext(ctx, queryString, pgUser)
+	u.opts.logQuery("user.Create", pgUser)
+	_, err = namedExecContext(ctx, u.db, queryString, pgUser)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
 			if pgErr.Code == PgUniqueViolationCode {
-				return domain.User{}, errors.Wrap(errs.ErrDuplicate, err.Error())
+				return domain.User{}, uniqueViolationError(pgErr, err)
 			} else {
-				return domain.User{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+				return domain.User{}, persistenceError(u.db, err)
 			}
 		} else {
-			return domain.User{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.User{}, persistenceError(u.db, err)
 		}
 	}
 
 	var createdUser entity.PgUser
-	err = u.db.GetContext(ctx, &createdUser, userFindByIDQuery, pgUser.ID)
+	err = u.db.GetContext(ctx, &createdUser, u.db.Rebind(userFindByIDQuery), pgUser.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.User{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.User{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.User{}, persistenceError(u.db, err)
+		}
+	}
+
+	return createdUser.ToDomain(), nil
+}
+
+// ImportUsers creates the users in one transaction, each of them behind a
+// savepoint: a user that fails validation or conflicts with an existing
+// user, or with an earlier user of the batch, is reported in the result
+// while the others are still imported. An error is returned only when the
+// transaction itself fails, then no user is imported.
+func (u *PostgresUserRepo) ImportUsers(ctx context.Context, users []domain.User) (ImportResult, error) {
+	if err := u.opts.checkWritable(); err != nil {
+		return ImportResult{}, err
+	}
+
+	var result ImportResult
+	err := WithinTx(ctx, u.db, nil, func(tx *sqlx.Tx) error {
+		result = ImportResult{}
+		for i, user := range users {
+			pgUser, err := entity.NewPgUser(user)
+			if err == nil {
+				err = pgUser.Validate()
+			}
+			if err != nil {
+				result.Failed = append(result.Failed, ImportFailure{Index: i, User: user, Err: err})
+				continue
+			}
+
+			if _, err := tx.ExecContext(ctx, userImportSavepointQuery); err != nil {
+				return persistenceError(u.db, err)
+			}
+			imported, err := u.importUser(ctx, tx, pgUser)
+			if err != nil {
+				if _, rollbackErr := tx.ExecContext(ctx, userImportRollbackSavepointQuery); rollbackErr != nil {
+					return persistenceError(u.db, rollbackErr)
+				}
+			}
+			// ROLLBACK TO keeps the savepoint, so it is released after a
+			// failed user as well, or every failure of the batch would
+			// leave one behind until the commit
+			if _, releaseErr := tx.ExecContext(ctx, userImportReleaseSavepointQuery); releaseErr != nil {
+				return persistenceError(u.db, releaseErr)
+			}
+			if err != nil {
+				result.Failed = append(result.Failed, ImportFailure{Index: i, User: user, Err: err})
+				continue
+			}
+			result.Imported = append(result.Imported, imported)
 		}
+		return nil
+	})
+	if err != nil {
+		return ImportResult{}, err
+	}
+	return result, nil
+}
+
+func (u *PostgresUserRepo) importUser(ctx context.Context, tx *sqlx.Tx, pgUser entity.PgUser) (domain.User, error) {
+	queryString := entity.InsertQueryString(pgUser, "user")
+	u.opts.logQuery("user.ImportUsers", pgUser)
+	if _, err := namedExecContext(ctx, tx, queryString, pgUser); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.User{}, uniqueViolationError(pgErr, err)
+		}
+		return domain.User{}, persistenceError(u.db, err)
 	}
 
+	var createdUser entity.PgUser
+	if err := tx.GetContext(ctx, &createdUser, tx.Rebind(userFindByIDQuery), pgUser.ID); err != nil {
+		return domain.User{}, persistenceError(u.db, err)
+	}
 	return createdUser.ToDomain(), nil
 }
 
 func (u *PostgresUserRepo) Update(ctx context.Context, user domain.User) (domain.User, error) {
-	var pgUser = entity.NewPgUser(user)
+	if err := u.opts.checkWritable(); err != nil {
+		return domain.User{}, err
+	}
+
+	pgUser, err := entity.NewPgUser(user)
+	if err != nil {
+		return domain.User{}, err
+	}
 	queryString := entity.UpdateQueryString(pgUser, "user")
-	_, err := u.db.NamedExecContext(ctx, queryString, pgUser)
+	u.opts.logQuery("user.Update", pgUser)
+	_, err = namedExecContext(ctx, u.db, queryString, pgUser)
 	if err != nil {
 		return domain.User{}, errors.Wrap(errs.ErrUpdateFailed, err.Error())
 	}
 
 	var updatedUser entity.PgUser
-	err = u.db.GetContext(ctx, &updatedUser, userFindByIDQuery, pgUser.ID)
+	err = u.db.GetContext(ctx, &updatedUser, u.db.Rebind(userFindByIDQuery), pgUser.ID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return domain.User{}, errors.Wrap(errs.ErrNotExist, err.Error())
 		} else {
-			return domain.User{}, errors.Wrap(errs.ErrPersistenceFailed, err.Error())
+			return domain.User{}, persistenceError(u.db, err)
 		}
 	}
 	return updatedUser.ToDomain(), nil
 }
 
+// Patch updates only the given columns of the user, keyed by column name
+// such as "city", and returns the updated user. An unknown or read-only
+// column is rejected with entity.ErrUnknownColumn and an empty patch with
+// entity.ErrValidation.
+func (u *PostgresUserRepo) Patch(ctx context.Context, userID domain.ID, fields map[string]any) (domain.User, error) {
+	if err := u.opts.checkWritable(); err != nil {
+		return domain.User{}, err
+	}
+
+	query, args, err := entity.PatchQueryString(entity.PgUser{}, "user", fields)
+	if err != nil {
+		return domain.User{}, err
+	}
+
+	args = append(args, userID)
+	u.opts.logQuery("user.Patch", args...)
+	var patchedUser entity.PgUser
+	if err = u.db.GetContext(ctx, &patchedUser, u.db.Rebind(query), args...); err != nil {
+		var pgErr *pgconn.PgError
+		if err == sql.ErrNoRows {
+			return domain.User{}, errors.Wrap(errs.ErrNotExist, err.Error())
+		} else if errors.As(err, &pgErr) && pgErr.Code == PgUniqueViolationCode {
+			return domain.User{}, uniqueViolationError(pgErr, err)
+		} else {
+			return domain.User{}, errors.Wrap(errs.ErrUpdateFailed, err.Error())
+		}
+	}
+	return patchedUser.ToDomain(), nil
+}
+
 func (u *PostgresUserRepo) Delete(ctx context.Context, userID domain.ID) error {
-	_, err := u.db.ExecContext(ctx, userDeleteQuery, userID)
+	if err := u.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	_, err := u.db.ExecContext(ctx, u.db.Rebind(userDeleteQuery), userID)
 	if err != nil {
 		return errors.Wrap(errs.ErrDeleteFailed, err.Error())
 	}
 	return nil
 }
+
+// EraseUserData removes the user together with their reviews, enrollments
+// and certificates in one transaction, nothing is removed if any step fails.
+func (u *PostgresUserRepo) EraseUserData(ctx context.Context, userID domain.ID) error {
+	if err := u.opts.checkWritable(); err != nil {
+		return err
+	}
+
+	tx, err := u.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+
+	queries := []string{
+		userEraseReviewsQuery,
+		userEraseEnrollmentsQuery,
+		userEraseCertificatesQuery,
+		userDeleteQuery,
+	}
+	for _, query := range queries {
+		_, err = tx.ExecContext(ctx, tx.Rebind(query), userID)
+		if err != nil {
+			if rollbackErr := tx.Rollback(); rollbackErr != nil {
+				return errors.Wrapf(errs.ErrDeleteFailed, "%s, rollback: %s",
+					err.Error(), rollbackErr.Error())
+			}
+			return errors.Wrap(errs.ErrDeleteFailed, err.Error())
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		tx.Rollback()
+		return errors.Wrap(errs.ErrTransactionError, err.Error())
+	}
+
+	return nil
+}
+
+var likePatternReplacer = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// escapeLikePattern escapes the LIKE wildcards so user input matches literally.
+func escapeLikePattern(pattern string) string {
+	return likePatternReplacer.Replace(pattern)
+}